@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/configreloader"
+	eventsync "github.com/twothicc/canal/handlers/events/sync"
+	"github.com/twothicc/canal/infra/grpcserver"
 	"github.com/twothicc/canal/infra/httprouter"
 	"github.com/twothicc/canal/tools/env"
 	"github.com/twothicc/common-go/logger"
@@ -18,7 +23,10 @@ import (
 
 var ctx = context.Background()
 
-const READ_HEADER_TIMEOUT = 2
+const (
+	READ_HEADER_TIMEOUT = 2
+	CONFIG_PATH         = "./conf/app.toml"
+)
 
 func main() {
 	logger.InitLogger(zapcore.InfoLevel)
@@ -31,6 +39,12 @@ func main() {
 
 	dependencies := initDependencies(ctx)
 
+	if dependencies.AppConfig.LogConfig.Level != "" {
+		// validated when loaded
+		level, _ := dependencies.AppConfig.LogConfig.ZapLevel()
+		logger.InitLogger(level)
+	}
+
 	logger.WithContext(ctx).Info("loaded dependencies")
 
 	// TODO read addr from toml config
@@ -40,53 +54,152 @@ func main() {
 			GrpcClient:     dependencies.GrpcClient,
 			Cfg:            dependencies.AppConfig,
 			SyncController: dependencies.SyncController,
+			ConfigReloader: dependencies.ConfigReloader,
+			AuditLog:       dependencies.AuditLog,
 		}),
 		ReadHeaderTimeout: READ_HEADER_TIMEOUT * time.Second,
 	}
 
-	// testing purpose only
-	dependencies.AppConfig.DbConfig.Pass = env.EnvConfigs.DbPass
+	// tail streams stay open until their subscriptions are closed
+	httpServer.RegisterOnShutdown(eventsync.CloseTaps)
 
-	syncManager, err := syncmanager.NewSyncManager(
-		ctx,
-		dependencies.AppConfig,
-	)
+	grpcServer := grpcserver.NewGRPCServer(ctx, &grpcserver.GrpcServerDependencies{
+		Cfg:            dependencies.AppConfig,
+		SyncController: dependencies.SyncController,
+		AuditLog:       dependencies.AuditLog,
+	})
+
+	if err := dependencies.SyncController.Restore(ctx); err != nil {
+		logger.WithContext(ctx).Error("fail to restore pipelines", zap.Error(err))
+	}
+
+	if declared := dependencies.AppConfig.Declared(); len(declared) > 0 {
+		reconcile(ctx, dependencies, declared)
+	} else if len(dependencies.SyncController.Status()) == 0 {
+		// only bootstrap the pipeline in conf/app.toml when none were declared or persisted
+		syncManager, err := dependencies.SyncController.NewSyncManager(
+			ctx,
+			dependencies.AppConfig,
+		)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := dependencies.SyncController.Add(ctx, syncManager.GetId(), syncManager); err != nil {
+			logger.WithContext(ctx).Error("fail to persist canal", zap.Error(err))
+		}
+
+		if err := dependencies.SyncController.Start(ctx, syncManager.GetId(), false); err != nil {
+			logger.WithContext(ctx).Error("fail to run canal", zap.Error(err))
+		}
+	}
+
+	// changes to the config file are only picked up once booted
+	go dependencies.ConfigReloader.Watch(ctx)
+
+	go serveHTTP(ctx, httpServer)
+
+	if addr := dependencies.AppConfig.GrpcConfig.Addr; addr != "" {
+		go serveGRPC(ctx, grpcServer, addr)
+	}
+
+	ListenSignals(ctx, httpServer, grpcServer, dependencies)
+}
+
+// reconcile - creates, updates, starts and stops pipelines to match those declared in conf/app.toml
+func reconcile(ctx context.Context, dependencies *Dependencies, declared []*config.Config) {
+	res, err := dependencies.SyncController.Reconcile(ctx, declared)
 	if err != nil {
-		panic(err)
+		logger.WithContext(ctx).Error("[Main.reconcile]fail to reconcile declared pipelines", zap.Error(err))
+
+		return
 	}
 
-	dependencies.SyncController.Add(ctx, syncManager.GetId(), syncManager)
+	logger.WithContext(ctx).Info(
+		"[Main.reconcile]reconciled declared pipelines",
+		zap.Uint32s("created", res.Created),
+		zap.Uint32s("updated", res.Updated),
+		zap.Uint32s("started", res.Started),
+		zap.Uint32s("stopped", res.Stopped),
+		zap.Uint32s("unchanged", res.Unchanged),
+		zap.Any("failed", res.Failed),
+	)
+}
 
-	if err := dependencies.SyncController.Start(ctx, syncManager.GetId(), false); err != nil {
-		logger.WithContext(ctx).Error("fail to run canal", zap.Error(err))
+func serveHTTP(ctx context.Context, httpServer *http.Server) {
+	logger.WithContext(ctx).Info("[Main.serveHTTP]serving http", zap.String("addr", httpServer.Addr))
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.WithContext(ctx).Error("[Main.serveHTTP]fail to serve http", zap.Error(err))
 	}
+}
 
-	ListenSignals(ctx, httpServer, dependencies)
+func serveGRPC(ctx context.Context, grpcServer *grpcserver.GrpcServer, addr string) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.WithContext(ctx).Error("[Main.serveGRPC]fail to listen", zap.String("addr", addr), zap.Error(err))
+
+		return
+	}
+
+	logger.WithContext(ctx).Info("[Main.serveGRPC]serving grpc", zap.String("addr", addr))
+
+	if err := grpcServer.Serve(listener); err != nil {
+		logger.WithContext(ctx).Error("[Main.serveGRPC]fail to serve grpc", zap.Error(err))
+	}
 }
 
-func ListenSignals(ctx context.Context, httpServer *http.Server, d *Dependencies) {
+func ListenSignals(ctx context.Context, httpServer *http.Server, grpcServer *grpcserver.GrpcServer, d *Dependencies) {
 	signalChan := make(chan os.Signal, 1)
 
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
 
 	sig := <-signalChan
 
+	for ; sig == syscall.SIGHUP; sig = <-signalChan {
+		d.ConfigReloader.Reload(ctx, configreloader.TRIGGER_SIGNAL)
+	}
+
 	logger.WithContext(ctx).Info("receive signal, stopping server", zap.String("signal", sig.String()))
-	time.Sleep(1 * time.Second)
 
-	d.GrpcClient.Close(ctx)
-	logger.WithContext(ctx).Info("[Main.ListenSignals]closed grpc clients")
+	// pipelines must not be reconciled while they are drained
+	d.ConfigReloader.Close()
+
+	// stop accepting control-plane requests before draining pipelines
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.WithContext(ctx).Error("[Main.ListenSignals]fail to gracefully shutdown http server", zap.Error(err))
+	} else {
+		logger.WithContext(ctx).Info("[Main.ListenSignals]gracefully shutdown http server")
+	}
+
+	grpcServer.Shutdown()
+	logger.WithContext(ctx).Info("[Main.ListenSignals]gracefully shutdown grpc server")
+
+	drains, err := d.SyncController.Close(ctx)
+	for id, drain := range drains {
+		logger.WithContext(ctx).Info(
+			"[Main.ListenSignals]drained syncmanager",
+			zap.Uint32("server id", id),
+			zap.Duration("drain duration", drain.Duration),
+			zap.Bool("is drained", drain.IsCompleted),
+			zap.Int("in flight", drain.InFlight),
+			zap.Stringer("checkpoint", drain.Checkpoint),
+		)
+	}
 
-	if err := d.SyncController.Close(ctx); err != nil {
+	if err != nil {
 		logger.WithContext(ctx).Error("[Main.ListenSignals]fail to close sync controller", zap.Error(err))
 	} else {
 		logger.WithContext(ctx).Info("[Main.ListenSignals]closed sync controller")
 	}
 
-	if err := httpServer.Shutdown(ctx); err != nil {
-		logger.WithContext(ctx).Error("[Main.ListenSignals]fail to gracefully shutdown http server", zap.Error(err))
+	d.GrpcClient.Close(ctx)
+	logger.WithContext(ctx).Info("[Main.ListenSignals]closed grpc clients")
+
+	if err := d.AuditLog.Close(); err != nil {
+		logger.WithContext(ctx).Error("[Main.ListenSignals]fail to close audit log", zap.Error(err))
 	} else {
-		logger.WithContext(ctx).Info("[Main.ListenSignals]gracefully shutdown http server")
+		logger.WithContext(ctx).Info("[Main.ListenSignals]closed audit log")
 	}
 
 	logger.Sync()
@@ -2,10 +2,17 @@ package main
 
 import (
 	"context"
+	"os"
 
 	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/configreloader"
 	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/synccontroller/leaderlock"
+	"github.com/twothicc/canal/domain/entity/synccontroller/pipelinestore"
+	"github.com/twothicc/canal/domain/entity/synccontroller/registry"
+	"github.com/twothicc/canal/infra/auditlog"
 	"github.com/twothicc/canal/tools/env"
+	"github.com/twothicc/canal/tools/secret"
 	"github.com/twothicc/common-go/grpcclient"
 	"github.com/twothicc/common-go/grpcclient/pool"
 	"github.com/twothicc/common-go/logger"
@@ -16,10 +23,12 @@ type Dependencies struct {
 	GrpcClient     *grpcclient.Client
 	AppConfig      *config.Config
 	SyncController synccontroller.SyncController
+	ConfigReloader configreloader.ConfigReloader
+	AuditLog       *auditlog.AuditLog
 }
 
 func initDependencies(ctx context.Context) *Dependencies {
-	appConfig, err := config.NewConfig("./conf/app.toml")
+	appConfig, err := config.NewConfig(CONFIG_PATH)
 	if err != nil {
 		logger.WithContext(ctx).Error("[initDependencies]fail to load config", zap.Error(err))
 	}
@@ -32,11 +41,45 @@ func initDependencies(ctx context.Context) *Dependencies {
 
 	client := grpcclient.NewClient(ctx, clientConfigs)
 
-	syncController := synccontroller.NewSyncController(ctx)
+	appConfig.StoreConfig.Pass = env.EnvConfigs.StorePass
+
+	for i := range appConfig.AuthConfig.Keys {
+		appConfig.AuthConfig.Keys[i].Secret = os.Getenv(appConfig.AuthConfig.Keys[i].SecretEnv)
+	}
+
+	secret.Allow(appConfig.SecretConfig.AllowedEnv, appConfig.SecretConfig.AllowedDirs)
+
+	store, err := pipelinestore.NewPipelineStore(ctx, appConfig.StoreConfig)
+	if err != nil {
+		logger.WithContext(ctx).Error("[initDependencies]fail to create pipeline store", zap.Error(err))
+		panic(err)
+	}
+
+	leaderLock, err := leaderlock.NewLeaderLock(ctx, appConfig.LeaderConfig, appConfig.StoreConfig)
+	if err != nil {
+		logger.WithContext(ctx).Error("[initDependencies]fail to create leadership lock", zap.Error(err))
+		panic(err)
+	}
+
+	reg, err := registry.NewRegistry(ctx, appConfig.ClusterConfig, appConfig.StoreConfig)
+	if err != nil {
+		logger.WithContext(ctx).Error("[initDependencies]fail to create instance registry", zap.Error(err))
+		panic(err)
+	}
+
+	auditLog, err := auditlog.NewAuditLog(ctx, appConfig.AuditConfig, appConfig.KafkaConfig)
+	if err != nil {
+		logger.WithContext(ctx).Error("[initDependencies]fail to open audit log", zap.Error(err))
+		panic(err)
+	}
+
+	syncController := synccontroller.NewSyncController(ctx, store, leaderLock, reg, appConfig)
 
 	return &Dependencies{
 		GrpcClient:     client,
 		AppConfig:      appConfig,
 		SyncController: syncController,
+		ConfigReloader: configreloader.NewConfigReloader(ctx, CONFIG_PATH, appConfig, syncController),
+		AuditLog:       auditLog,
 	}
 }
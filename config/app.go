@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/BurntSushi/toml"
+	"go.uber.org/zap/zapcore"
 )
 
 type SourceConfig struct {
@@ -12,10 +13,13 @@ type SourceConfig struct {
 	Tables []string `toml:"tables"`
 }
 
+// DbConfig - configures the source database
+//
+// Pass is a secret reference, env:NAME or file:/path, resolved only when connecting
 type DbConfig struct {
 	Addr    string `toml:"addr"`
 	User    string `toml:"user"`
-	Pass    string
+	Pass    string `toml:"pass" json:"-"`
 	Charset string `toml:"charset"`
 	Flavor  string `toml:"flavor"`
 }
@@ -27,16 +31,214 @@ type KafkaConfig struct {
 	Flush      uint32   `toml:"flush"`
 }
 
+// DumpConfig - configures the snapshot of existing records taken by legacy syncs
+//
+// SkipTables and TableWhere keys are given as schema.table. Skipped tables are
+// not dumped but are still streamed from the binlog.
 type DumpConfig struct {
-	DumpExecPath string `toml:"mysqldump_path"`
+	TableWhere         map[string]string `toml:"table_where"`
+	DumpExecPath       string            `toml:"mysqldump_path"`
+	Where              string            `toml:"where"`
+	LockMode           string            `toml:"lock_mode"`
+	Charset            string            `toml:"charset"`
+	ExtraOptions       []string          `toml:"extra_options"`
+	SkipTables         []string          `toml:"skip_tables"`
+	MaxAllowedPacketMB int               `toml:"max_allowed_packet_mb"`
 }
 
+// SnapshotConfig - configures the parallel snapshot used by legacy syncs
+//
+// Workers of 0 falls back to a single mysqldump run by canal
+type SnapshotConfig struct {
+	ReplicaAddr      string `toml:"replica_addr"`
+	Workers          int    `toml:"workers"`
+	ChunkSize        int    `toml:"chunk_size"`
+	RowsPerSecond    int    `toml:"rows_per_second"`
+	MaxReplicaLag    uint32 `toml:"max_replica_lag"`
+	LagCheckInterval uint32 `toml:"lag_check_interval"`
+}
+
+// StoreConfig - configures where pipeline definitions are persisted
+//
+// Type is either file, storing definitions under Dir, or mysql, storing them in Table
+type StoreConfig struct {
+	Type            string `toml:"type"`
+	Dir             string `toml:"dir"`
+	CheckpointDir   string `toml:"checkpoint_dir"`
+	Addr            string `toml:"addr"`
+	User            string `toml:"user"`
+	Pass            string `toml:"-" json:"-"`
+	Database        string `toml:"database"`
+	Table           string `toml:"table"`
+	CheckpointTable string `toml:"checkpoint_table"`
+}
+
+// SupervisorConfig - configures how failed pipelines are restarted
+//
+// Backoffs are in milliseconds. MaxAttempts of 0 retries forever
+type SupervisorConfig struct {
+	Multiplier     float64 `toml:"multiplier"`
+	Jitter         float64 `toml:"jitter"`
+	InitialBackoff uint32  `toml:"initial_backoff"`
+	MaxBackoff     uint32  `toml:"max_backoff"`
+	MaxAttempts    uint32  `toml:"max_attempts"`
+}
+
+// DrainConfig - configures how long closing a pipeline waits for in-flight messages
+//
+// Timeout is in milliseconds
+type DrainConfig struct {
+	Timeout uint32 `toml:"timeout"`
+}
+
+// LeaderConfig - configures the per-pipeline leadership lock shared by redundant instances
+//
+// An empty Type disables the lock. The mysql lock uses the store's database.
+// LeaseTimeout and RenewInterval are in milliseconds
+type LeaderConfig struct {
+	Type          string `toml:"type"`
+	Dir           string `toml:"dir"`
+	InstanceId    string `toml:"instance_id"`
+	LeaseTimeout  uint32 `toml:"lease_timeout"`
+	RenewInterval uint32 `toml:"renew_interval"`
+}
+
+// ClusterConfig - configures how instances register to share pipelines between them
+//
+// An empty Type runs every pipeline on this instance. The mysql registry uses the store's
+// database. Addr is advertised to other instances. Intervals are in milliseconds
+type ClusterConfig struct {
+	Type              string `toml:"type"`
+	Dir               string `toml:"dir"`
+	Table             string `toml:"table"`
+	Addr              string `toml:"addr"`
+	HeartbeatInterval uint32 `toml:"heartbeat_interval"`
+	InstanceTimeout   uint32 `toml:"instance_timeout"`
+}
+
+// QuotaConfig - caps the resources pipelines may use, 0 is unlimited
+//
+// MaxPipelines applies per instance, the rest per pipeline
+type QuotaConfig struct {
+	MaxPipelines       uint32 `toml:"max_pipelines"`
+	MaxTables          uint32 `toml:"max_tables"`
+	MaxEventsPerSecond uint32 `toml:"max_events_per_second"`
+	MaxBytesPerSecond  uint32 `toml:"max_bytes_per_second"`
+}
+
+// GrpcConfig - configures the gRPC control plane, disabled when Addr is empty
+type GrpcConfig struct {
+	Addr string `toml:"addr"`
+}
+
+// EventLogConfig - configures the in-memory log of the latest messages of each pipeline
+type EventLogConfig struct {
+	Size uint32 `toml:"size"`
+}
+
+// HealthConfig - thresholds past which the instance is reported as not ready
+//
+// MaxLagSeconds only applies while a pipeline has binlog left to read, so that a pipeline of an
+// idle source is not reported as lagging. 0 disables a threshold. Check results are reused for
+// CheckTtl milliseconds
+type HealthConfig struct {
+	MaxLagSeconds uint32 `toml:"max_lag_seconds"`
+	MaxLagBytes   uint64 `toml:"max_lag_bytes"`
+	CheckTtl      uint32 `toml:"check_ttl"`
+}
+
+// AuditConfig - configures the audit log of control-plane calls
+//
+// Records are appended to the JSONL file at Path and, if Topic is set, produced to it on the
+// default kafka brokers. Size is the number of latest records kept to be paged through
+type AuditConfig struct {
+	Path  string `toml:"path"`
+	Topic string `toml:"topic"`
+	Size  uint32 `toml:"size"`
+}
+
+// LogConfig - configures the instance's log, Level being debug, info, warn or error
+//
+// An empty Level keeps the level the instance started with
+type LogConfig struct {
+	Level string `toml:"level"`
+}
+
+// ReloadConfig - configures how changes to the config file are picked up
+//
+// The file is checked for changes every WatchInterval milliseconds, 0 only reloads on SIGHUP
+type ReloadConfig struct {
+	WatchInterval uint32 `toml:"watch_interval"`
+}
+
+// AuthKey - a caller of the control plane and the role it is granted
+//
+// Secret is read from the environment variable named by SecretEnv. It is sent as a bearer
+// token or used to sign requests with HMAC-SHA256
+type AuthKey struct {
+	Id        string `toml:"id"`
+	Role      string `toml:"role"`
+	SecretEnv string `toml:"secret_env"`
+	Secret    string `toml:"-" json:"-"`
+}
+
+// AuthConfig - configures who may call the control plane
+//
+// Auth is disabled when there are no keys. MaxSkew is how far in milliseconds the timestamp
+// of a signed request may be from now
+type AuthConfig struct {
+	Keys    []AuthKey `toml:"key"`
+	MaxSkew uint32    `toml:"max_skew"`
+}
+
+// SecretConfig - configures the secret references callers of the API may give
+//
+// Passwords given through the API must be env:NAME with NAME in AllowedEnv, or file:/path with
+// path under one of AllowedDirs. References in the config file are not restricted
+type SecretConfig struct {
+	AllowedEnv  []string `toml:"allowed_env"`
+	AllowedDirs []string `toml:"allowed_dirs"`
+}
+
+// PipelineConfig - a pipeline declared in the config file
+//
+// Names and ids must be unique across declarations, Id being the pipeline's server id. The set
+// fields of Database and Kafka, and Sources if any, override the top-level sections
+type PipelineConfig struct {
+	Name     string         `toml:"name"`
+	Database DbConfig       `toml:"database"`
+	Kafka    KafkaConfig    `toml:"kafka"`
+	Sources  []SourceConfig `toml:"source"`
+	Id       uint32         `toml:"id"`
+}
+
+// Config - settings of the instance, and the defaults of its pipelines
+//
+// Pipelines declares the pipelines the instance runs. Name and ServerId identify the pipeline a
+// config belongs to and are empty in the instance's own config
 type Config struct {
-	DbConfig    DbConfig       `toml:"database"`
-	DumpConfig  DumpConfig     `toml:"dump"`
-	Sources     []SourceConfig `toml:"source"`
-	KafkaConfig KafkaConfig    `toml:"kafka"`
-	ServerId    uint32
+	DbConfig         DbConfig         `toml:"database"`
+	DumpConfig       DumpConfig       `toml:"dump"`
+	SnapshotConfig   SnapshotConfig   `toml:"snapshot"`
+	Sources          []SourceConfig   `toml:"source"`
+	KafkaConfig      KafkaConfig      `toml:"kafka"`
+	StoreConfig      StoreConfig      `toml:"store"`
+	SupervisorConfig SupervisorConfig `toml:"supervisor"`
+	DrainConfig      DrainConfig      `toml:"drain"`
+	LeaderConfig     LeaderConfig     `toml:"leader"`
+	ClusterConfig    ClusterConfig    `toml:"cluster"`
+	QuotaConfig      QuotaConfig      `toml:"quota"`
+	AuthConfig       AuthConfig       `toml:"auth"`
+	SecretConfig     SecretConfig     `toml:"secret"`
+	EventLogConfig   EventLogConfig   `toml:"event_log"`
+	GrpcConfig       GrpcConfig       `toml:"grpc"`
+	HealthConfig     HealthConfig     `toml:"health"`
+	AuditConfig      AuditConfig      `toml:"audit"`
+	LogConfig        LogConfig        `toml:"log"`
+	ReloadConfig     ReloadConfig     `toml:"reload"`
+	Pipelines        []PipelineConfig `toml:"pipeline"`
+	Name             string
+	ServerId         uint32
 }
 
 func NewConfig(path string) (*Config, error) {
@@ -45,12 +247,38 @@ func NewConfig(path string) (*Config, error) {
 		return nil, ErrNotFound.New(fmt.Sprintf("[NewConfig]%s", err.Error()))
 	}
 
+	return Parse(data)
+}
+
+// Parse - decodes and validates the content of a config file
+func Parse(data []byte) (*Config, error) {
 	var c Config
 
-	_, err = toml.Decode(string(data), &c)
-	if err != nil {
-		return nil, ErrParse.New(fmt.Sprintf("[NewConfig]%s", err.Error()))
+	if _, err := toml.Decode(string(data), &c); err != nil {
+		return nil, ErrParse.New(fmt.Sprintf("[Parse]%s", err.Error()))
+	}
+
+	if _, err := c.LogConfig.ZapLevel(); err != nil {
+		return nil, err
+	}
+
+	if err := c.validatePipelines(); err != nil {
+		return nil, err
 	}
 
 	return &c, nil
 }
+
+// ZapLevel - returns the configured log level, info if unset
+func (l LogConfig) ZapLevel() (zapcore.Level, error) {
+	if l.Level == "" {
+		return zapcore.InfoLevel, nil
+	}
+
+	level, err := zapcore.ParseLevel(l.Level)
+	if err != nil {
+		return level, ErrInvalid.New(fmt.Sprintf("[LogConfig.ZapLevel]%s", err.Error()))
+	}
+
+	return level, nil
+}
@@ -0,0 +1,57 @@
+package config
+
+// Dump lock modes
+const (
+	// LOCK_MODE_SINGLE_TRANSACTION - dumps in a consistent read without locking tables
+	LOCK_MODE_SINGLE_TRANSACTION = "single_transaction"
+	// LOCK_MODE_LOCK_TABLES - locks each dumped database's tables for the dump
+	LOCK_MODE_LOCK_TABLES = "lock_tables"
+	// LOCK_MODE_LOCK_ALL_TABLES - locks all tables across databases for the dump
+	LOCK_MODE_LOCK_ALL_TABLES = "lock_all_tables"
+)
+
+// Pipeline store types
+const (
+	STORE_TYPE_FILE  = "file"
+	STORE_TYPE_MYSQL = "mysql"
+)
+
+// Leadership lock types
+const (
+	LOCK_TYPE_FILE  = "file"
+	LOCK_TYPE_MYSQL = "mysql"
+)
+
+// Instance registry types
+const (
+	REGISTRY_TYPE_FILE  = "file"
+	REGISTRY_TYPE_MYSQL = "mysql"
+)
+
+// Sections of pipeline settings, named by their toml keys
+const (
+	SECTION_NAME      = "name"
+	SECTION_DATABASE  = "database"
+	SECTION_DUMP      = "dump"
+	SECTION_SNAPSHOT  = "snapshot"
+	SECTION_SOURCE    = "source"
+	SECTION_KAFKA     = "kafka"
+	SECTION_QUOTA     = "quota"
+	SECTION_EVENT_LOG = "event_log"
+	SECTION_DRAIN     = "drain"
+)
+
+// Sections of instance settings, named by their toml keys
+const (
+	SECTION_STORE         = "store"
+	SECTION_SUPERVISOR    = "supervisor"
+	SECTION_LEADER        = "leader"
+	SECTION_CLUSTER       = "cluster"
+	SECTION_MAX_PIPELINES = "quota.max_pipelines"
+	SECTION_AUTH          = "auth"
+	SECTION_SECRET        = "secret"
+	SECTION_GRPC          = "grpc"
+	SECTION_HEALTH        = "health"
+	SECTION_AUDIT         = "audit"
+	SECTION_RELOAD        = "reload"
+)
@@ -10,4 +10,5 @@ const pkg = "config"
 var (
 	ErrParse    = errortype.ErrorType{Code: 1, Pkg: pkg}
 	ErrNotFound = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrInvalid  = errortype.ErrorType{Code: 3, Pkg: pkg}
 )
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/twothicc/canal/tools/secret"
+)
+
+// Clone - returns a deep copy of c, so that the copy can be changed without affecting c
+func (c *Config) Clone() *Config {
+	clone := *c
+
+	clone.DumpConfig.TableWhere = cloneMap(c.DumpConfig.TableWhere)
+	clone.DumpConfig.ExtraOptions = cloneStrings(c.DumpConfig.ExtraOptions)
+	clone.DumpConfig.SkipTables = cloneStrings(c.DumpConfig.SkipTables)
+	clone.KafkaConfig.BrokerList = cloneStrings(c.KafkaConfig.BrokerList)
+	clone.Sources = cloneSources(c.Sources)
+	clone.AuthConfig.Keys = append([]AuthKey(nil), c.AuthConfig.Keys...)
+	clone.SecretConfig.AllowedEnv = cloneStrings(c.SecretConfig.AllowedEnv)
+	clone.SecretConfig.AllowedDirs = cloneStrings(c.SecretConfig.AllowedDirs)
+	clone.Pipelines = append([]PipelineConfig(nil), c.Pipelines...)
+
+	return &clone
+}
+
+// Pipeline - returns a copy of the defaults in c with the set fields of db and kafka, and
+// sources if any, applied on top
+//
+// The default password is only inherited when db keeps the default addr and user, so that it
+// is never sent to another database, see CheckLogin. c is used as a template and is not modified
+func (c *Config) Pipeline(db DbConfig, kafka KafkaConfig, sources []SourceConfig) *Config {
+	pipelineCfg := c.Clone()
+
+	pipelineCfg.Name = ""
+	pipelineCfg.ServerId = 0
+	// auth, secrets and declarations apply to the instance, not to its pipelines
+	pipelineCfg.AuthConfig = AuthConfig{}
+	pipelineCfg.SecretConfig = SecretConfig{}
+	pipelineCfg.Pipelines = nil
+
+	mergeString(&pipelineCfg.DbConfig.Addr, db.Addr)
+	mergeString(&pipelineCfg.DbConfig.User, db.User)
+	if c.isDefaultLogin(db) {
+		mergeString(&pipelineCfg.DbConfig.Pass, db.Pass)
+	} else {
+		pipelineCfg.DbConfig.Pass = db.Pass
+	}
+	mergeString(&pipelineCfg.DbConfig.Charset, db.Charset)
+	mergeString(&pipelineCfg.DbConfig.Flavor, db.Flavor)
+
+	mergeString(&pipelineCfg.KafkaConfig.Topic, kafka.Topic)
+
+	if len(kafka.BrokerList) > 0 {
+		pipelineCfg.KafkaConfig.BrokerList = cloneStrings(kafka.BrokerList)
+	}
+
+	if kafka.Retry > 0 {
+		pipelineCfg.KafkaConfig.Retry = kafka.Retry
+	}
+
+	if kafka.Flush > 0 {
+		pipelineCfg.KafkaConfig.Flush = kafka.Flush
+	}
+
+	if len(sources) > 0 {
+		pipelineCfg.Sources = cloneSources(sources)
+	}
+
+	return pipelineCfg
+}
+
+// CheckLogin - checks that db has its own password if it overrides the default addr or user
+func (c *Config) CheckLogin(db DbConfig) error {
+	if db.Pass == "" && !c.isDefaultLogin(db) {
+		return ErrInvalid.New("[Config.CheckLogin]pass is required when addr or user differ from the defaults")
+	}
+
+	return nil
+}
+
+// isDefaultLogin - indicates if db connects to the default addr as the default user
+func (c *Config) isDefaultLogin(db DbConfig) bool {
+	return (db.Addr == "" || db.Addr == c.DbConfig.Addr) && (db.User == "" || db.User == c.DbConfig.User)
+}
+
+// Declared - returns the config of each pipeline declared in c, its overrides applied on top
+// of the defaults in c
+func (c *Config) Declared() []*Config {
+	declared := make([]*Config, 0, len(c.Pipelines))
+
+	for _, pipeline := range c.Pipelines {
+		pipelineCfg := c.Pipeline(pipeline.Database, pipeline.Kafka, pipeline.Sources)
+		pipelineCfg.Name = pipeline.Name
+		pipelineCfg.ServerId = pipeline.Id
+
+		declared = append(declared, pipelineCfg)
+	}
+
+	return declared
+}
+
+// Changes - returns the sections of pipeline settings that differ between c and other
+//
+// Instance settings such as store, auth and cluster are not compared. Empty lists are equal
+// to missing ones
+func (c *Config) Changes(other *Config) []string {
+	a, b := c.normalized(), other.normalized()
+
+	sections := []struct {
+		name string
+		a, b interface{}
+	}{
+		{SECTION_NAME, a.Name, b.Name},
+		{SECTION_DATABASE, a.DbConfig, b.DbConfig},
+		{SECTION_DUMP, a.DumpConfig, b.DumpConfig},
+		{SECTION_SNAPSHOT, a.SnapshotConfig, b.SnapshotConfig},
+		{SECTION_SOURCE, a.Sources, b.Sources},
+		{SECTION_KAFKA, a.KafkaConfig, b.KafkaConfig},
+		{SECTION_QUOTA, a.QuotaConfig, b.QuotaConfig},
+		{SECTION_EVENT_LOG, a.EventLogConfig, b.EventLogConfig},
+		{SECTION_DRAIN, a.DrainConfig, b.DrainConfig},
+	}
+
+	var changes []string
+
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.a, section.b) {
+			changes = append(changes, section.name)
+		}
+	}
+
+	return changes
+}
+
+// InstanceChanges - returns the sections of instance settings that differ between c and other
+//
+// Secrets that are not read from the config file, such as the store password and auth key
+// secrets, are not compared
+func (c *Config) InstanceChanges(other *Config) []string {
+	a, b := c.instance(), other.instance()
+
+	sections := []struct {
+		name string
+		a, b interface{}
+	}{
+		{SECTION_STORE, a.StoreConfig, b.StoreConfig},
+		{SECTION_SUPERVISOR, a.SupervisorConfig, b.SupervisorConfig},
+		{SECTION_LEADER, a.LeaderConfig, b.LeaderConfig},
+		{SECTION_CLUSTER, a.ClusterConfig, b.ClusterConfig},
+		{SECTION_MAX_PIPELINES, a.QuotaConfig.MaxPipelines, b.QuotaConfig.MaxPipelines},
+		{SECTION_AUTH, a.AuthConfig, b.AuthConfig},
+		{SECTION_SECRET, a.SecretConfig, b.SecretConfig},
+		{SECTION_GRPC, a.GrpcConfig, b.GrpcConfig},
+		{SECTION_HEALTH, a.HealthConfig, b.HealthConfig},
+		{SECTION_AUDIT, a.AuditConfig, b.AuditConfig},
+		{SECTION_RELOAD, a.ReloadConfig, b.ReloadConfig},
+	}
+
+	var changes []string
+
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.a, section.b) {
+			changes = append(changes, section.name)
+		}
+	}
+
+	return changes
+}
+
+// validatePipelines - checks that declared pipelines have unique names and ids, valid
+// password references and sources, given their own or the defaults, and their own password
+// if they connect as another login
+func (c *Config) validatePipelines() error {
+	names := make(map[string]bool, len(c.Pipelines))
+	ids := make(map[uint32]bool, len(c.Pipelines))
+
+	for i, pipeline := range c.Pipelines {
+		switch {
+		case pipeline.Name == "":
+			return ErrInvalid.New(fmt.Sprintf("[Config.validatePipelines]pipeline %d is missing a name", i))
+		case names[pipeline.Name]:
+			return ErrInvalid.New(fmt.Sprintf("[Config.validatePipelines]pipeline name %s is declared twice", pipeline.Name))
+		case pipeline.Id == 0:
+			return ErrInvalid.New(fmt.Sprintf("[Config.validatePipelines]pipeline %s is missing an id", pipeline.Name))
+		case ids[pipeline.Id]:
+			return ErrInvalid.New(fmt.Sprintf("[Config.validatePipelines]pipeline id %d is declared twice", pipeline.Id))
+		case len(pipeline.Sources) == 0 && len(c.Sources) == 0:
+			return ErrInvalid.New(fmt.Sprintf("[Config.validatePipelines]pipeline %s has no sources", pipeline.Name))
+		}
+
+		if err := secret.Check(pipeline.Database.Pass); err != nil {
+			return ErrInvalid.New(fmt.Sprintf("[Config.validatePipelines]pipeline %s: %s", pipeline.Name, err.Error()))
+		}
+
+		if err := c.CheckLogin(pipeline.Database); err != nil {
+			return ErrInvalid.New(fmt.Sprintf("[Config.validatePipelines]pipeline %s: %s", pipeline.Name, err.Error()))
+		}
+
+		names[pipeline.Name] = true
+		ids[pipeline.Id] = true
+	}
+
+	return nil
+}
+
+// normalized - returns a copy of c with empty lists and maps set to nil
+func (c *Config) normalized() *Config {
+	n := c.Clone()
+
+	if len(n.DumpConfig.TableWhere) == 0 {
+		n.DumpConfig.TableWhere = nil
+	}
+
+	n.DumpConfig.ExtraOptions = nilIfEmpty(n.DumpConfig.ExtraOptions)
+	n.DumpConfig.SkipTables = nilIfEmpty(n.DumpConfig.SkipTables)
+	n.KafkaConfig.BrokerList = nilIfEmpty(n.KafkaConfig.BrokerList)
+
+	if len(n.Sources) == 0 {
+		n.Sources = nil
+	}
+
+	for i := range n.Sources {
+		n.Sources[i].Tables = nilIfEmpty(n.Sources[i].Tables)
+	}
+
+	return n
+}
+
+// instance - returns a copy of c without the secrets that are not read from the config file
+func (c *Config) instance() *Config {
+	n := c.Clone()
+
+	n.StoreConfig.Pass = ""
+
+	if len(n.AuthConfig.Keys) == 0 {
+		n.AuthConfig.Keys = nil
+	}
+
+	n.SecretConfig.AllowedEnv = nilIfEmpty(n.SecretConfig.AllowedEnv)
+	n.SecretConfig.AllowedDirs = nilIfEmpty(n.SecretConfig.AllowedDirs)
+
+	for i := range n.AuthConfig.Keys {
+		n.AuthConfig.Keys[i].Secret = ""
+	}
+
+	return n
+}
+
+func nilIfEmpty(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	return s
+}
+
+func mergeString(dst *string, src string) {
+	if src != "" {
+		*dst = src
+	}
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append(make([]string, 0, len(s)), s...)
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+
+	return clone
+}
+
+func cloneSources(sources []SourceConfig) []SourceConfig {
+	if sources == nil {
+		return nil
+	}
+
+	clone := make([]SourceConfig, len(sources))
+	for i, source := range sources {
+		clone[i] = SourceConfig{
+			Schema: source.Schema,
+			Tables: cloneStrings(source.Tables),
+		}
+	}
+
+	return clone
+}
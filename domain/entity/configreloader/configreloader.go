@@ -0,0 +1,249 @@
+package configreloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// ReloadStatus - result of the latest reload of the config file
+//
+// Pipelines is nil when no pipelines are declared, as only declared pipelines are reconciled.
+// Unapplied holds the changed instance sections, which only apply on restart. IsApplied is
+// false if the file was invalid, leaving everything as it was, or if any pipeline failed
+type ReloadStatus struct {
+	Time      time.Time
+	Pipelines *synccontroller.ReconcileResult
+	Trigger   string
+	Error     string
+	LogLevel  string
+	Unapplied []string
+	Reloads   uint64
+	IsApplied bool
+}
+
+// ConfigReloader - reloads the config file, applying changed pipelines and log level
+type ConfigReloader interface {
+	Reload(ctx context.Context, trigger string) *ReloadStatus
+	Status() *ReloadStatus
+	Watch(ctx context.Context)
+	Close()
+}
+
+type configReloader struct {
+	syncController synccontroller.SyncController
+	bootCfg        *config.Config
+	status         *ReloadStatus
+	stopWatch      chan struct{}
+	path           string
+	hash           [sha256.Size]byte
+	watchInterval  time.Duration
+	logLevel       string
+	mu             sync.Mutex
+	statusMu       sync.RWMutex
+	stopOnce       sync.Once
+	isClosed       bool
+}
+
+// NewConfigReloader - creates a ConfigReloader of the file at path, which bootCfg was loaded from
+//
+// bootCfg is kept to tell which instance sections changed since start and is not modified
+func NewConfigReloader(
+	ctx context.Context,
+	path string,
+	bootCfg *config.Config,
+	syncController synccontroller.SyncController,
+) ConfigReloader {
+	r := &configReloader{
+		syncController: syncController,
+		bootCfg:        bootCfg,
+		stopWatch:      make(chan struct{}),
+		path:           path,
+		watchInterval:  time.Duration(bootCfg.ReloadConfig.WatchInterval) * time.Millisecond,
+		logLevel:       bootCfg.LogConfig.Level,
+		status: &ReloadStatus{
+			Time:      time.Now(),
+			Trigger:   TRIGGER_BOOT,
+			LogLevel:  bootCfg.LogConfig.Level,
+			IsApplied: true,
+		},
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		r.hash = sha256.Sum256(data)
+	} else {
+		logger.WithContext(ctx).Warn("[ConfigReloader.NewConfigReloader]fail to read config file", zap.Error(err))
+	}
+
+	return r
+}
+
+// Status - returns the result of the latest reload
+func (r *configReloader) Status() *ReloadStatus {
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
+
+	status := *r.status
+
+	return &status
+}
+
+// Reload - reads and validates the config file, then applies its log level and reconciles
+// its declared pipelines, so that only the pipelines that changed are rebuilt
+//
+// An invalid file is reported and leaves everything as it was. Once closed, nothing is reloaded
+// and the result of the latest reload is returned
+func (r *configReloader) Reload(ctx context.Context, trigger string) *ReloadStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isClosed {
+		return r.Status()
+	}
+
+	logger.WithContext(ctx).Info("[ConfigReloader.Reload]reloading config", zap.String("trigger", trigger), zap.String("path", r.path))
+
+	status := &ReloadStatus{
+		Time:     time.Now(),
+		Trigger:  trigger,
+		LogLevel: r.logLevel,
+		Reloads:  r.Status().Reloads + 1,
+	}
+
+	if err := r.reload(ctx, status); err != nil {
+		logger.WithContext(ctx).Error("[ConfigReloader.Reload]fail to reload config", zap.String("trigger", trigger), zap.Error(err))
+
+		status.Error = err.Error()
+	} else {
+		status.IsApplied = status.Pipelines == nil || len(status.Pipelines.Failed) == 0
+	}
+
+	fields := []zap.Field{
+		zap.String("trigger", trigger),
+		zap.Bool("is applied", status.IsApplied),
+		zap.String("log level", status.LogLevel),
+		zap.Strings("unapplied", status.Unapplied),
+	}
+
+	if res := status.Pipelines; res != nil {
+		fields = append(fields,
+			zap.Uint32s("created", res.Created),
+			zap.Uint32s("updated", res.Updated),
+			zap.Uint32s("started", res.Started),
+			zap.Uint32s("stopped", res.Stopped),
+			zap.Uint32s("unchanged", res.Unchanged),
+			zap.Any("failed", res.Failed),
+		)
+	}
+
+	logger.WithContext(ctx).Info("[ConfigReloader.Reload]reloaded config", fields...)
+
+	r.statusMu.Lock()
+	r.status = status
+	r.statusMu.Unlock()
+
+	return r.Status()
+}
+
+// reload - see Reload, filling in status. Must be called with r.mu held
+func (r *configReloader) reload(ctx context.Context, status *ReloadStatus) error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return ErrFile.New(fmt.Sprintf("[ConfigReloader.reload]%s", err.Error()))
+	}
+
+	// a file that fails to parse is not retried until it changes again
+	r.hash = sha256.Sum256(data)
+
+	newCfg, err := config.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	// the store and its password belong to this instance until restarted
+	newCfg.StoreConfig = r.bootCfg.StoreConfig
+
+	status.Unapplied = r.bootCfg.InstanceChanges(newCfg)
+
+	if newCfg.LogConfig.Level != r.logLevel {
+		// validated when loaded
+		level, _ := newCfg.LogConfig.ZapLevel()
+
+		logger.InitLogger(level)
+
+		r.logLevel = newCfg.LogConfig.Level
+		status.LogLevel = r.logLevel
+	}
+
+	declared := newCfg.Declared()
+	if len(declared) == 0 {
+		return nil
+	}
+
+	res, err := r.syncController.Reconcile(ctx, declared)
+	if err != nil {
+		return err
+	}
+
+	status.Pipelines = res
+
+	return nil
+}
+
+// Watch - reloads whenever the content of the config file changes, checking every watch
+// interval until closed. Returns immediately if watching is disabled
+func (r *configReloader) Watch(ctx context.Context) {
+	if r.watchInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.stopWatch:
+			return
+		}
+
+		if r.isChanged(ctx) {
+			r.Reload(ctx, TRIGGER_FILE)
+		}
+	}
+}
+
+// Close - stops watching and reloading the config file, waiting for a reload in progress
+func (r *configReloader) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stopWatch)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.isClosed = true
+}
+
+// isChanged - returns whether the content of the config file differs from when it was last read
+func (r *configReloader) isChanged(ctx context.Context) bool {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		logger.WithContext(ctx).Warn("[ConfigReloader.isChanged]fail to read config file", zap.Error(err))
+
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return sha256.Sum256(data) != r.hash
+}
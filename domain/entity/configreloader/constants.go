@@ -0,0 +1,8 @@
+package configreloader
+
+// Reload triggers
+const (
+	TRIGGER_BOOT   = "boot"
+	TRIGGER_SIGNAL = "signal"
+	TRIGGER_FILE   = "file"
+)
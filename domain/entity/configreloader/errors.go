@@ -0,0 +1,12 @@
+package configreloader
+
+import (
+	"github.com/twothicc/common-go/errortype"
+)
+
+const pkg = "domain/entity/configreloader"
+
+//nolint:gomnd // error code
+var (
+	ErrFile = errortype.ErrorType{Code: 1, Pkg: pkg}
+)
@@ -0,0 +1,346 @@
+package synccontroller
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/twothicc/canal/domain/entity/synccontroller/pipelinestore"
+	"github.com/twothicc/canal/domain/entity/synccontroller/registry"
+	"github.com/twothicc/canal/tools/metrics"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// ClusterStatus - instances sharing pipelines and the instance each pipeline is assigned to
+type ClusterStatus struct {
+	Assignment map[uint32]string
+	InstanceId string
+	Instances  []*registry.Instance
+}
+
+// Cluster - returns the cluster-wide assignment as of the last heartbeat
+func (s *syncController) Cluster() *ClusterStatus {
+	s.mu.Lock()
+	ids := make([]uint32, 0, len(s.syncmanagers))
+
+	for id := range s.syncmanagers {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	members := s.members()
+
+	for _, member := range members {
+		ids = append(ids, member.Pipelines...)
+	}
+
+	assignment := make(map[uint32]string, len(ids))
+	for _, id := range ids {
+		assignment[id] = assign(members, id)
+	}
+
+	return &ClusterStatus{
+		InstanceId: s.instanceId,
+		Instances:  members,
+		Assignment: assignment,
+	}
+}
+
+// heartbeatLoop - keeps this instance registered and its view of the cluster fresh until ctx is done
+func (s *syncController) heartbeatLoop(ctx context.Context) {
+	defer close(s.heartbeatDone)
+
+	ticker := time.NewTicker(s.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.heartbeat(ctx)
+		s.adopt(ctx)
+	}
+}
+
+// heartbeat - registers the pipelines this instance leads and refreshes the live instances
+//
+// The last known instances are kept if the registry cannot be reached
+func (s *syncController) heartbeat(ctx context.Context) {
+	if err := s.registry.Heartbeat(ctx, &registry.Instance{
+		Id:        s.instanceId,
+		Addr:      s.instanceAddr,
+		Pipelines: s.leading(),
+		Heartbeat: time.Now().UnixMilli(),
+	}); err != nil {
+		logger.WithContext(ctx).Error("[SyncController.heartbeat]fail to register instance", zap.Error(err))
+
+		return
+	}
+
+	instances, err := s.registry.List(ctx)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncController.heartbeat]fail to list instances", zap.Error(err))
+
+		return
+	}
+
+	s.membersMu.Lock()
+	defer s.membersMu.Unlock()
+
+	if !isSameMembers(s.instances, instances) {
+		logger.WithContext(ctx).Info("[SyncController.heartbeat]cluster membership changed, rebalancing",
+			zap.Int("instances", len(instances)),
+		)
+	}
+
+	s.instances = instances
+}
+
+// adopt - brings the pipelines of this instance in line with a shared store
+//
+// Pipelines defined by other instances are restored, and only run here once they are assigned
+// to this instance. Pipelines deleted through another instance are removed, and those another
+// instance started, stopped or paused are supervised, closed or paused to match
+func (s *syncController) adopt(ctx context.Context) {
+	if !s.isClustered {
+		return
+	}
+
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncController.adopt]fail to list pipelines", zap.Error(err))
+
+		return
+	}
+
+	isStored := make(map[uint32]bool, len(pipelines))
+
+	for _, pipeline := range pipelines {
+		isStored[pipeline.Config.ServerId] = true
+
+		s.mu.Lock()
+		_, isKnown := s.syncmanagers[pipeline.Config.ServerId]
+		s.mu.Unlock()
+
+		if isKnown {
+			s.follow(ctx, pipeline)
+		} else {
+			s.restore(ctx, pipeline)
+		}
+	}
+
+	s.mu.Lock()
+	ids := make([]uint32, 0, len(s.syncmanagers))
+
+	for id := range s.syncmanagers {
+		if !isStored[id] {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		s.forget(ctx, id)
+	}
+}
+
+// follow - supervises or halts a known pipeline to match its stored desired state, without
+// persisting it again
+//
+// The definition is read again once the pipeline is marked transitioning, as pipeline may
+// predate a change made here since. Pipelines already being changed are left to the next
+// heartbeat
+func (s *syncController) follow(ctx context.Context, pipeline *pipelinestore.Pipeline) {
+	id := pipeline.Config.ServerId
+
+	if s.isSupervised(id) == (pipeline.DesiredState == pipelinestore.DESIRED_RUNNING) {
+		s.mu.Lock()
+		s.owners[id] = ownership{
+			origin:              pipeline.GetOrigin(),
+			isStoppedByOperator: pipeline.IsStoppedByOperator,
+		}
+		s.mu.Unlock()
+
+		return
+	}
+
+	if _, err := s.begin(id); err != nil {
+		return
+	}
+
+	defer s.end(id)
+
+	pipeline, err := s.definition(ctx, id)
+	if err != nil || pipeline == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.owners[id] = ownership{
+		origin:              pipeline.GetOrigin(),
+		isStoppedByOperator: pipeline.IsStoppedByOperator,
+	}
+	s.mu.Unlock()
+
+	isRunning := pipeline.DesiredState == pipelinestore.DESIRED_RUNNING
+	if s.isSupervised(id) == isRunning {
+		return
+	}
+
+	logger.WithContext(ctx).Info(
+		"[SyncController.follow]desired state changed through another instance",
+		zap.Uint32("id", id),
+		zap.String("desired state", pipeline.DesiredState),
+	)
+
+	if !isRunning {
+		manager := s.detach(id)
+
+		if pipeline.DesiredState == pipelinestore.DESIRED_PAUSED {
+			manager.Pause()
+		} else {
+			manager.Close()
+		}
+
+		return
+	}
+
+	manager, err := s.reopen(ctx, s.current(id))
+	if err != nil {
+		return
+	}
+
+	// only redo the legacy sync if it never got as far as saving a checkpoint
+	s.ensureSupervised(ctx, id, manager, pipeline.IsLegacySync && manager.Checkpoint().Name == "")
+}
+
+// forget - removes a pipeline deleted through another instance, leaving the store as it is
+func (s *syncController) forget(ctx context.Context, id uint32) {
+	if _, err := s.begin(id); err != nil {
+		return
+	}
+
+	defer s.end(id)
+
+	// the pipeline may have been added here since the store was listed
+	if pipeline, err := s.definition(ctx, id); err != nil || pipeline != nil {
+		return
+	}
+
+	logger.WithContext(ctx).Info("[SyncController.forget]pipeline deleted through another instance, removing", zap.Uint32("id", id))
+
+	s.detach(id).Close()
+
+	s.mu.Lock()
+	delete(s.syncmanagers, id)
+	delete(s.supervisions, id)
+	delete(s.owners, id)
+	s.mu.Unlock()
+
+	metrics.DeletePipeline(id)
+}
+
+// isAssigned - indicates if pipeline id is assigned to this instance
+//
+// Every pipeline is assigned to this instance until the cluster is known
+func (s *syncController) isAssigned(id uint32) bool {
+	members := s.members()
+	if len(members) == 0 {
+		return true
+	}
+
+	return assign(members, id) == s.instanceId
+}
+
+// leading - returns the sorted ids of pipelines this instance leads
+func (s *syncController) leading() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []uint32{}
+
+	for id, sup := range s.supervisions {
+		if sup.role == ROLE_LEADER && sup.ctx.Err() == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	return ids
+}
+
+func (s *syncController) members() []*registry.Instance {
+	s.membersMu.RLock()
+	defer s.membersMu.RUnlock()
+
+	members := make([]*registry.Instance, len(s.instances))
+	copy(members, s.instances)
+
+	return members
+}
+
+// assign - returns the id of the instance pipeline id is assigned to
+//
+// Uses rendezvous hashing, so that instances joining or leaving only move the pipelines
+// assigned to them
+func assign(members []*registry.Instance, id uint32) string {
+	var (
+		owner     string
+		bestScore uint64
+	)
+
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, id)
+
+	for _, member := range members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(member.Id))
+		_, _ = h.Write(key)
+
+		if score := mix(h.Sum64()); owner == "" || score > bestScore {
+			owner = member.Id
+			bestScore = score
+		}
+	}
+
+	return owner
+}
+
+// mix - spreads hashes of similar inputs apart (splitmix64 finalizer)
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
+
+func isSameMembers(prev, curr []*registry.Instance) bool {
+	if len(prev) != len(curr) {
+		return false
+	}
+
+	ids := make(map[string]bool, len(prev))
+	for _, instance := range prev {
+		ids[instance.Id] = true
+	}
+
+	for _, instance := range curr {
+		if !ids[instance.Id] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,30 @@
+package synccontroller
+
+import "time"
+
+// Supervisor defaults
+const (
+	DEFAULT_INITIAL_BACKOFF = 1 * time.Second
+	DEFAULT_MAX_BACKOFF     = 1 * time.Minute
+	DEFAULT_MULTIPLIER      = 2.0
+)
+
+// Leadership constants
+const (
+	DEFAULT_RENEW_INTERVAL = 2 * time.Second
+	ROLE_LEADER            = "leader"
+	ROLE_STANDBY           = "standby"
+)
+
+// Cluster constants
+const (
+	DEFAULT_HEARTBEAT_INTERVAL = 3 * time.Second
+)
+
+// Reconcile actions
+const (
+	ACTION_CREATED   = "created"
+	ACTION_UPDATED   = "updated"
+	ACTION_STARTED   = "started"
+	ACTION_UNCHANGED = "unchanged"
+)
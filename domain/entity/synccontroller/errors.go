@@ -8,5 +8,9 @@ const pkg = "domain/entity/synccontroller"
 
 //nolint:gomnd // error code
 var (
-	ErrParam = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrParam    = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrQuota    = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrBusy     = errortype.ErrorType{Code: 3, Pkg: pkg}
+	ErrExists   = errortype.ErrorType{Code: 4, Pkg: pkg}
+	ErrNotFound = errortype.ErrorType{Code: 5, Pkg: pkg}
 )
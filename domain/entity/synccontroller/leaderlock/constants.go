@@ -0,0 +1,23 @@
+package leaderlock
+
+import "time"
+
+const (
+	DEFAULT_LEASE_TIMEOUT = 10 * time.Second
+	INSTANCE_ID_FORMAT    = "%s:%d"
+)
+
+// File lock constants
+const (
+	DEFAULT_DIR       = "./leases"
+	LEASE_FILE_FORMAT = "%d.lock"
+	FILE_PERMISSION   = 0o644
+)
+
+// MySQL lock constants
+const (
+	LOCK_NAME_FORMAT = "canal_pipeline_%d"
+	WAIT_TIMEOUT_SQL = "SET SESSION wait_timeout = ?"
+	GET_LOCK_SQL     = "SELECT GET_LOCK(?, 0)"
+	RELEASE_LOCK_SQL = "SELECT RELEASE_LOCK(?)"
+)
@@ -0,0 +1,13 @@
+package leaderlock
+
+import "github.com/twothicc/common-go/errortype"
+
+const pkg = "domain/entity/synccontroller/leaderlock"
+
+//nolint:gomnd // error code
+var (
+	ErrFile    = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrQuery   = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrConfig  = errortype.ErrorType{Code: 3, Pkg: pkg}
+	ErrConnect = errortype.ErrorType{Code: 4, Pkg: pkg}
+)
@@ -0,0 +1,178 @@
+package leaderlock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/siddontang/go/ioutil2"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// lease - contents of a lease file
+type lease struct {
+	Owner  string `toml:"owner"`
+	Expiry int64  `toml:"expiry"`
+}
+
+// fileLock - leases each pipeline through a file in dir, for instances sharing a filesystem
+//
+// Meant for local testing, takeover of an expired lease is not atomic across instances
+type fileLock struct {
+	dir          string
+	owner        string
+	leaseTimeout time.Duration
+	mu           sync.Mutex
+}
+
+func NewFileLock(ctx context.Context, dir string, owner string, leaseTimeout time.Duration) (ILeaderLock, error) {
+	if dir == "" {
+		dir = DEFAULT_DIR
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		logger.WithContext(ctx).Error("[FileLock.NewFileLock]fail to create/find dir", zap.Error(err))
+
+		return nil, ErrFile.New(fmt.Sprintf("[FileLock.NewFileLock]%s", err.Error()))
+	}
+
+	return &fileLock{
+		dir:          dir,
+		owner:        owner,
+		leaseTimeout: leaseTimeout,
+	}, nil
+}
+
+func (f *fileLock) Acquire(ctx context.Context, id uint32) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	filePath := f.filePath(id)
+
+	current, err := f.read(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	if current == nil {
+		return f.create(ctx, filePath)
+	}
+
+	if current.Owner != f.owner && time.Now().UnixMilli() < current.Expiry {
+		return false, nil
+	}
+
+	data, err := f.encode()
+	if err != nil {
+		return false, err
+	}
+
+	if err := ioutil2.WriteFileAtomic(filePath, data, FILE_PERMISSION); err != nil {
+		logger.WithContext(ctx).Error("[FileLock.Acquire]fail to write lease", zap.Uint32("server id", id), zap.Error(err))
+
+		return false, ErrFile.New(fmt.Sprintf("[FileLock.Acquire]%s", err.Error()))
+	}
+
+	// another instance may have taken over the expired lease at the same time
+	current, err = f.read(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	return current != nil && current.Owner == f.owner, nil
+}
+
+func (f *fileLock) Release(ctx context.Context, id uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	filePath := f.filePath(id)
+
+	current, err := f.read(filePath)
+	if err != nil || current == nil || current.Owner != f.owner {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.WithContext(ctx).Error("[FileLock.Release]fail to remove lease", zap.Uint32("server id", id), zap.Error(err))
+
+		return ErrFile.New(fmt.Sprintf("[FileLock.Release]%s", err.Error()))
+	}
+
+	return nil
+}
+
+func (f *fileLock) Owner() string {
+	return f.owner
+}
+
+func (f *fileLock) Close() error {
+	return nil
+}
+
+// create - takes a lease that does not exist yet, failing if another instance creates it first
+func (f *fileLock) create(ctx context.Context, filePath string) (bool, error) {
+	data, err := f.encode()
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, FILE_PERMISSION)
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	} else if err != nil {
+		logger.WithContext(ctx).Error("[FileLock.create]fail to create lease", zap.String("path", filePath), zap.Error(err))
+
+		return false, ErrFile.New(fmt.Sprintf("[FileLock.create]%s", err.Error()))
+	}
+
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return false, ErrFile.New(fmt.Sprintf("[FileLock.create]%s", err.Error()))
+	}
+
+	return true, nil
+}
+
+// read - returns the lease in filePath, or nil if there is none
+func (f *fileLock) read(filePath string) (*lease, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, ErrFile.New(fmt.Sprintf("[FileLock.read]%s", err.Error()))
+	}
+
+	var current lease
+
+	// a lease still being created decodes as expired
+	_, _ = toml.Decode(string(data), &current)
+
+	return &current, nil
+}
+
+func (f *fileLock) encode() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := toml.NewEncoder(&buf).Encode(lease{
+		Owner:  f.owner,
+		Expiry: time.Now().Add(f.leaseTimeout).UnixMilli(),
+	}); err != nil {
+		return nil, ErrFile.New(fmt.Sprintf("[FileLock.encode]%s", err.Error()))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f *fileLock) filePath(id uint32) string {
+	return path.Join(f.dir, fmt.Sprintf(LEASE_FILE_FORMAT, id))
+}
@@ -0,0 +1,85 @@
+package leaderlock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/twothicc/canal/config"
+)
+
+// ILeaderLock - per-pipeline leadership shared by redundant instances
+//
+// Only the instance holding a pipeline's lock may run it. The lock is a lease that lapses
+// if its holder stops renewing it, letting a standby take over
+type ILeaderLock interface {
+	// Acquire - takes or renews the lock on pipeline id, returning false if another instance holds it
+	Acquire(ctx context.Context, id uint32) (bool, error)
+	// Release - gives up the lock on pipeline id if held
+	Release(ctx context.Context, id uint32) error
+	// Owner - identifies this instance as a lock holder
+	Owner() string
+	Close() error
+}
+
+// NewLeaderLock - creates the leadership lock configured by leaderCfg
+//
+// The mysql lock is taken on the database of storeCfg. An empty lock type creates a lock
+// that is always held, for running a single instance
+func NewLeaderLock(
+	ctx context.Context,
+	leaderCfg config.LeaderConfig,
+	storeCfg config.StoreConfig,
+) (ILeaderLock, error) {
+	owner := leaderCfg.InstanceId
+	if owner == "" {
+		owner = defaultInstanceId()
+	}
+
+	leaseTimeout := DEFAULT_LEASE_TIMEOUT
+	if leaderCfg.LeaseTimeout > 0 {
+		leaseTimeout = time.Duration(leaderCfg.LeaseTimeout) * time.Millisecond
+	}
+
+	switch leaderCfg.Type {
+	case "":
+		return &localLock{owner: owner}, nil
+	case config.LOCK_TYPE_FILE:
+		return NewFileLock(ctx, leaderCfg.Dir, owner, leaseTimeout)
+	case config.LOCK_TYPE_MYSQL:
+		return NewMySQLLock(ctx, storeCfg, owner, leaseTimeout)
+	default:
+		return nil, ErrConfig.New(fmt.Sprintf("[NewLeaderLock]invalid lock type %s", leaderCfg.Type))
+	}
+}
+
+// localLock - lock for a single instance, which always leads
+type localLock struct {
+	owner string
+}
+
+func (l *localLock) Acquire(_ context.Context, _ uint32) (bool, error) {
+	return true, nil
+}
+
+func (l *localLock) Release(_ context.Context, _ uint32) error {
+	return nil
+}
+
+func (l *localLock) Owner() string {
+	return l.owner
+}
+
+func (l *localLock) Close() error {
+	return nil
+}
+
+func defaultInstanceId() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return fmt.Sprintf(INSTANCE_ID_FORMAT, hostname, os.Getpid())
+}
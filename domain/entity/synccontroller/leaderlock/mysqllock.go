@@ -0,0 +1,177 @@
+package leaderlock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// mySQLLock - locks each pipeline with GET_LOCK on a single session
+//
+// Named locks are released by MySQL when the session ends. The session's wait_timeout is
+// set to the lease timeout, so the locks of an instance that stops renewing them lapse
+// even if its connection is never closed
+type mySQLLock struct {
+	conn         *client.Conn
+	held         map[uint32]bool
+	storeCfg     config.StoreConfig
+	owner        string
+	leaseTimeout time.Duration
+	mu           sync.Mutex
+}
+
+func NewMySQLLock(
+	ctx context.Context,
+	storeCfg config.StoreConfig,
+	owner string,
+	leaseTimeout time.Duration,
+) (ILeaderLock, error) {
+	lock := &mySQLLock{
+		held:         make(map[uint32]bool),
+		storeCfg:     storeCfg,
+		owner:        owner,
+		leaseTimeout: leaseTimeout,
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	if _, err := lock.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// Acquire - takes the lock on pipeline id, or checks that the session holding it is still alive
+//
+// Locks held before a lost connection are reported as lost, since another instance may
+// have taken them in the meantime
+func (m *mySQLLock) Acquire(ctx context.Context, id uint32) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	isReconnected, err := m.connect(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if m.held[id] {
+		return true, nil
+	} else if isReconnected {
+		return false, nil
+	}
+
+	res, err := m.conn.Execute(GET_LOCK_SQL, lockName(id))
+	if err != nil {
+		logger.WithContext(ctx).Error("[MySQLLock.Acquire]fail to get lock", zap.Uint32("server id", id), zap.Error(err))
+
+		return false, ErrQuery.New(fmt.Sprintf("[MySQLLock.Acquire]%s", err.Error()))
+	}
+
+	defer res.Close()
+
+	isAcquired, _ := res.GetInt(0, 0)
+	m.held[id] = isAcquired == 1
+
+	return m.held[id], nil
+}
+
+func (m *mySQLLock) Release(ctx context.Context, id uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.held[id] {
+		return nil
+	}
+
+	delete(m.held, id)
+
+	if m.conn == nil {
+		return nil
+	}
+
+	res, err := m.conn.Execute(RELEASE_LOCK_SQL, lockName(id))
+	if err != nil {
+		logger.WithContext(ctx).Error("[MySQLLock.Release]fail to release lock", zap.Uint32("server id", id), zap.Error(err))
+
+		return ErrQuery.New(fmt.Sprintf("[MySQLLock.Release]%s", err.Error()))
+	}
+
+	res.Close()
+
+	return nil
+}
+
+func (m *mySQLLock) Owner() string {
+	return m.owner
+}
+
+// Close - ends the session, releasing every lock held
+func (m *mySQLLock) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.held = make(map[uint32]bool)
+
+	if m.conn == nil {
+		return nil
+	}
+
+	err := m.conn.Close()
+	m.conn = nil
+
+	return err
+}
+
+// connect - opens a session if there is none or it was lost, dropping the locks held by the lost session
+//
+// Returns true if a lost session was replaced. Must be called with m.mu held
+func (m *mySQLLock) connect(ctx context.Context) (bool, error) {
+	isReconnected := false
+
+	if m.conn != nil && m.conn.Ping() != nil {
+		logger.WithContext(ctx).Error("[MySQLLock.connect]lost session, locks were released", zap.Int("locks", len(m.held)))
+
+		m.conn.Close()
+		m.conn = nil
+		m.held = make(map[uint32]bool)
+		isReconnected = true
+	}
+
+	if m.conn != nil {
+		return false, nil
+	}
+
+	conn, err := client.Connect(m.storeCfg.Addr, m.storeCfg.User, m.storeCfg.Pass, m.storeCfg.Database)
+	if err != nil {
+		logger.WithContext(ctx).Error("[MySQLLock.connect]fail to connect", zap.Error(err))
+
+		return isReconnected, ErrConnect.New(fmt.Sprintf("[MySQLLock.connect]%s", err.Error()))
+	}
+
+	waitTimeout := uint64(m.leaseTimeout / time.Second)
+	if waitTimeout == 0 {
+		waitTimeout = 1
+	}
+
+	if _, err := conn.Execute(WAIT_TIMEOUT_SQL, waitTimeout); err != nil {
+		conn.Close()
+
+		return isReconnected, ErrQuery.New(fmt.Sprintf("[MySQLLock.connect]%s", err.Error()))
+	}
+
+	m.conn = conn
+
+	return isReconnected, nil
+}
+
+func lockName(id uint32) string {
+	return fmt.Sprintf(LOCK_NAME_FORMAT, id)
+}
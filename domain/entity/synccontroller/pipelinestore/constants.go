@@ -0,0 +1,43 @@
+package pipelinestore
+
+// Desired states of a pipeline
+const (
+	DESIRED_RUNNING = "running"
+	DESIRED_STOPPED = "stopped"
+	DESIRED_PAUSED  = "paused"
+)
+
+// Origins of a pipeline
+const (
+	// ORIGIN_FILE - declared in the config file, and managed by reconciling it
+	ORIGIN_FILE = "file"
+	// ORIGIN_API - created through the control plane, and left alone by reconciling
+	ORIGIN_API = "api"
+)
+
+// File store constants
+const (
+	DEFAULT_DIR          = "./pipelines"
+	FILE_EXT             = ".toml"
+	PIPELINE_FILE_FORMAT = "%d" + FILE_EXT
+	// SEQ_FILE - holds the last allocated id, so that ids of deleted pipelines are not reused
+	SEQ_FILE        = ".seq"
+	BASE10          = 10
+	BIT32           = 32
+	FILE_PERMISSION = 0o644
+)
+
+// MySQL store constants
+const (
+	DEFAULT_TABLE    = "pipelines"
+	CREATE_TABLE_SQL = "CREATE TABLE IF NOT EXISTS `%s` (`id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, `definition` TEXT NOT NULL, `mtime` INT UNSIGNED NOT NULL)"
+	// tables created before ids were allocated from the store
+	AUTO_INCREMENT_SQL = "ALTER TABLE `%s` MODIFY `id` INT UNSIGNED NOT NULL AUTO_INCREMENT"
+	// ALLOCATE_SQL - reserves an id with an empty definition
+	ALLOCATE_SQL = "INSERT INTO `%s` (`definition`, `mtime`) VALUES ('', ?)"
+	RESERVE_SQL  = "INSERT IGNORE INTO `%s` (`id`, `definition`, `mtime`) VALUES (?, '', ?)"
+	CREATE_SQL   = "UPDATE `%s` SET `definition` = ?, `mtime` = ? WHERE `id` = ? AND `definition` = ''"
+	UPDATE_SQL   = "UPDATE `%s` SET `definition` = ?, `mtime` = ? WHERE `id` = ? AND `definition` != ''"
+	DELETE_SQL   = "DELETE FROM `%s` WHERE `id` = ?"
+	SELECT_SQL   = "SELECT `definition` FROM `%s` WHERE `definition` != '' ORDER BY `id`"
+)
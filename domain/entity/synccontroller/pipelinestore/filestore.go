@@ -0,0 +1,240 @@
+package pipelinestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/siddontang/go/ioutil2"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// fileStore - stores each pipeline definition as a toml file in dir
+//
+// Reserved ids have an empty file, and the last allocated id is kept in SEQ_FILE
+type fileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileStore(ctx context.Context, dir string) (IPipelineStore, error) {
+	if dir == "" {
+		dir = DEFAULT_DIR
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		logger.WithContext(ctx).Error("[FileStore.NewFileStore]fail to create/find dir", zap.Error(err))
+
+		return nil, ErrFile.New(fmt.Sprintf("[FileStore.NewFileStore]%s", err.Error()))
+	}
+
+	return &fileStore{
+		dir: dir,
+	}, nil
+}
+
+// Allocate - reserves the id after the last allocated one, or after the highest stored one for
+// dirs written before ids were allocated
+func (f *fileStore) Allocate(ctx context.Context) (uint32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	last, err := f.lastId()
+	if err != nil {
+		logger.WithContext(ctx).Error("[FileStore.Allocate]fail to find last id", zap.Error(err))
+
+		return 0, err
+	}
+
+	for id := last + 1; id != 0; id++ {
+		file, openErr := os.OpenFile(f.filePath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, FILE_PERMISSION)
+		if os.IsExist(openErr) {
+			continue
+		}
+
+		if openErr != nil {
+			logger.WithContext(ctx).Error("[FileStore.Allocate]fail to reserve id", zap.Uint32("server id", id), zap.Error(openErr))
+
+			return 0, ErrFile.New(fmt.Sprintf("[FileStore.Allocate]%s", openErr.Error()))
+		}
+
+		file.Close()
+
+		seq := []byte(strconv.FormatUint(uint64(id), BASE10))
+		if writeErr := ioutil2.WriteFileAtomic(path.Join(f.dir, SEQ_FILE), seq, FILE_PERMISSION); writeErr != nil {
+			logger.WithContext(ctx).Error("[FileStore.Allocate]fail to write last id", zap.Uint32("server id", id), zap.Error(writeErr))
+
+			return 0, ErrFile.New(fmt.Sprintf("[FileStore.Allocate]%s", writeErr.Error()))
+		}
+
+		return id, nil
+	}
+
+	return 0, ErrFile.New("[FileStore.Allocate]ids are exhausted")
+}
+
+func (f *fileStore) Create(ctx context.Context, pipeline *Pipeline) error {
+	data, err := encode(pipeline)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if isDefined, err := f.isDefined(pipeline.Config.ServerId); err != nil {
+		return err
+	} else if isDefined {
+		return ErrExists.New(fmt.Sprintf("[FileStore.Create]pipeline %d already exists", pipeline.Config.ServerId))
+	}
+
+	return f.write(ctx, pipeline.Config.ServerId, data)
+}
+
+func (f *fileStore) Save(ctx context.Context, pipeline *Pipeline) error {
+	data, err := encode(pipeline)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if isDefined, err := f.isDefined(pipeline.Config.ServerId); err != nil {
+		return err
+	} else if !isDefined {
+		return ErrNotFound.New(fmt.Sprintf("[FileStore.Save]pipeline %d does not exist", pipeline.Config.ServerId))
+	}
+
+	return f.write(ctx, pipeline.Config.ServerId, data)
+}
+
+// write - replaces the definition of pipeline id with data. Must be called with f.mu held
+func (f *fileStore) write(ctx context.Context, id uint32, data []byte) error {
+	if err := ioutil2.WriteFileAtomic(f.filePath(id), data, FILE_PERMISSION); err != nil {
+		logger.WithContext(ctx).Error("[FileStore.write]fail to write pipeline", zap.Uint32("server id", id), zap.Error(err))
+
+		return ErrFile.New(fmt.Sprintf("[FileStore.write]%s", err.Error()))
+	}
+
+	return nil
+}
+
+// isDefined - indicates if pipeline id has a definition rather than only a reserved id
+func (f *fileStore) isDefined(id uint32) (bool, error) {
+	info, err := os.Stat(f.filePath(id))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, ErrFile.New(fmt.Sprintf("[FileStore.isDefined]%s", err.Error()))
+	}
+
+	return info.Size() > 0, nil
+}
+
+// lastId - returns the last allocated id, or the highest stored one if higher
+func (f *fileStore) lastId() (uint32, error) {
+	var last uint64
+
+	data, err := os.ReadFile(path.Join(f.dir, SEQ_FILE))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, ErrFile.New(fmt.Sprintf("[FileStore.lastId]%s", err.Error()))
+	}
+
+	if err == nil {
+		if last, err = strconv.ParseUint(strings.TrimSpace(string(data)), BASE10, BIT32); err != nil {
+			return 0, ErrFile.New(fmt.Sprintf("[FileStore.lastId]invalid %s: %s", SEQ_FILE, err.Error()))
+		}
+	}
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return 0, ErrFile.New(fmt.Sprintf("[FileStore.lastId]%s", err.Error()))
+	}
+
+	for _, entry := range entries {
+		id, parseErr := strconv.ParseUint(strings.TrimSuffix(entry.Name(), FILE_EXT), BASE10, BIT32)
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), FILE_EXT) || parseErr != nil {
+			continue
+		}
+
+		if id > last {
+			last = id
+		}
+	}
+
+	return uint32(last), nil
+}
+
+func (f *fileStore) Delete(ctx context.Context, id uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.filePath(id)); err != nil && !os.IsNotExist(err) {
+		logger.WithContext(ctx).Error("[FileStore.Delete]fail to delete pipeline", zap.Uint32("server id", id), zap.Error(err))
+
+		return ErrFile.New(fmt.Sprintf("[FileStore.Delete]%s", err.Error()))
+	}
+
+	return nil
+}
+
+func (f *fileStore) List(ctx context.Context) ([]*Pipeline, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		logger.WithContext(ctx).Error("[FileStore.List]fail to read dir", zap.Error(err))
+
+		return nil, ErrFile.New(fmt.Sprintf("[FileStore.List]%s", err.Error()))
+	}
+
+	pipelines := make([]*Pipeline, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), FILE_EXT) {
+			continue
+		}
+
+		data, readErr := os.ReadFile(path.Join(f.dir, entry.Name()))
+		if readErr != nil {
+			return nil, ErrFile.New(fmt.Sprintf("[FileStore.List]%s", readErr.Error()))
+		}
+
+		// reserved ids have no definition yet
+		if len(data) == 0 {
+			continue
+		}
+
+		pipeline, decodeErr := decode(string(data))
+		if decodeErr != nil {
+			logger.WithContext(ctx).Error(
+				"[FileStore.List]fail to decode pipeline",
+				zap.String("file", entry.Name()),
+				zap.Error(decodeErr),
+			)
+
+			return nil, decodeErr
+		}
+
+		pipelines = append(pipelines, pipeline)
+	}
+
+	return pipelines, nil
+}
+
+func (f *fileStore) Close() error {
+	return nil
+}
+
+func (f *fileStore) filePath(id uint32) string {
+	return path.Join(f.dir, fmt.Sprintf(PIPELINE_FILE_FORMAT, id))
+}
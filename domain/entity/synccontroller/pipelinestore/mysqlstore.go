@@ -0,0 +1,195 @@
+package pipelinestore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// mySQLStore - stores pipeline definitions in a metadata table
+//
+// Ids are allocated from the table's auto increment, and reserved ids have an empty definition
+type mySQLStore struct {
+	conn     *client.Conn
+	storeCfg config.StoreConfig
+	table    string
+	mu       sync.Mutex
+}
+
+func NewMySQLStore(ctx context.Context, storeCfg config.StoreConfig) (IPipelineStore, error) {
+	table := storeCfg.Table
+	if table == "" {
+		table = DEFAULT_TABLE
+	}
+
+	store := &mySQLStore{
+		storeCfg: storeCfg,
+		table:    table,
+	}
+
+	for _, query := range []string{CREATE_TABLE_SQL, AUTO_INCREMENT_SQL} {
+		if _, err := store.execute(ctx, fmt.Sprintf(query, table)); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// Allocate - reserves the next auto increment id of the table
+func (m *mySQLStore) Allocate(ctx context.Context) (uint32, error) {
+	res, err := m.execute(ctx, fmt.Sprintf(ALLOCATE_SQL, m.table), time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	if res.InsertId == 0 || res.InsertId > math.MaxUint32 {
+		return 0, ErrQuery.New(fmt.Sprintf("[MySQLStore.Allocate]invalid allocated id %d", res.InsertId))
+	}
+
+	return uint32(res.InsertId), nil
+}
+
+// Create - fills in the definition of a reserved id, reserving it first if it was not allocated
+func (m *mySQLStore) Create(ctx context.Context, pipeline *Pipeline) error {
+	data, err := encode(pipeline)
+	if err != nil {
+		return err
+	}
+
+	id := pipeline.Config.ServerId
+	now := time.Now().Unix()
+
+	if _, err := m.execute(ctx, fmt.Sprintf(RESERVE_SQL, m.table), id, now); err != nil {
+		return err
+	}
+
+	res, err := m.execute(ctx, fmt.Sprintf(CREATE_SQL, m.table), string(data), now, id)
+	if err != nil {
+		return err
+	}
+
+	if res.AffectedRows == 0 {
+		return ErrExists.New(fmt.Sprintf("[MySQLStore.Create]pipeline %d already exists", id))
+	}
+
+	return nil
+}
+
+func (m *mySQLStore) Save(ctx context.Context, pipeline *Pipeline) error {
+	data, err := encode(pipeline)
+	if err != nil {
+		return err
+	}
+
+	res, err := m.execute(
+		ctx,
+		fmt.Sprintf(UPDATE_SQL, m.table),
+		string(data),
+		time.Now().Unix(),
+		pipeline.Config.ServerId,
+	)
+	if err != nil {
+		return err
+	}
+
+	if res.AffectedRows == 0 {
+		return ErrNotFound.New(fmt.Sprintf("[MySQLStore.Save]pipeline %d does not exist", pipeline.Config.ServerId))
+	}
+
+	return nil
+}
+
+func (m *mySQLStore) Delete(ctx context.Context, id uint32) error {
+	_, err := m.execute(ctx, fmt.Sprintf(DELETE_SQL, m.table), id)
+
+	return err
+}
+
+func (m *mySQLStore) List(ctx context.Context) ([]*Pipeline, error) {
+	res, err := m.execute(ctx, fmt.Sprintf(SELECT_SQL, m.table))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	pipelines := make([]*Pipeline, 0, res.Resultset.RowNumber())
+
+	for rowNum := 0; rowNum < res.Resultset.RowNumber(); rowNum++ {
+		data, _ := res.GetString(rowNum, 0)
+
+		pipeline, decodeErr := decode(data)
+		if decodeErr != nil {
+			logger.WithContext(ctx).Error("[MySQLStore.List]fail to decode pipeline", zap.Error(decodeErr))
+
+			return nil, decodeErr
+		}
+
+		pipelines = append(pipelines, pipeline)
+	}
+
+	return pipelines, nil
+}
+
+func (m *mySQLStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		return nil
+	}
+
+	err := m.conn.Close()
+	m.conn = nil
+
+	return err
+}
+
+// execute - runs a statement, reconnecting once if the connection was lost
+func (m *mySQLStore) execute(ctx context.Context, query string, args ...interface{}) (*mysql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn != nil && m.conn.Ping() != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+
+	if m.conn == nil {
+		// count matched rather than changed rows, so that saving an unchanged definition succeeds
+		conn, err := client.Connect(
+			m.storeCfg.Addr,
+			m.storeCfg.User,
+			m.storeCfg.Pass,
+			m.storeCfg.Database,
+			func(conn *client.Conn) {
+				conn.SetCapability(mysql.CLIENT_FOUND_ROWS)
+			},
+		)
+		if err != nil {
+			logger.WithContext(ctx).Error("[MySQLStore.execute]fail to connect", zap.Error(err))
+
+			return nil, ErrConnect.New(fmt.Sprintf("[MySQLStore.execute]%s", err.Error()))
+		}
+
+		m.conn = conn
+	}
+
+	res, err := m.conn.Execute(query, args...)
+	if err != nil {
+		logger.WithContext(ctx).Error("[MySQLStore.execute]fail to execute", zap.String("raw sql", query), zap.Error(err))
+
+		return nil, ErrQuery.New(fmt.Sprintf("[MySQLStore.execute]%s", err.Error()))
+	}
+
+	return res, nil
+}
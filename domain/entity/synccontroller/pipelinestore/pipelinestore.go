@@ -0,0 +1,91 @@
+package pipelinestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/tools/secret"
+)
+
+// Pipeline - durable definition of a pipeline
+//
+// The database password is only persisted as a secret reference, never in plain text. Origin
+// is where the pipeline was declared, ORIGIN_FILE or ORIGIN_API. IsStoppedByOperator is set
+// while the pipeline is stopped by a call to the control plane rather than by reconciling
+type Pipeline struct {
+	DesiredState        string        `toml:"desired_state"`
+	Origin              string        `toml:"origin"`
+	Config              config.Config `toml:"config"`
+	IsLegacySync        bool          `toml:"is_legacy_sync"`
+	IsStoppedByOperator bool          `toml:"is_stopped_by_operator"`
+}
+
+// GetOrigin - returns where the pipeline was declared
+//
+// Pipelines persisted before origins were recorded are from the config file if named, as only
+// declared pipelines have names
+func (p *Pipeline) GetOrigin() string {
+	switch {
+	case p.Origin != "":
+		return p.Origin
+	case p.Config.Name != "":
+		return ORIGIN_FILE
+	default:
+		return ORIGIN_API
+	}
+}
+
+// IPipelineStore - persists pipeline definitions across restarts
+//
+// Allocate reserves an id no instance sharing the store has been given, deleted pipelines
+// included. Create stores a new definition, failing with ErrExists if its id already has one,
+// and Save replaces an existing definition, failing with ErrNotFound if it was deleted
+type IPipelineStore interface {
+	Allocate(ctx context.Context) (uint32, error)
+	Create(ctx context.Context, pipeline *Pipeline) error
+	Save(ctx context.Context, pipeline *Pipeline) error
+	Delete(ctx context.Context, id uint32) error
+	List(ctx context.Context) ([]*Pipeline, error)
+	Close() error
+}
+
+// NewPipelineStore - creates the pipeline store configured by storeCfg
+func NewPipelineStore(ctx context.Context, storeCfg config.StoreConfig) (IPipelineStore, error) {
+	switch storeCfg.Type {
+	case "", config.STORE_TYPE_FILE:
+		return NewFileStore(ctx, storeCfg.Dir)
+	case config.STORE_TYPE_MYSQL:
+		return NewMySQLStore(ctx, storeCfg)
+	default:
+		return nil, ErrConfig.New(fmt.Sprintf("[NewPipelineStore]invalid store type %s", storeCfg.Type))
+	}
+}
+
+func encode(pipeline *Pipeline) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := secret.Check(pipeline.Config.DbConfig.Pass); err != nil {
+		redacted := *pipeline
+		redacted.Config.DbConfig.Pass = ""
+		pipeline = &redacted
+	}
+
+	if err := toml.NewEncoder(&buf).Encode(pipeline); err != nil {
+		return nil, ErrEncode.New(fmt.Sprintf("[PipelineStore.encode]%s", err.Error()))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decode(data string) (*Pipeline, error) {
+	var pipeline Pipeline
+
+	if _, err := toml.Decode(data, &pipeline); err != nil {
+		return nil, ErrEncode.New(fmt.Sprintf("[PipelineStore.decode]%s", err.Error()))
+	}
+
+	return &pipeline, nil
+}
@@ -0,0 +1,211 @@
+package synccontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller/pipelinestore"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// ReconcileResult - server ids of the pipelines reconciling created, updated, started, stopped
+// or left unchanged
+//
+// Failed holds the error of each pipeline that could not be brought in line
+type ReconcileResult struct {
+	Failed    map[uint32]string
+	Created   []uint32
+	Updated   []uint32
+	Started   []uint32
+	Stopped   []uint32
+	Unchanged []uint32
+}
+
+// Reconcile - creates, updates, starts and stops pipelines so that they match declared
+//
+// Only pipelines declared in the config file are managed, those created through the control
+// plane are left alone. Declared pipelines are matched by server id. New ones are created and
+// started, changed ones rebuilt keeping their checkpoints, and stopped ones started. Paused
+// pipelines, and those an operator stopped, are rebuilt if changed but stay as they are.
+// Running pipelines that are no longer declared are stopped but kept, so they can still be
+// inspected, resumed or deleted
+func (s *syncController) Reconcile(ctx context.Context, declared []*config.Config) (*ReconcileResult, error) {
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncController.Reconcile]fail to list pipelines", zap.Error(err))
+
+		return nil, err
+	}
+
+	desiredStates := make(map[uint32]string, len(pipelines))
+	for _, pipeline := range pipelines {
+		desiredStates[pipeline.Config.ServerId] = pipeline.DesiredState
+	}
+
+	res := &ReconcileResult{
+		Failed: make(map[uint32]string),
+	}
+
+	isDeclared := make(map[uint32]bool, len(declared))
+
+	for _, cfg := range declared {
+		isDeclared[cfg.ServerId] = true
+
+		action, err := s.reconcile(ctx, cfg, desiredStates[cfg.ServerId])
+		if err != nil {
+			logger.WithContext(ctx).Error(
+				"[SyncController.Reconcile]fail to reconcile pipeline",
+				zap.Uint32("id", cfg.ServerId),
+				zap.String("name", cfg.Name),
+				zap.Error(err),
+			)
+
+			res.Failed[cfg.ServerId] = err.Error()
+
+			continue
+		}
+
+		switch action {
+		case ACTION_CREATED:
+			res.Created = append(res.Created, cfg.ServerId)
+		case ACTION_UPDATED:
+			res.Updated = append(res.Updated, cfg.ServerId)
+		case ACTION_STARTED:
+			res.Started = append(res.Started, cfg.ServerId)
+		default:
+			res.Unchanged = append(res.Unchanged, cfg.ServerId)
+		}
+	}
+
+	s.mu.Lock()
+	managers := make(map[uint32]syncmanager.SyncManager, len(s.syncmanagers))
+	for id, manager := range s.syncmanagers {
+		managers[id] = manager
+	}
+	s.mu.Unlock()
+
+	for id, manager := range managers {
+		if isDeclared[id] || s.owner(id).origin != pipelinestore.ORIGIN_FILE ||
+			(desiredStates[id] != pipelinestore.DESIRED_RUNNING && !manager.Status().IsRunning) {
+			continue
+		}
+
+		logger.WithContext(ctx).Info("[SyncController.Reconcile]stopping undeclared pipeline", zap.Uint32("id", id))
+
+		if err := s.undeclare(ctx, id); err != nil {
+			res.Failed[id] = err.Error()
+
+			continue
+		}
+
+		res.Stopped = append(res.Stopped, id)
+	}
+
+	for _, ids := range [][]uint32{res.Created, res.Updated, res.Started, res.Stopped, res.Unchanged} {
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i] < ids[j]
+		})
+	}
+
+	return res, nil
+}
+
+// reconcile - brings pipeline cfg.ServerId in line with cfg given its persisted desiredState,
+// returning the action taken
+func (s *syncController) reconcile(ctx context.Context, cfg *config.Config, desiredState string) (string, error) {
+	id := cfg.ServerId
+
+	s.mu.Lock()
+	_, isKnown := s.syncmanagers[id]
+	s.mu.Unlock()
+
+	if !isKnown {
+		newManager, err := syncmanager.RestoreSyncManager(ctx, cfg)
+		if err != nil {
+			return "", err
+		}
+
+		if err := s.add(ctx, id, newManager, pipelinestore.ORIGIN_FILE); err != nil {
+			newManager.Close()
+
+			return "", err
+		}
+
+		logger.WithContext(ctx).Info("[SyncController.reconcile]created pipeline", zap.Uint32("id", id), zap.String("name", cfg.Name))
+
+		return ACTION_CREATED, s.Start(ctx, id, false)
+	}
+
+	manager, err := s.begin(id)
+	if err != nil {
+		return "", err
+	}
+
+	defer s.end(id)
+
+	owner := s.owner(id)
+	if owner.origin != pipelinestore.ORIGIN_FILE {
+		return "", ErrParam.New(fmt.Sprintf(
+			"[SyncController.reconcile]id %d belongs to a pipeline created through the control plane",
+			id,
+		))
+	}
+
+	action := ACTION_UNCHANGED
+
+	current := manager.GetConfig()
+	if changes := current.Changes(cfg); len(changes) > 0 {
+		// the store settings belong to this instance and are kept
+		newCfg := *cfg.Clone()
+		newCfg.StoreConfig = current.StoreConfig
+
+		if err := s.reconfigure(ctx, id, newCfg, false); err != nil {
+			return "", err
+		}
+
+		logger.WithContext(ctx).Info(
+			"[SyncController.reconcile]updated pipeline",
+			zap.Uint32("id", id),
+			zap.String("name", cfg.Name),
+			zap.Strings("changes", changes),
+		)
+
+		action = ACTION_UPDATED
+	}
+
+	// running pipelines are already supervised, and paused or stopped ones are held by an operator
+	if desiredState == pipelinestore.DESIRED_RUNNING || desiredState == pipelinestore.DESIRED_PAUSED ||
+		owner.isStoppedByOperator {
+		return action, nil
+	}
+
+	if err := s.start(ctx, id, false); err != nil {
+		return "", err
+	}
+
+	logger.WithContext(ctx).Info("[SyncController.reconcile]started pipeline", zap.Uint32("id", id), zap.String("name", cfg.Name))
+
+	if action == ACTION_UNCHANGED {
+		action = ACTION_STARTED
+	}
+
+	return action, nil
+}
+
+// undeclare - stops a pipeline that is no longer declared in the config file, so that it is
+// started again once declared again
+func (s *syncController) undeclare(ctx context.Context, id uint32) error {
+	if _, err := s.begin(id); err != nil {
+		return err
+	}
+
+	defer s.end(id)
+
+	_, err := s.halt(ctx, id, pipelinestore.DESIRED_STOPPED)
+
+	return err
+}
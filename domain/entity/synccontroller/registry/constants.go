@@ -0,0 +1,24 @@
+package registry
+
+import "time"
+
+const (
+	DEFAULT_INSTANCE_TIMEOUT = 15 * time.Second
+)
+
+// File registry constants
+const (
+	DEFAULT_DIR          = "./instances"
+	FILE_EXT             = ".toml"
+	INSTANCE_FILE_FORMAT = "%x" + FILE_EXT
+	FILE_PERMISSION      = 0o644
+)
+
+// MySQL registry constants
+const (
+	DEFAULT_TABLE    = "instances"
+	CREATE_TABLE_SQL = "CREATE TABLE IF NOT EXISTS `%s` (`id` VARCHAR(255) NOT NULL PRIMARY KEY, `definition` TEXT NOT NULL, `heartbeat` BIGINT NOT NULL)"
+	UPSERT_SQL       = "INSERT INTO `%s` (`id`, `definition`, `heartbeat`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `definition` = VALUES(`definition`), `heartbeat` = VALUES(`heartbeat`)"
+	DELETE_SQL       = "DELETE FROM `%s` WHERE `id` = ?"
+	SELECT_SQL       = "SELECT `definition` FROM `%s` WHERE `heartbeat` >= ? ORDER BY `id`"
+)
@@ -0,0 +1,14 @@
+package registry
+
+import "github.com/twothicc/common-go/errortype"
+
+const pkg = "domain/entity/synccontroller/registry"
+
+//nolint:gomnd // error code
+var (
+	ErrFile    = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrQuery   = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrEncode  = errortype.ErrorType{Code: 3, Pkg: pkg}
+	ErrConfig  = errortype.ErrorType{Code: 4, Pkg: pkg}
+	ErrConnect = errortype.ErrorType{Code: 5, Pkg: pkg}
+)
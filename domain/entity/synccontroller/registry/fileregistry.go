@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siddontang/go/ioutil2"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// fileRegistry - registers each instance as a toml file in dir, for instances sharing a filesystem
+type fileRegistry struct {
+	dir             string
+	instanceTimeout time.Duration
+	mu              sync.Mutex
+}
+
+func NewFileRegistry(ctx context.Context, dir string, instanceTimeout time.Duration) (IRegistry, error) {
+	if dir == "" {
+		dir = DEFAULT_DIR
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		logger.WithContext(ctx).Error("[FileRegistry.NewFileRegistry]fail to create/find dir", zap.Error(err))
+
+		return nil, ErrFile.New(fmt.Sprintf("[FileRegistry.NewFileRegistry]%s", err.Error()))
+	}
+
+	return &fileRegistry{
+		dir:             dir,
+		instanceTimeout: instanceTimeout,
+	}, nil
+}
+
+func (f *fileRegistry) Heartbeat(ctx context.Context, instance *Instance) error {
+	data, err := encode(instance)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ioutil2.WriteFileAtomic(f.filePath(instance.Id), data, FILE_PERMISSION); err != nil {
+		logger.WithContext(ctx).Error("[FileRegistry.Heartbeat]fail to write instance", zap.String("id", instance.Id), zap.Error(err))
+
+		return ErrFile.New(fmt.Sprintf("[FileRegistry.Heartbeat]%s", err.Error()))
+	}
+
+	return nil
+}
+
+func (f *fileRegistry) List(ctx context.Context) ([]*Instance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		logger.WithContext(ctx).Error("[FileRegistry.List]fail to read dir", zap.Error(err))
+
+		return nil, ErrFile.New(fmt.Sprintf("[FileRegistry.List]%s", err.Error()))
+	}
+
+	cutoff := time.Now().Add(-f.instanceTimeout).UnixMilli()
+	instances := make([]*Instance, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), FILE_EXT) {
+			continue
+		}
+
+		data, readErr := os.ReadFile(path.Join(f.dir, entry.Name()))
+		if readErr != nil {
+			continue
+		}
+
+		// skip instances whose file is being replaced
+		instance, decodeErr := decode(string(data))
+		if decodeErr != nil || instance.Heartbeat < cutoff {
+			continue
+		}
+
+		instances = append(instances, instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Id < instances[j].Id
+	})
+
+	return instances, nil
+}
+
+func (f *fileRegistry) Deregister(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.filePath(id)); err != nil && !os.IsNotExist(err) {
+		logger.WithContext(ctx).Error("[FileRegistry.Deregister]fail to delete instance", zap.String("id", id), zap.Error(err))
+
+		return ErrFile.New(fmt.Sprintf("[FileRegistry.Deregister]%s", err.Error()))
+	}
+
+	return nil
+}
+
+func (f *fileRegistry) Close() error {
+	return nil
+}
+
+// filePath - hex encodes id, which may contain characters not allowed in file names
+func (f *fileRegistry) filePath(id string) string {
+	return path.Join(f.dir, fmt.Sprintf(INSTANCE_FILE_FORMAT, id))
+}
@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// mySQLRegistry - registers instances in a metadata table
+type mySQLRegistry struct {
+	conn            *client.Conn
+	storeCfg        config.StoreConfig
+	table           string
+	instanceTimeout time.Duration
+	mu              sync.Mutex
+}
+
+func NewMySQLRegistry(
+	ctx context.Context,
+	table string,
+	storeCfg config.StoreConfig,
+	instanceTimeout time.Duration,
+) (IRegistry, error) {
+	if table == "" {
+		table = DEFAULT_TABLE
+	}
+
+	registry := &mySQLRegistry{
+		storeCfg:        storeCfg,
+		table:           table,
+		instanceTimeout: instanceTimeout,
+	}
+
+	if _, err := registry.execute(ctx, fmt.Sprintf(CREATE_TABLE_SQL, table)); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+func (m *mySQLRegistry) Heartbeat(ctx context.Context, instance *Instance) error {
+	data, err := encode(instance)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.execute(ctx, fmt.Sprintf(UPSERT_SQL, m.table), instance.Id, string(data), instance.Heartbeat)
+
+	return err
+}
+
+func (m *mySQLRegistry) List(ctx context.Context) ([]*Instance, error) {
+	cutoff := time.Now().Add(-m.instanceTimeout).UnixMilli()
+
+	res, err := m.execute(ctx, fmt.Sprintf(SELECT_SQL, m.table), cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	instances := make([]*Instance, 0, res.Resultset.RowNumber())
+
+	for rowNum := 0; rowNum < res.Resultset.RowNumber(); rowNum++ {
+		data, _ := res.GetString(rowNum, 0)
+
+		instance, decodeErr := decode(data)
+		if decodeErr != nil {
+			logger.WithContext(ctx).Error("[MySQLRegistry.List]fail to decode instance", zap.Error(decodeErr))
+
+			return nil, decodeErr
+		}
+
+		instances = append(instances, instance)
+	}
+
+	return instances, nil
+}
+
+func (m *mySQLRegistry) Deregister(ctx context.Context, id string) error {
+	_, err := m.execute(ctx, fmt.Sprintf(DELETE_SQL, m.table), id)
+
+	return err
+}
+
+func (m *mySQLRegistry) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		return nil
+	}
+
+	err := m.conn.Close()
+	m.conn = nil
+
+	return err
+}
+
+// execute - runs a statement, reconnecting once if the connection was lost
+func (m *mySQLRegistry) execute(ctx context.Context, query string, args ...interface{}) (*mysql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn != nil && m.conn.Ping() != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+
+	if m.conn == nil {
+		conn, err := client.Connect(m.storeCfg.Addr, m.storeCfg.User, m.storeCfg.Pass, m.storeCfg.Database)
+		if err != nil {
+			logger.WithContext(ctx).Error("[MySQLRegistry.execute]fail to connect", zap.Error(err))
+
+			return nil, ErrConnect.New(fmt.Sprintf("[MySQLRegistry.execute]%s", err.Error()))
+		}
+
+		m.conn = conn
+	}
+
+	res, err := m.conn.Execute(query, args...)
+	if err != nil {
+		logger.WithContext(ctx).Error("[MySQLRegistry.execute]fail to execute", zap.String("raw sql", query), zap.Error(err))
+
+		return nil, ErrQuery.New(fmt.Sprintf("[MySQLRegistry.execute]%s", err.Error()))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/twothicc/canal/config"
+)
+
+// Instance - a member of the cluster sharing pipelines
+//
+// Heartbeat is in unix milliseconds. Pipelines are those the instance currently leads
+type Instance struct {
+	Id        string   `toml:"id"`
+	Addr      string   `toml:"addr"`
+	Pipelines []uint32 `toml:"pipelines"`
+	Heartbeat int64    `toml:"heartbeat"`
+}
+
+// IRegistry - shared record of the live instances of a cluster
+type IRegistry interface {
+	// Heartbeat - registers instance or refreshes its registration
+	Heartbeat(ctx context.Context, instance *Instance) error
+	// List - returns instances that heartbeated within the instance timeout
+	List(ctx context.Context) ([]*Instance, error)
+	Deregister(ctx context.Context, id string) error
+	Close() error
+}
+
+// NewRegistry - creates the registry configured by clusterCfg
+//
+// The mysql registry uses the database of storeCfg. An empty registry type creates a
+// registry holding only this instance
+func NewRegistry(
+	ctx context.Context,
+	clusterCfg config.ClusterConfig,
+	storeCfg config.StoreConfig,
+) (IRegistry, error) {
+	instanceTimeout := DEFAULT_INSTANCE_TIMEOUT
+	if clusterCfg.InstanceTimeout > 0 {
+		instanceTimeout = time.Duration(clusterCfg.InstanceTimeout) * time.Millisecond
+	}
+
+	switch clusterCfg.Type {
+	case "":
+		return &localRegistry{}, nil
+	case config.REGISTRY_TYPE_FILE:
+		return NewFileRegistry(ctx, clusterCfg.Dir, instanceTimeout)
+	case config.REGISTRY_TYPE_MYSQL:
+		return NewMySQLRegistry(ctx, clusterCfg.Table, storeCfg, instanceTimeout)
+	default:
+		return nil, ErrConfig.New(fmt.Sprintf("[NewRegistry]invalid registry type %s", clusterCfg.Type))
+	}
+}
+
+// localRegistry - registry of a single instance
+type localRegistry struct {
+	instance *Instance
+	mu       sync.Mutex
+}
+
+func (l *localRegistry) Heartbeat(_ context.Context, instance *Instance) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.instance = instance
+
+	return nil
+}
+
+func (l *localRegistry) List(_ context.Context) ([]*Instance, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.instance == nil {
+		return []*Instance{}, nil
+	}
+
+	return []*Instance{l.instance}, nil
+}
+
+func (l *localRegistry) Deregister(_ context.Context, _ string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.instance = nil
+
+	return nil
+}
+
+func (l *localRegistry) Close() error {
+	return nil
+}
+
+func encode(instance *Instance) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := toml.NewEncoder(&buf).Encode(instance); err != nil {
+		return nil, ErrEncode.New(fmt.Sprintf("[Registry.encode]%s", err.Error()))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decode(data string) (*Instance, error) {
+	var instance Instance
+
+	if _, err := toml.Decode(data, &instance); err != nil {
+		return nil, ErrEncode.New(fmt.Sprintf("[Registry.decode]%s", err.Error()))
+	}
+
+	return &instance, nil
+}
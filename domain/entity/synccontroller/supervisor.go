@@ -0,0 +1,385 @@
+package synccontroller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// supervision - restart bookkeeping of a supervised syncmanager
+type supervision struct {
+	lastFailureTime time.Time
+	ctx             context.Context
+	cancel          context.CancelFunc
+	lastFailure     string
+	role            string
+	restarts        uint32
+}
+
+// maxBackoff - returns the configured cap on restart delays
+func maxBackoff(supervisorCfg config.SupervisorConfig) time.Duration {
+	if supervisorCfg.MaxBackoff > 0 {
+		return time.Duration(supervisorCfg.MaxBackoff) * time.Millisecond
+	}
+
+	return DEFAULT_MAX_BACKOFF
+}
+
+// backoff - returns the delay before the given restart attempt, starting from 1
+func backoff(supervisorCfg config.SupervisorConfig, attempt uint32) time.Duration {
+	initial := DEFAULT_INITIAL_BACKOFF
+	if supervisorCfg.InitialBackoff > 0 {
+		initial = time.Duration(supervisorCfg.InitialBackoff) * time.Millisecond
+	}
+
+	multiplier := DEFAULT_MULTIPLIER
+	if supervisorCfg.Multiplier >= 1 {
+		multiplier = supervisorCfg.Multiplier
+	}
+
+	delay := math.Min(
+		float64(initial)*math.Pow(multiplier, float64(attempt-1)),
+		float64(maxBackoff(supervisorCfg)),
+	)
+
+	if supervisorCfg.Jitter > 0 {
+		//nolint:gosec // jitter does not need a secure source
+		delay += delay * supervisorCfg.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// supervise - runs manager while this instance leads it, restarting it from its checkpoint
+// with backoff whenever it fails
+//
+// Returns once the manager stops without error, its supervision is cancelled by Stop or
+// Remove, or the configured max attempts are exhausted
+func (s *syncController) supervise(
+	ctx context.Context,
+	sup *supervision,
+	manager syncmanager.SyncManager,
+	isLegacySync bool,
+) {
+	id := manager.GetId()
+
+	defer s.release(ctx, id)
+
+	var attempt uint32
+
+	for {
+		newManager, ok := s.lead(ctx, sup, manager)
+		if !ok {
+			return
+		}
+
+		// only redo the legacy sync if it never got as far as saving a checkpoint
+		isLegacySync = isLegacySync && newManager.Checkpoint().Name == ""
+		manager = newManager
+
+		startTime := time.Now()
+
+		stopHold := s.hold(ctx, sup, manager)
+		err := manager.Run(isLegacySync)
+		isLost := stopHold()
+
+		if sup.ctx.Err() != nil {
+			return
+		}
+
+		// the manager was closed by hold, stand by until the lock is free again
+		if isLost {
+			continue
+		}
+
+		if err == nil {
+			return
+		}
+
+		// a run that outlasted the max backoff was healthy, so start counting afresh
+		if time.Since(startTime) > maxBackoff(s.supervisorCfg) {
+			attempt = 0
+		}
+
+		s.recordFailure(ctx, sup, id, err)
+
+		manager.Close()
+
+		if manager, ok = s.restart(ctx, sup, manager, &attempt); !ok {
+			return
+		}
+	}
+}
+
+// lead - blocks until manager's pipeline is assigned to this instance and it holds its leadership lock
+//
+// A manager that was closed, or built before another instance let the lock lapse, is
+// rebuilt from the shared definition and checkpoint. Returns false when supervision is cancelled
+func (s *syncController) lead(
+	ctx context.Context,
+	sup *supervision,
+	manager syncmanager.SyncManager,
+) (syncmanager.SyncManager, bool) {
+	id := manager.GetId()
+	isStandby := false
+
+	ticker := time.NewTicker(s.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		isLeader := false
+
+		if s.isAssigned(id) {
+			var err error
+
+			if isLeader, err = s.lock.Acquire(ctx, id); err != nil {
+				logger.WithContext(ctx).Error("[SyncController.lead]fail to acquire lock", zap.Uint32("server id", id), zap.Error(err))
+			}
+		}
+
+		if isLeader {
+			if !isStandby && !manager.IsClosed() {
+				s.setRole(sup, ROLE_LEADER)
+
+				return manager, true
+			}
+
+			newManager, takeoverErr := s.takeover(ctx, sup, manager)
+			if takeoverErr == nil {
+				return newManager, true
+			}
+
+			s.recordFailure(ctx, sup, id, takeoverErr)
+
+			// deleted through another instance, and removed here on the next heartbeat
+			if ErrNotFound.Is(takeoverErr) {
+				return nil, false
+			}
+
+			s.release(ctx, id)
+		} else if !isStandby {
+			logger.WithContext(ctx).Info(
+				"[SyncController.lead]pipeline is assigned to or led by another instance, standing by",
+				zap.Uint32("server id", id),
+			)
+
+			s.setRole(sup, ROLE_STANDBY)
+		}
+
+		isStandby = true
+
+		select {
+		case <-ticker.C:
+		case <-sup.ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// takeover - rebuilds manager from the stored definition and shared checkpoint once this
+// instance holds its lock
+//
+// Fails with ErrNotFound if the pipeline was deleted through another instance
+func (s *syncController) takeover(
+	ctx context.Context,
+	sup *supervision,
+	manager syncmanager.SyncManager,
+) (syncmanager.SyncManager, error) {
+	id := manager.GetId()
+
+	// the previous leader may have updated the definition
+	pipeline, err := s.definition(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if pipeline == nil {
+		return nil, ErrNotFound.New(fmt.Sprintf("[SyncController.takeover]pipeline %d was deleted", id))
+	}
+
+	cfg := pipeline.Config
+	cfg.StoreConfig = s.storeCfg
+
+	manager.Close()
+
+	newManager, err := syncmanager.RestoreSyncManager(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sup.ctx.Err() != nil {
+		newManager.Close()
+
+		return nil, sup.ctx.Err()
+	}
+
+	s.syncmanagers[id] = newManager
+	sup.role = ROLE_LEADER
+
+	logger.WithContext(ctx).Info(
+		"[SyncController.takeover]took over pipeline",
+		zap.Uint32("server id", id),
+		zap.Stringer("checkpoint", newManager.Checkpoint()),
+	)
+
+	return newManager, nil
+}
+
+// hold - renews the leadership lock while manager runs, closing it if the lock is lost or
+// the pipeline is reassigned to another instance
+//
+// The returned func stops renewing and reports whether leadership was lost
+func (s *syncController) hold(ctx context.Context, sup *supervision, manager syncmanager.SyncManager) func() bool {
+	var isLost int32
+
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(s.renewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			case <-sup.ctx.Done():
+				return
+			}
+
+			// hand over to the instance the pipeline was rebalanced to
+			if !s.isAssigned(manager.GetId()) {
+				logger.WithContext(ctx).Info(
+					"[SyncController.hold]pipeline reassigned, handing over",
+					zap.Uint32("server id", manager.GetId()),
+				)
+
+				atomic.StoreInt32(&isLost, 1)
+				s.setRole(sup, ROLE_STANDBY)
+				manager.Close()
+				s.release(ctx, manager.GetId())
+
+				return
+			}
+
+			if isLeader, err := s.lock.Acquire(ctx, manager.GetId()); err != nil || !isLeader {
+				logger.WithContext(ctx).Error(
+					"[SyncController.hold]lost leadership, closing syncmanager",
+					zap.Uint32("server id", manager.GetId()),
+					zap.Error(err),
+				)
+
+				atomic.StoreInt32(&isLost, 1)
+				s.setRole(sup, ROLE_STANDBY)
+				manager.Close()
+
+				return
+			}
+		}
+	}()
+
+	return func() bool {
+		close(done)
+
+		return atomic.LoadInt32(&isLost) == 1
+	}
+}
+
+// release - gives up the leadership lock so that a standby can take over
+func (s *syncController) release(ctx context.Context, id uint32) {
+	if err := s.lock.Release(ctx, id); err != nil {
+		logger.WithContext(ctx).Error("[SyncController.release]fail to release lock", zap.Uint32("server id", id), zap.Error(err))
+	}
+}
+
+func (s *syncController) setRole(sup *supervision, role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sup.role = role
+}
+
+// restart - recreates a failed manager from its config after a backoff, retrying until it succeeds
+//
+// Returns false when supervision is cancelled or the max attempts are exhausted
+func (s *syncController) restart(
+	ctx context.Context,
+	sup *supervision,
+	manager syncmanager.SyncManager,
+	attempt *uint32,
+) (syncmanager.SyncManager, bool) {
+	id := manager.GetId()
+
+	for {
+		*attempt++
+
+		if s.supervisorCfg.MaxAttempts > 0 && *attempt > s.supervisorCfg.MaxAttempts {
+			logger.WithContext(ctx).Error(
+				"[SyncController.restart]giving up restarting syncmanager",
+				zap.Uint32("server id", id),
+				zap.Uint32("max attempts", s.supervisorCfg.MaxAttempts),
+			)
+
+			return nil, false
+		}
+
+		delay := backoff(s.supervisorCfg, *attempt)
+
+		logger.WithContext(ctx).Info(
+			"[SyncController.restart]restarting syncmanager",
+			zap.Uint32("server id", id),
+			zap.Uint32("attempt", *attempt),
+			zap.Duration("backoff", delay),
+		)
+
+		select {
+		case <-time.After(delay):
+		case <-sup.ctx.Done():
+			return nil, false
+		}
+
+		cfg := manager.GetConfig()
+
+		newManager, err := syncmanager.RestoreSyncManager(ctx, &cfg)
+		if err != nil {
+			s.recordFailure(ctx, sup, id, err)
+
+			continue
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if sup.ctx.Err() != nil {
+			newManager.Close()
+
+			return nil, false
+		}
+
+		s.syncmanagers[id] = newManager
+		sup.restarts++
+
+		return newManager, true
+	}
+}
+
+func (s *syncController) recordFailure(ctx context.Context, sup *supervision, id uint32, err error) {
+	logger.WithContext(ctx).Error("[SyncController.recordFailure]syncmanager failed", zap.Uint32("server id", id), zap.Error(err))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sup.lastFailure = err.Error()
+	sup.lastFailureTime = time.Now()
+}
@@ -5,33 +5,120 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller/leaderlock"
+	"github.com/twothicc/canal/domain/entity/synccontroller/pipelinestore"
+	"github.com/twothicc/canal/domain/entity/synccontroller/registry"
 	"github.com/twothicc/canal/domain/entity/syncmanager"
+	eventsync "github.com/twothicc/canal/handlers/events/sync"
+	"github.com/twothicc/canal/tools/metrics"
+	"github.com/twothicc/canal/tools/secret"
 	"github.com/twothicc/common-go/logger"
 	"go.uber.org/zap"
 )
 
 type SyncController interface {
-	Add(ctx context.Context, id uint32, manager syncmanager.SyncManager)
+	NewSyncManager(ctx context.Context, cfg *config.Config) (syncmanager.SyncManager, error)
+	Add(ctx context.Context, id uint32, manager syncmanager.SyncManager) error
 	Remove(ctx context.Context, id uint32) error
+	Restore(ctx context.Context) error
 
 	Start(ctx context.Context, id uint32, isLegacySync bool) error
-	Stop(ctx context.Context, id uint32) error
+	Stop(ctx context.Context, id uint32) (*syncmanager.DrainResult, error)
+
+	Pause(ctx context.Context, id uint32) (*syncmanager.DrainResult, error)
+	Resume(ctx context.Context, id uint32) error
+
+	Update(ctx context.Context, id uint32, sources []config.SourceConfig, isBackfill bool) error
+	Reconcile(ctx context.Context, declared []*config.Config) (*ReconcileResult, error)
 
 	Status() map[uint32]*syncmanager.Status
+	Configs() map[uint32]config.Config
+	Cluster() *ClusterStatus
+	Events(id uint32, before uint64, limit int) (*eventsync.EventPage, error)
 
-	Close(ctx context.Context) error
+	Close(ctx context.Context) (map[uint32]*syncmanager.DrainResult, error)
 }
 
 type syncController struct {
-	syncmanagers map[uint32]syncmanager.SyncManager
-	mu           sync.Mutex
+	store             pipelinestore.IPipelineStore
+	lock              leaderlock.ILeaderLock
+	registry          registry.IRegistry
+	syncmanagers      map[uint32]syncmanager.SyncManager
+	supervisions      map[uint32]*supervision
+	transitioning     map[uint32]bool
+	settled           *sync.Cond
+	owners            map[uint32]ownership
+	stopHeartbeat     context.CancelFunc
+	heartbeatDone     chan struct{}
+	instanceId        string
+	instanceAddr      string
+	instances         []*registry.Instance
+	storeCfg          config.StoreConfig
+	supervisorCfg     config.SupervisorConfig
+	renewInterval     time.Duration
+	heartbeatInterval time.Duration
+	maxPipelines      uint32
+	mu                sync.Mutex
+	membersMu         sync.RWMutex
+	isClustered       bool
+	isClosing         bool
 }
 
-func NewSyncController(_ context.Context) SyncController {
-	return &syncController{
-		syncmanagers: make(map[uint32]syncmanager.SyncManager),
+// NewSyncController - creates a SyncController that persists pipeline definitions to store
+//
+// Started syncmanagers are supervised, restarting with backoff per cfg when they fail, and
+// only run while they are assigned to this instance and it holds their lock. The instance
+// registers itself with reg until closed
+func NewSyncController(
+	ctx context.Context,
+	store pipelinestore.IPipelineStore,
+	lock leaderlock.ILeaderLock,
+	reg registry.IRegistry,
+	cfg *config.Config,
+) SyncController {
+	renewInterval := DEFAULT_RENEW_INTERVAL
+	if cfg.LeaderConfig.RenewInterval > 0 {
+		renewInterval = time.Duration(cfg.LeaderConfig.RenewInterval) * time.Millisecond
+	}
+
+	heartbeatInterval := DEFAULT_HEARTBEAT_INTERVAL
+	if cfg.ClusterConfig.HeartbeatInterval > 0 {
+		heartbeatInterval = time.Duration(cfg.ClusterConfig.HeartbeatInterval) * time.Millisecond
+	}
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+
+	s := &syncController{
+		store:             store,
+		lock:              lock,
+		registry:          reg,
+		syncmanagers:      make(map[uint32]syncmanager.SyncManager),
+		supervisions:      make(map[uint32]*supervision),
+		transitioning:     make(map[uint32]bool),
+		owners:            make(map[uint32]ownership),
+		stopHeartbeat:     stopHeartbeat,
+		heartbeatDone:     make(chan struct{}),
+		instanceId:        lock.Owner(),
+		instanceAddr:      cfg.ClusterConfig.Addr,
+		storeCfg:          cfg.StoreConfig,
+		supervisorCfg:     cfg.SupervisorConfig,
+		renewInterval:     renewInterval,
+		heartbeatInterval: heartbeatInterval,
+		maxPipelines:      cfg.QuotaConfig.MaxPipelines,
+		isClustered:       cfg.ClusterConfig.Type != "",
 	}
+
+	s.settled = sync.NewCond(&s.mu)
+
+	// know the cluster before any pipeline is started
+	s.heartbeat(heartbeatCtx)
+
+	go s.heartbeatLoop(heartbeatCtx)
+
+	return s
 }
 
 func (s *syncController) Status() map[uint32]*syncmanager.Status {
@@ -41,91 +128,805 @@ func (s *syncController) Status() map[uint32]*syncmanager.Status {
 	res := make(map[uint32]*syncmanager.Status)
 
 	for id, manager := range s.syncmanagers {
-		res[id] = manager.Status()
+		status := manager.Status()
+
+		if sup, ok := s.supervisions[id]; ok {
+			status.Restarts = sup.restarts
+			status.LastFailure = sup.lastFailure
+			status.LastFailureTime = sup.lastFailureTime
+			status.Role = sup.role
+		}
+
+		res[id] = status
 	}
 
 	return res
 }
 
-func (s *syncController) Add(ctx context.Context, id uint32, manager syncmanager.SyncManager) {
+// Configs - returns the config of each syncmanager on this instance
+func (s *syncController) Configs() map[uint32]config.Config {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	res := make(map[uint32]config.Config, len(s.syncmanagers))
+
+	for id, manager := range s.syncmanagers {
+		res[id] = manager.GetConfig()
+	}
+
+	return res
+}
+
+// Events - returns a page of the latest messages and errors of syncmanager id, newest first
+func (s *syncController) Events(id uint32, before uint64, limit int) (*eventsync.EventPage, error) {
+	s.mu.Lock()
+	manager, ok := s.syncmanagers[id]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, ErrParam.New(fmt.Sprintf("[SyncController.Events]id %d does not exist", id))
+	}
+
+	return manager.Events(before, limit), nil
+}
+
+// NewSyncManager - creates a syncmanager from cfg with a server id allocated from the pipeline
+// store, to be registered with Add
+//
+// Ids are unique across instances sharing the store and never reused, so that a new pipeline
+// neither shares a replication server id nor picks up the checkpoint of a deleted one. Fails with
+// ErrQuota before connecting to the source or Kafka once the instance has the maximum number of
+// pipelines
+func (s *syncController) NewSyncManager(ctx context.Context, cfg *config.Config) (syncmanager.SyncManager, error) {
+	s.mu.Lock()
+	err := s.checkQuota("NewSyncManager")
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.store.Allocate(ctx)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncController.NewSyncManager]fail to allocate id", zap.Error(err))
+
+		return nil, err
+	}
+
+	return syncmanager.NewSyncManager(ctx, id, cfg)
+}
+
+// ownership - who manages a pipeline, and whether an operator stopped it
+type ownership struct {
+	origin              string
+	isStoppedByOperator bool
+}
+
+// Add - registers and persists manager as a stopped pipeline created through the control plane
+//
+// Ids that are already registered, here or by another instance sharing the store, are rejected
+// with ErrExists, and new pipelines with ErrQuota once the instance has the maximum number of
+// pipelines
+func (s *syncController) Add(ctx context.Context, id uint32, manager syncmanager.SyncManager) error {
+	return s.add(ctx, id, manager, pipelinestore.ORIGIN_API)
+}
+
+// add - see Add, recording where the pipeline was declared
+func (s *syncController) add(ctx context.Context, id uint32, manager syncmanager.SyncManager, origin string) error {
 	logger.WithContext(ctx).Info("[SyncController.Add]adding syncmanager", zap.Uint32("id", id))
 
+	s.mu.Lock()
+
+	if s.isClosing {
+		s.mu.Unlock()
+
+		return ErrBusy.New("[SyncController.Add]controller is closing")
+	}
+
+	if s.transitioning[id] {
+		s.mu.Unlock()
+
+		return ErrBusy.New(fmt.Sprintf("[SyncController.Add]pipeline %d is already being changed", id))
+	}
+
+	if _, isKnown := s.syncmanagers[id]; isKnown {
+		s.mu.Unlock()
+
+		return ErrExists.New(fmt.Sprintf("[SyncController.Add]pipeline %d already exists", id))
+	}
+
+	if err := s.checkQuota("Add"); err != nil {
+		s.mu.Unlock()
+
+		return err
+	}
+
 	s.syncmanagers[id] = manager
+	s.owners[id] = ownership{origin: origin}
+	s.transitioning[id] = true
+	s.mu.Unlock()
+
+	defer s.end(id)
+
+	err := s.store.Create(ctx, s.definitionOf(manager, pipelinestore.DESIRED_STOPPED, false))
+	if err == nil {
+		return nil
+	}
+
+	logger.WithContext(ctx).Error("[SyncController.Add]fail to create pipeline definition", zap.Uint32("id", id), zap.Error(err))
+
+	s.mu.Lock()
+	delete(s.syncmanagers, id)
+	delete(s.owners, id)
+	s.mu.Unlock()
+
+	// another instance sharing the store created a pipeline with the same id
+	if pipelinestore.ErrExists.Is(err) {
+		return ErrExists.New(fmt.Sprintf("[SyncController.Add]pipeline %d already exists", id))
+	}
+
+	return err
 }
 
-func (s *syncController) Remove(ctx context.Context, id uint32) error {
+// checkQuota - returns ErrQuota if the instance already has the maximum number of pipelines,
+// naming method in the error. Must be called with s.mu held
+func (s *syncController) checkQuota(method string) error {
+	if s.maxPipelines == 0 || len(s.syncmanagers) < int(s.maxPipelines) {
+		return nil
+	}
+
+	return ErrQuota.New(fmt.Sprintf(
+		"[SyncController.%s]instance already has the maximum of %d pipelines",
+		method,
+		s.maxPipelines,
+	))
+}
+
+// Restore - recreates persisted pipelines, resuming those that were running from their checkpoints
+//
+// Pipelines that fail to be recreated are logged and skipped
+func (s *syncController) Restore(ctx context.Context) error {
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncController.Restore]fail to list pipelines", zap.Error(err))
+
+		return err
+	}
+
+	for _, pipeline := range pipelines {
+		s.restore(ctx, pipeline)
+	}
+
+	return nil
+}
+
+// restore - recreates a persisted pipeline unless it is already known, resuming it if it was running
+//
+// Pipelines persisted with a plain text password are skipped, as no password is known to be
+// theirs, until they are recreated with a secret reference
+func (s *syncController) restore(ctx context.Context, pipeline *pipelinestore.Pipeline) {
+	cfg := pipeline.Config
+	// the store password is not persisted and belongs to this instance
+	cfg.StoreConfig = s.storeCfg
+
+	if err := secret.Check(cfg.DbConfig.Pass); err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.restore]persisted password is not a secret reference, skipping pipeline",
+			zap.Uint32("id", cfg.ServerId),
+			zap.Error(err),
+		)
+
+		return
+	}
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	_, isKnown := s.syncmanagers[cfg.ServerId]
+	s.mu.Unlock()
 
-	logger.WithContext(ctx).Info("[SyncController.Remove]removing syncmanager", zap.Uint32("id", id))
+	if isKnown {
+		return
+	}
 
-	if manager, ok := s.syncmanagers[id]; !ok {
-		return ErrParam.New(fmt.Sprintf("[SyncController.Remove]id %d does not exist", id))
-	} else {
-		if manager.Status().IsRunning {
-			manager.Close()
-		}
+	manager, err := syncmanager.RestoreSyncManager(ctx, &cfg)
+	if err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.restore]fail to restore syncmanager",
+			zap.Uint32("id", cfg.ServerId),
+			zap.Error(err),
+		)
 
-		delete(s.syncmanagers, id)
+		return
+	}
 
-		if err := os.Remove(fmt.Sprintf("canal%d.log", manager.GetId())); err != nil {
-			logger.WithContext(ctx).Error(
-				"[SyncController.Remove]fail to delete log file",
-				zap.Uint32("server id", manager.GetId()),
-			)
+	s.mu.Lock()
+	if _, isKnown = s.syncmanagers[manager.GetId()]; !isKnown && !s.isClosing {
+		s.syncmanagers[manager.GetId()] = manager
+		s.owners[manager.GetId()] = ownership{
+			origin:              pipeline.GetOrigin(),
+			isStoppedByOperator: pipeline.IsStoppedByOperator,
 		}
 	}
+	s.mu.Unlock()
+
+	if isKnown || s.isClosing {
+		manager.Close()
+
+		return
+	}
+
+	logger.WithContext(ctx).Info(
+		"[SyncController.restore]restored syncmanager",
+		zap.Uint32("id", manager.GetId()),
+		zap.String("desired state", pipeline.DesiredState),
+	)
+
+	if pipeline.DesiredState != pipelinestore.DESIRED_RUNNING {
+		return
+	}
+
+	// only redo the legacy sync if it never got as far as saving a checkpoint
+	isLegacySync := pipeline.IsLegacySync && manager.Checkpoint().Name == ""
+
+	if err := s.Start(ctx, manager.GetId(), isLegacySync); err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.restore]fail to resume syncmanager",
+			zap.Uint32("id", manager.GetId()),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *syncController) Remove(ctx context.Context, id uint32) error {
+	logger.WithContext(ctx).Info("[SyncController.Remove]removing syncmanager", zap.Uint32("id", id))
+
+	if _, err := s.begin(id); err != nil {
+		return err
+	}
+
+	defer s.end(id)
+
+	manager := s.detach(id)
+	manager.Close()
+
+	s.mu.Lock()
+	delete(s.syncmanagers, id)
+	delete(s.supervisions, id)
+	delete(s.owners, id)
+	s.mu.Unlock()
+
+	metrics.DeletePipeline(id)
+
+	if err := s.store.Delete(ctx, id); err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.Remove]fail to delete pipeline definition",
+			zap.Uint32("server id", id),
+			zap.Error(err),
+		)
+	}
+
+	if err := os.Remove(fmt.Sprintf("canal%d.log", manager.GetId())); err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.Remove]fail to delete log file",
+			zap.Uint32("server id", manager.GetId()),
+		)
+	}
 
 	return nil
 }
 
+// Start - runs the syncmanager under supervision
+//
+// A syncmanager that was stopped or paused is rebuilt from its config first and
+// continues from its checkpoint
 func (s *syncController) Start(ctx context.Context, id uint32, isLegacySync bool) error {
+	if _, err := s.begin(id); err != nil {
+		return err
+	}
+
+	defer s.end(id)
+
+	return s.start(ctx, id, isLegacySync)
+}
+
+// start - see Start. Must be called between begin and end
+func (s *syncController) start(ctx context.Context, id uint32, isLegacySync bool) error {
+	manager, err := s.reopen(ctx, s.current(id))
+	if err != nil {
+		return err
+	}
+
+	s.ensureSupervised(ctx, id, manager, isLegacySync)
+
+	s.mu.Lock()
+	owner := s.owners[id]
+	owner.isStoppedByOperator = false
+	s.owners[id] = owner
+	s.mu.Unlock()
+
+	return s.persist(ctx, manager, pipelinestore.DESIRED_RUNNING, isLegacySync)
+}
+
+// ensureSupervised - supervises manager unless it is running or already supervised, keeping
+// its restart history. Must be called between begin and end
+func (s *syncController) ensureSupervised(
+	ctx context.Context,
+	id uint32,
+	manager syncmanager.SyncManager,
+	isLegacySync bool,
+) {
+	isRunning := manager.Status().IsRunning
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if manager, ok := s.syncmanagers[id]; !ok {
-		return ErrParam.New(fmt.Sprintf("[SyncController.Start]id %d does not exist", id))
-	} else if ok {
-		if !manager.Status().IsRunning {
-			go func() {
-				if err := manager.Run(isLegacySync); err != nil {
-					logger.WithContext(ctx).Error("[SyncController.Start]fail to run syncmanager", zap.Error(err))
-				}
-			}()
-		}
+	sup, isSupervised := s.supervisions[id]
+	if isRunning || (isSupervised && sup.ctx.Err() == nil) {
+		return
 	}
 
-	return nil
+	supCtx, supCancel := context.WithCancel(ctx)
+
+	newSup := &supervision{
+		ctx:    supCtx,
+		cancel: supCancel,
+	}
+
+	if isSupervised {
+		newSup.restarts = sup.restarts
+		newSup.lastFailure = sup.lastFailure
+		newSup.lastFailureTime = sup.lastFailureTime
+	}
+
+	s.supervisions[id] = newSup
+
+	go s.supervise(ctx, newSup, manager, isLegacySync)
 }
 
-func (s *syncController) Stop(ctx context.Context, id uint32) error {
+// isSupervised - indicates if the syncmanager of id is supervised
+func (s *syncController) isSupervised(id uint32) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if manager, ok := s.syncmanagers[id]; !ok {
-		return ErrParam.New(fmt.Sprintf("[SyncController.Stop]id %d does not exist", id))
-	} else if ok {
-		if manager.Status().IsRunning {
-			manager.Close()
+	sup, ok := s.supervisions[id]
+
+	return ok && sup.ctx.Err() == nil
+}
+
+// Stop - drains and closes the syncmanager, saving the checkpoint of acknowledged messages
+//
+// The pipeline stays stopped until started again, even if declared in the config file
+func (s *syncController) Stop(ctx context.Context, id uint32) (*syncmanager.DrainResult, error) {
+	if _, err := s.begin(id); err != nil {
+		return nil, err
+	}
+
+	defer s.end(id)
+
+	s.mu.Lock()
+	owner := s.owners[id]
+	owner.isStoppedByOperator = true
+	s.owners[id] = owner
+	s.mu.Unlock()
+
+	return s.halt(ctx, id, pipelinestore.DESIRED_STOPPED)
+}
+
+// Pause - closes the syncmanager, saving its checkpoint, so that it can be resumed later
+//
+// Paused pipelines stay paused across restarts until resumed
+func (s *syncController) Pause(ctx context.Context, id uint32) (*syncmanager.DrainResult, error) {
+	logger.WithContext(ctx).Info("[SyncController.Pause]pausing syncmanager", zap.Uint32("id", id))
+
+	if _, err := s.begin(id); err != nil {
+		return nil, err
+	}
+
+	defer s.end(id)
+
+	return s.halt(ctx, id, pipelinestore.DESIRED_PAUSED)
+}
+
+// Resume - rebuilds a paused or stopped syncmanager and continues from its checkpoint
+func (s *syncController) Resume(ctx context.Context, id uint32) error {
+	logger.WithContext(ctx).Info("[SyncController.Resume]resuming syncmanager", zap.Uint32("id", id))
+
+	if _, err := s.begin(id); err != nil {
+		return err
+	}
+
+	defer s.end(id)
+
+	return s.start(ctx, id, false)
+}
+
+// Update - replaces the sources of a syncmanager, keeping its server id and checkpoint
+//
+// canal cannot change its tables while running, so the syncmanager is paused and rebuilt
+// with the new sources, then started again from its checkpoint if it was meant to run, or
+// else left stopped or paused.
+// isBackfill snapshots existing records of newly added tables before streaming resumes.
+// If the new sources are invalid, the syncmanager is rebuilt with its previous sources.
+func (s *syncController) Update(
+	ctx context.Context,
+	id uint32,
+	sources []config.SourceConfig,
+	isBackfill bool,
+) error {
+	logger.WithContext(ctx).Info("[SyncController.Update]updating syncmanager sources", zap.Uint32("id", id))
+
+	if len(sources) == 0 {
+		return ErrParam.New("[SyncController.Update]sources cannot be empty")
+	}
+
+	manager, err := s.begin(id)
+	if err != nil {
+		return err
+	}
+
+	defer s.end(id)
+
+	newCfg := manager.GetConfig()
+	newCfg.Sources = sources
+
+	return s.reconfigure(ctx, id, newCfg, isBackfill)
+}
+
+// reconfigure - rebuilds a syncmanager with newCfg, keeping its server id, checkpoint and
+// desired state
+//
+// The syncmanager is paused and rebuilt, then started again from its checkpoint if it was
+// supervised or desired to run, including while backing off or standing by, or else persisted
+// stopped or paused as it was. If newCfg is invalid, the syncmanager is rebuilt with its
+// previous config. Must be called between begin and end
+func (s *syncController) reconfigure(
+	ctx context.Context,
+	id uint32,
+	newCfg config.Config,
+	isBackfill bool,
+) error {
+	pipeline, err := s.definition(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if pipeline == nil {
+		return ErrNotFound.New(fmt.Sprintf("[SyncController.reconfigure]pipeline %d was deleted", id))
+	}
+
+	isRunning := s.isSupervised(id) || pipeline.DesiredState == pipelinestore.DESIRED_RUNNING
+	manager := s.detach(id)
+
+	oldTables := manager.Tables()
+	oldCfg := manager.GetConfig()
+
+	manager.Pause()
+
+	newCfg.ServerId = id
+
+	newManager, err := syncmanager.RestoreSyncManager(ctx, &newCfg)
+	if err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.reconfigure]fail to rebuild syncmanager with new config, rolling back",
+			zap.Uint32("id", id),
+			zap.Error(err),
+		)
+
+		if rollbackErr := s.rollback(ctx, id, oldCfg, isRunning); rollbackErr != nil {
+			return rollbackErr
 		}
+
+		return err
 	}
 
-	return nil
+	if isBackfill {
+		newManager.SetBackfill(addedTables(oldTables, newManager.Tables()))
+	}
+
+	s.swap(id, newManager)
+
+	if !isRunning {
+		return s.persist(ctx, newManager, pipeline.DesiredState, false)
+	}
+
+	return s.start(ctx, id, false)
+}
+
+// rollback - rebuilds the syncmanager with cfg, restarting it if it was running
+//
+// Must be called between begin and end
+func (s *syncController) rollback(ctx context.Context, id uint32, cfg config.Config, isRunning bool) error {
+	manager, err := syncmanager.RestoreSyncManager(ctx, &cfg)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncController.rollback]fail to rebuild syncmanager", zap.Uint32("id", id), zap.Error(err))
+
+		return err
+	}
+
+	s.swap(id, manager)
+
+	if !isRunning {
+		return nil
+	}
+
+	return s.start(ctx, id, false)
+}
+
+// halt - stops supervising and closes the syncmanager, persisting desiredState
+//
+// Returns a nil drain result if the syncmanager was already closed. Must be called between
+// begin and end
+func (s *syncController) halt(
+	ctx context.Context,
+	id uint32,
+	desiredState string,
+) (*syncmanager.DrainResult, error) {
+	manager := s.detach(id)
+
+	var drain *syncmanager.DrainResult
+
+	if desiredState == pipelinestore.DESIRED_PAUSED {
+		drain = manager.Pause()
+	} else {
+		drain = manager.Close()
+	}
+
+	return drain, s.persist(ctx, manager, desiredState, false)
+}
+
+// reopen - rebuilds a closed syncmanager from its config, keeping its server id and checkpoint
+//
+// Must be called between begin and end
+func (s *syncController) reopen(
+	ctx context.Context,
+	manager syncmanager.SyncManager,
+) (syncmanager.SyncManager, error) {
+	if !manager.IsClosed() {
+		return manager, nil
+	}
+
+	cfg := manager.GetConfig()
+
+	newManager, err := syncmanager.RestoreSyncManager(ctx, &cfg)
+	if err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.reopen]fail to rebuild syncmanager",
+			zap.Uint32("id", cfg.ServerId),
+			zap.Error(err),
+		)
+
+		return nil, err
+	}
+
+	s.swap(cfg.ServerId, newManager)
+
+	return newManager, nil
+}
+
+// begin - marks pipeline id as transitioning and returns its syncmanager, so that an operation
+// can rebuild, drain and persist it without holding s.mu. Must be followed by end
+//
+// Other operations on the pipeline are rejected with ErrBusy until then, as are all operations
+// once the controller is closing
+func (s *syncController) begin(id uint32) (syncmanager.SyncManager, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isClosing {
+		return nil, ErrBusy.New("[SyncController.begin]controller is closing")
+	}
+
+	manager, ok := s.syncmanagers[id]
+	if !ok {
+		return nil, ErrParam.New(fmt.Sprintf("[SyncController.begin]id %d does not exist", id))
+	}
+
+	if s.transitioning[id] {
+		return nil, ErrBusy.New(fmt.Sprintf("[SyncController.begin]pipeline %d is already being changed", id))
+	}
+
+	s.transitioning[id] = true
+
+	return manager, nil
+}
+
+// end - clears the transitioning mark of pipeline id set by begin
+func (s *syncController) end(id uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.transitioning, id)
+	s.settled.Broadcast()
 }
 
-func (s *syncController) Close(ctx context.Context) error {
+// current - returns the syncmanager of id, which its supervision may have rebuilt since begin
+func (s *syncController) current(id uint32) syncmanager.SyncManager {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.syncmanagers[id]
+}
+
+// detach - stops supervising the syncmanager of id and returns it
+func (s *syncController) detach(id uint32) syncmanager.SyncManager {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancelSupervision(id)
+
+	return s.syncmanagers[id]
+}
+
+// owner - returns who manages pipeline id
+func (s *syncController) owner(id uint32) ownership {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.owners[id]
+}
+
+// swap - replaces the syncmanager of id with manager
+func (s *syncController) swap(id uint32, manager syncmanager.SyncManager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.syncmanagers[id] = manager
+}
+
+// Close - drains and closes all syncmanagers in parallel, keeping their definitions so they
+// are restored on restart
+//
+// New operations are rejected with ErrBusy, and those in flight finish first, so that no
+// syncmanager they build is left open. Returns the drain result of every syncmanager that was
+// still open
+func (s *syncController) Close(ctx context.Context) (map[uint32]*syncmanager.DrainResult, error) {
+	// the heartbeat loop takes s.mu, so it is stopped first
+	s.stopHeartbeat()
+	<-s.heartbeatDone
+
 	logger.WithContext(ctx).Info("[SyncController.Close]closing all syncmanagers")
 
-	for key := range s.syncmanagers {
-		if err := s.Remove(ctx, key); err != nil {
-			logger.WithContext(ctx).Error("[SyncController.Close]fail to close syncmanagers", zap.Error(err))
+	s.mu.Lock()
+	s.isClosing = true
 
-			return err
-		}
+	for len(s.transitioning) > 0 {
+		s.settled.Wait()
+	}
+
+	managers := make(map[uint32]syncmanager.SyncManager, len(s.syncmanagers))
+
+	for id, manager := range s.syncmanagers {
+		// supervisions check for cancellation under s.mu before installing a rebuilt syncmanager
+		s.cancelSupervision(id)
+		s.transitioning[id] = true
+		managers[id] = manager
+	}
+	s.mu.Unlock()
+
+	var (
+		wg      sync.WaitGroup
+		drainMu sync.Mutex
+	)
+
+	drains := make(map[uint32]*syncmanager.DrainResult)
+
+	for id, manager := range managers {
+		wg.Add(1)
+
+		go func(id uint32, manager syncmanager.SyncManager) {
+			defer wg.Done()
+
+			if drain := manager.Close(); drain != nil {
+				drainMu.Lock()
+				drains[id] = drain
+				drainMu.Unlock()
+			}
+		}(id, manager)
+	}
+
+	wg.Wait()
+
+	// leave the cluster so that other instances take over without waiting for the timeout
+	if err := s.registry.Deregister(ctx, s.instanceId); err != nil {
+		logger.WithContext(ctx).Error("[SyncController.Close]fail to deregister instance", zap.Error(err))
+	}
+
+	if err := s.registry.Close(); err != nil {
+		logger.WithContext(ctx).Error("[SyncController.Close]fail to close registry", zap.Error(err))
+	}
+
+	if err := s.lock.Close(); err != nil {
+		logger.WithContext(ctx).Error("[SyncController.Close]fail to close leadership lock", zap.Error(err))
+	}
+
+	if err := s.store.Close(); err != nil {
+		logger.WithContext(ctx).Error("[SyncController.Close]fail to close pipeline store", zap.Error(err))
+
+		return drains, err
+	}
+
+	return drains, nil
+}
+
+// cancelSupervision - stops restarting the syncmanager, keeping its restart history
+//
+// Must be called with s.mu held
+func (s *syncController) cancelSupervision(id uint32) {
+	if sup, ok := s.supervisions[id]; ok {
+		sup.cancel()
+	}
+}
+
+// persist - saves the manager's pipeline definition with the given desired state
+func (s *syncController) persist(
+	ctx context.Context,
+	manager syncmanager.SyncManager,
+	desiredState string,
+	isLegacySync bool,
+) error {
+	if err := s.store.Save(ctx, s.definitionOf(manager, desiredState, isLegacySync)); err != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncController.persist]fail to save pipeline definition",
+			zap.Uint32("server id", manager.GetId()),
+			zap.Error(err),
+		)
+
+		return err
 	}
 
 	return nil
 }
+
+// definitionOf - returns the pipeline definition of manager with the given desired state
+func (s *syncController) definitionOf(
+	manager syncmanager.SyncManager,
+	desiredState string,
+	isLegacySync bool,
+) *pipelinestore.Pipeline {
+	owner := s.owner(manager.GetId())
+
+	return &pipelinestore.Pipeline{
+		Config:              manager.GetConfig(),
+		DesiredState:        desiredState,
+		Origin:              owner.origin,
+		IsLegacySync:        isLegacySync,
+		IsStoppedByOperator: owner.isStoppedByOperator,
+	}
+}
+
+// addedTables - returns the tables of newTables that are not in oldTables
+func addedTables(oldTables, newTables map[string][]string) map[string][]string {
+	added := make(map[string][]string)
+
+	for schema, tables := range newTables {
+		existing := make(map[string]bool, len(oldTables[schema]))
+		for _, table := range oldTables[schema] {
+			existing[table] = true
+		}
+
+		for _, table := range tables {
+			if !existing[table] {
+				added[schema] = append(added[schema], table)
+			}
+		}
+	}
+
+	return added
+}
+
+// definition - returns the stored definition of pipeline id, or nil if it was deleted
+func (s *syncController) definition(ctx context.Context, id uint32) (*pipelinestore.Pipeline, error) {
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncController.definition]fail to list pipelines", zap.Error(err))
+
+		return nil, err
+	}
+
+	for _, pipeline := range pipelines {
+		if pipeline.Config.ServerId == id {
+			return pipeline, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -11,7 +11,18 @@ const (
 // Format specifiers
 const (
 	SOURCE_KEY_FORMAT  = "%s.%s"
-	WILDCARD_TABLE_SQL = `"SELECT table_name FROM information_schema.tables WHERE table_name RLIKE "%s" AND table_schema = "%s";`
+	WILDCARD_TABLE_SQL = "SELECT table_name FROM information_schema.tables WHERE table_name RLIKE ? AND table_schema = ?"
+)
+
+// mysqldump options
+const (
+	SKIP_SINGLE_TRANSACTION_OPTION = "--skip-single-transaction"
+	LOCK_TABLES_OPTION             = "--lock-tables"
+	LOCK_ALL_TABLES_OPTION         = "--lock-all-tables"
+	DUMP_CHARSET_OPTION_FORMAT     = "--default-character-set=%s"
+	IGNORE_TABLE_FORMAT            = "%s,%s"
+	TABLE_KEY_SEPARATOR            = "."
+	TABLE_KEY_PARTS                = 2
 )
 
 // Log constants
@@ -24,3 +35,59 @@ const (
 	SAVE_INTERVAL     = 3 * time.Second
 	SYNC_CHANNEL_SIZE = 4096
 )
+
+// source sampling constants
+const (
+	SOURCE_POLL_INTERVAL      = 5 * time.Second
+	SHOW_MASTER_STATUS_SQL    = "SHOW MASTER STATUS"
+	SHOW_BINARY_LOGS_SQL      = "SHOW BINARY LOGS"
+	MASTER_STATUS_FILE_COLUMN = 0
+	MASTER_STATUS_POS_COLUMN  = 1
+	MASTER_STATUS_GTID_COLUMN = 4
+	BINARY_LOGS_NAME_COLUMN   = 0
+	BINARY_LOGS_SIZE_COLUMN   = 1
+)
+
+// drain constants
+const (
+	DEFAULT_DRAIN_TIMEOUT = 10 * time.Second
+	DRAIN_POLL_INTERVAL   = 50 * time.Millisecond
+)
+
+// validation constants
+const (
+	FULL_ROW_IMAGE    = "FULL"
+	ROW_BINLOG_FORMAT = "ROW"
+	GTID_MODE_ON      = "ON"
+	SHOW_GRANTS_SQL   = "SHOW GRANTS FOR CURRENT_USER()"
+	SHOW_VARIABLE_SQL = "SHOW GLOBAL VARIABLES LIKE ?"
+	PRIMARY_KEY_SQL   = "SELECT COUNT(*) FROM information_schema.table_constraints WHERE constraint_type = 'PRIMARY KEY' AND table_schema = ? AND table_name = ?"
+	ALL_PRIVILEGES    = "ALL PRIVILEGES"
+	BINLOG_FORMAT_VAR = "binlog_format"
+	BINLOG_IMAGE_VAR  = "binlog_row_image"
+	GTID_MODE_VAR     = "gtid_mode"
+	PRIVILEGE_SELECT  = "SELECT"
+	PRIVILEGE_REPL    = "REPLICATION SLAVE"
+	PRIVILEGE_CLIENT  = "REPLICATION CLIENT"
+)
+
+// Validation check results
+const (
+	CHECK_OK      = "ok"
+	CHECK_WARN    = "warn"
+	CHECK_FAIL    = "fail"
+	CHECK_SKIPPED = "skipped"
+)
+
+// Validation check names
+const (
+	CHECK_CONNECT       = "mysql connectivity"
+	CHECK_PRIVILEGES    = "mysql privileges"
+	CHECK_BINLOG_FORMAT = "binlog format"
+	CHECK_BINLOG_IMAGE  = "binlog row image"
+	CHECK_GTID_MODE     = "gtid mode"
+	CHECK_TABLES        = "source tables"
+	CHECK_PRIMARY_KEYS  = "primary keys"
+	CHECK_KAFKA_BROKERS = "kafka brokers"
+	CHECK_KAFKA_TOPIC   = "kafka topic"
+)
@@ -0,0 +1,29 @@
+package syncmanager
+
+import (
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/config"
+)
+
+// DrainResult - outcome of draining a syncmanager on close
+//
+// IsCompleted is false if messages were still in flight when the drain timed out or kafka
+// rejected any, in which case Checkpoint is the last position whose messages were all
+// acknowledged. Failed is the number of rejected messages
+type DrainResult struct {
+	Checkpoint  mysql.Position
+	Duration    time.Duration
+	InFlight    int
+	Failed      int
+	IsCompleted bool
+}
+
+func drainTimeout(drainCfg config.DrainConfig) time.Duration {
+	if drainCfg.Timeout > 0 {
+		return time.Duration(drainCfg.Timeout) * time.Millisecond
+	}
+
+	return DEFAULT_DRAIN_TIMEOUT
+}
@@ -16,4 +16,7 @@ var (
 	ErrParam   = errortype.ErrorType{Code: 6, Pkg: pkg}
 	ErrSave    = errortype.ErrorType{Code: 7, Pkg: pkg}
 	ErrEvent   = errortype.ErrorType{Code: 8, Pkg: pkg}
+	ErrRun     = errortype.ErrorType{Code: 9, Pkg: pkg}
+	ErrState   = errortype.ErrorType{Code: 10, Pkg: pkg}
+	ErrQuota   = errortype.ErrorType{Code: 11, Pkg: pkg}
 )
@@ -0,0 +1,197 @@
+package syncmanager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	eventsync "github.com/twothicc/canal/handlers/events/sync"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// Replication - how far a syncmanager is behind its source
+//
+// SourcePosition and SourceGTIDSet are sampled from SHOW MASTER STATUS every
+// SOURCE_POLL_INTERVAL while running, as of SourceCheckedAt. LagBytes spans binlog files
+// using the sizes from SHOW BINARY LOGS. LagSeconds is now minus LastEventTime, so it keeps
+// growing while the source is idle. Throughput is keyed by action
+type Replication struct {
+	SourceCheckedAt    time.Time
+	LastEventTime      time.Time
+	LastCheckpointTime time.Time
+	Throughput         map[string]eventsync.Rate
+	Position           mysql.Position
+	SourcePosition     mysql.Position
+	GTIDSet            string
+	SourceGTIDSet      string
+	LagSeconds         float64
+	LagBytes           uint64
+	InFlight           int
+}
+
+// binlogFile - a binlog file of the source and its size in bytes
+type binlogFile struct {
+	name string
+	size uint64
+}
+
+// sourceSample - latest position of the source and its binlog files
+type sourceSample struct {
+	at     time.Time
+	pos    mysql.Position
+	gtid   string
+	binlog []binlogFile
+}
+
+// sourceSampler - race-free latest sample of the source
+type sourceSampler struct {
+	sample sourceSample
+	mu     sync.RWMutex
+}
+
+func (s *sourceSampler) store(sample sourceSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sample = sample
+}
+
+func (s *sourceSampler) load() sourceSample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sample
+}
+
+// replication - returns how far the syncmanager is behind its source as of its latest sample
+func (sm *syncManager) replication() *Replication {
+	stats := sm.eventHandler.Stats()
+	sample := sm.source.load()
+
+	res := &Replication{
+		SourceCheckedAt: sample.at,
+		SourcePosition:  sample.pos,
+		SourceGTIDSet:   sample.gtid,
+		LastEventTime:   stats.LastEventTime,
+		Throughput:      stats.Rates,
+		Position:        sm.canal.SyncedPosition(),
+		InFlight:        sm.eventHandler.InFlight(),
+	}
+
+	// canal only knows its position once streaming
+	if res.Position.Name == "" {
+		res.Position = sm.saveInfo.Position()
+	}
+
+	if gtidSet := sm.canal.SyncedGTIDSet(); gtidSet != nil {
+		res.GTIDSet = gtidSet.String()
+	}
+
+	if checkpointTime, ok := sm.lastCheckpoint.Load().(time.Time); ok {
+		res.LastCheckpointTime = checkpointTime
+	}
+
+	if !stats.LastEventTime.IsZero() {
+		res.LagSeconds = time.Since(stats.LastEventTime).Seconds()
+	}
+
+	res.LagBytes = lagBytes(res.Position, sample.pos, sample.binlog)
+
+	return res
+}
+
+// sampleSourceLoop - samples the latest position of the source until the syncmanager is closed
+func (sm *syncManager) sampleSourceLoop() {
+	ticker := time.NewTicker(SOURCE_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		if err := sm.sampleSource(); err != nil {
+			logger.WithContext(sm.ctx).Warn(
+				"[SyncManager.sampleSourceLoop]fail to sample source position",
+				zap.Uint32("server id", sm.cfg.ServerId),
+				zap.Error(err),
+			)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-sm.ctx.Done():
+			return
+		}
+	}
+}
+
+// sampleSource - reads the latest position of the source and the sizes of its binlog files
+func (sm *syncManager) sampleSource() error {
+	res, err := sm.canal.Execute(SHOW_MASTER_STATUS_SQL)
+	if err != nil {
+		return ErrQuery.New(fmt.Sprintf("[SyncManager.sampleSource]%s", err.Error()))
+	}
+
+	if res.Resultset.RowNumber() == 0 {
+		return ErrBinlog.New("[SyncManager.sampleSource]binary logging is disabled on the source")
+	}
+
+	name, _ := res.GetString(0, MASTER_STATUS_FILE_COLUMN)
+	pos, _ := res.GetUint(0, MASTER_STATUS_POS_COLUMN)
+
+	// MariaDB does not report executed gtids here
+	gtid, _ := res.GetString(0, MASTER_STATUS_GTID_COLUMN)
+
+	sample := sourceSample{
+		at: time.Now(),
+		pos: mysql.Position{
+			Name: name,
+			Pos:  uint32(pos),
+		},
+		gtid: gtid,
+	}
+
+	// without the file sizes, lag is only known within the same binlog file
+	if logs, logsErr := sm.canal.Execute(SHOW_BINARY_LOGS_SQL); logsErr == nil {
+		for rowNum := 0; rowNum < logs.Resultset.RowNumber(); rowNum++ {
+			logName, _ := logs.GetString(rowNum, BINARY_LOGS_NAME_COLUMN)
+			logSize, _ := logs.GetUint(rowNum, BINARY_LOGS_SIZE_COLUMN)
+
+			sample.binlog = append(sample.binlog, binlogFile{
+				name: logName,
+				size: logSize,
+			})
+		}
+	}
+
+	sm.source.store(sample)
+
+	return nil
+}
+
+// lagBytes - returns the bytes of binlog between current and latest, spanning the binlog files
+// in between when their sizes are known
+func lagBytes(current, latest mysql.Position, binlog []binlogFile) uint64 {
+	if current.Name == "" || latest.Name == "" || current.Compare(latest) >= 0 {
+		return 0
+	}
+
+	if current.Name == latest.Name {
+		return uint64(latest.Pos - current.Pos)
+	}
+
+	var lag uint64
+
+	for _, file := range binlog {
+		switch cmp := mysql.CompareBinlogFileName(file.name, current.Name); {
+		case cmp < 0:
+		case cmp == 0:
+			if file.size > uint64(current.Pos) {
+				lag += file.size - uint64(current.Pos)
+			}
+		case mysql.CompareBinlogFileName(file.name, latest.Name) < 0:
+			lag += file.size
+		}
+	}
+
+	return lag + uint64(latest.Pos)
+}
@@ -1,10 +1,25 @@
 package savemanager
 
+import "time"
+
 const (
 	SAVE_DIR             = "./syncdata"
 	SAVE_FILE_PERMISSION = 0o644
 )
 
+const (
+	PING_FILE_PATTERN = ".ping-*"
+	PING_TIMEOUT      = 5 * time.Second
+)
+
 const (
 	BASE10 = 10
 )
+
+// MySQL checkpoint constants
+const (
+	DEFAULT_TABLE    = "checkpoints"
+	CREATE_TABLE_SQL = "CREATE TABLE IF NOT EXISTS `%s` (`id` INT UNSIGNED NOT NULL PRIMARY KEY, `bin_name` VARCHAR(255) NOT NULL, `bin_pos` INT UNSIGNED NOT NULL, `mtime` INT UNSIGNED NOT NULL)"
+	UPSERT_SQL       = "INSERT INTO `%s` (`id`, `bin_name`, `bin_pos`, `mtime`) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE `bin_name` = VALUES(`bin_name`), `bin_pos` = VALUES(`bin_pos`), `mtime` = VALUES(`mtime`)"
+	SELECT_SQL       = "SELECT `bin_name`, `bin_pos` FROM `%s` WHERE `id` = ?"
+)
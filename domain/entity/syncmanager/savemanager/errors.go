@@ -6,5 +6,7 @@ const pkg = "domain/entity/syncmanager/savemanager"
 
 //nolint:gomnd // error code
 var (
-	ErrFile = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrFile    = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrConnect = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrQuery   = errortype.ErrorType{Code: 3, Pkg: pkg}
 )
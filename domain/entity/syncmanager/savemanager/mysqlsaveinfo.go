@@ -0,0 +1,164 @@
+package savemanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// mySQLSaveInfo - stores the checkpoint of a syncmanager in a metadata table
+type mySQLSaveInfo struct {
+	lastSaveTime time.Time
+	conn         *client.Conn
+	storeCfg     config.StoreConfig
+	table        string
+	pos          mysql.Position
+	mu           sync.Mutex
+	serverId     uint32
+}
+
+func loadMySQLSaveInfo(ctx context.Context, storeCfg config.StoreConfig, serverId uint32) (ISaveInfo, error) {
+	table := storeCfg.CheckpointTable
+	if table == "" {
+		table = DEFAULT_TABLE
+	}
+
+	s := &mySQLSaveInfo{
+		lastSaveTime: time.Now(),
+		storeCfg:     storeCfg,
+		table:        table,
+		serverId:     serverId,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.execute(ctx, fmt.Sprintf(CREATE_TABLE_SQL, table)); err != nil {
+		return nil, err
+	}
+
+	res, err := s.execute(ctx, fmt.Sprintf(SELECT_SQL, table), serverId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	if res.Resultset.RowNumber() > 0 {
+		name, _ := res.GetString(0, 0)
+		pos, _ := res.GetUint(0, 1)
+
+		s.pos = mysql.Position{
+			Name: name,
+			Pos:  uint32(pos),
+		}
+	}
+
+	return s, nil
+}
+
+// pingMySQL - connects to the store's database within PING_TIMEOUT
+func pingMySQL(ctx context.Context, storeCfg config.StoreConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, PING_TIMEOUT)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+
+	conn, err := client.ConnectWithDialer(
+		ctx, "", storeCfg.Addr, storeCfg.User, storeCfg.Pass, storeCfg.Database, dialer.DialContext,
+	)
+	if err != nil {
+		return ErrConnect.New(fmt.Sprintf("[SaveManager.pingMySQL]%s", err.Error()))
+	}
+
+	defer conn.Close()
+
+	if err := conn.Ping(); err != nil {
+		return ErrConnect.New(fmt.Sprintf("[SaveManager.pingMySQL]%s", err.Error()))
+	}
+
+	return nil
+}
+
+// Save - records pos, writing it to the table at most once a second
+func (s *mySQLSaveInfo) Save(ctx context.Context, pos mysql.Position) error {
+	return s.save(ctx, pos, false)
+}
+
+func (s *mySQLSaveInfo) save(ctx context.Context, pos mysql.Position, isForced bool) error {
+	logger.WithContext(ctx).Debug(fmt.Sprintf("[SaveManager.Save]%s", pos))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pos = pos
+
+	n := time.Now()
+	if !isForced && n.Sub(s.lastSaveTime) < time.Second {
+		return nil
+	}
+
+	s.lastSaveTime = n
+
+	_, err := s.execute(ctx, fmt.Sprintf(UPSERT_SQL, s.table), s.serverId, pos.Name, pos.Pos, n.Unix())
+
+	return err
+}
+
+func (s *mySQLSaveInfo) Position() mysql.Position {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pos
+}
+
+// Close - writes the last recorded position to the table and closes the connection
+func (s *mySQLSaveInfo) Close(ctx context.Context) error {
+	err := s.save(ctx, s.Position(), true)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return err
+}
+
+// execute - runs a statement, reconnecting once if the connection was lost. Must be called with s.mu held
+func (s *mySQLSaveInfo) execute(ctx context.Context, query string, args ...interface{}) (*mysql.Result, error) {
+	if s.conn != nil && s.conn.Ping() != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	if s.conn == nil {
+		conn, err := client.Connect(s.storeCfg.Addr, s.storeCfg.User, s.storeCfg.Pass, s.storeCfg.Database)
+		if err != nil {
+			logger.WithContext(ctx).Error("[SaveManager.execute]fail to connect", zap.Error(err))
+
+			return nil, ErrConnect.New(fmt.Sprintf("[SaveManager.execute]%s", err.Error()))
+		}
+
+		s.conn = conn
+	}
+
+	res, err := s.conn.Execute(query, args...)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SaveManager.execute]fail to execute", zap.String("raw sql", query), zap.Error(err))
+
+		return nil, ErrQuery.New(fmt.Sprintf("[SaveManager.execute]%s", err.Error()))
+	}
+
+	return res, nil
+}
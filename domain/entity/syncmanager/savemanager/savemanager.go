@@ -15,6 +15,7 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/siddontang/go/ioutil2"
+	"github.com/twothicc/canal/config"
 	"github.com/twothicc/common-go/logger"
 	"go.uber.org/zap"
 )
@@ -33,10 +34,56 @@ type ISaveInfo interface {
 	Close(ctx context.Context) error
 }
 
-func LoadSaveInfo(ctx context.Context, serverId uint32) (ISaveInfo, error) {
+// LoadSaveInfo - loads the checkpoint of serverId from the store configured by storeCfg
+//
+// Checkpoints follow the pipeline store, so that with a shared mysql store any instance
+// can continue a pipeline from where another left off
+func LoadSaveInfo(ctx context.Context, storeCfg config.StoreConfig, serverId uint32) (ISaveInfo, error) {
+	if storeCfg.Type == config.STORE_TYPE_MYSQL {
+		return loadMySQLSaveInfo(ctx, storeCfg, serverId)
+	}
+
+	saveDir := storeCfg.CheckpointDir
+	if saveDir == "" {
+		saveDir = SAVE_DIR
+	}
+
+	return loadFileSaveInfo(ctx, saveDir, serverId)
+}
+
+// Ping - checks that checkpoints can be written to the store configured by storeCfg
+func Ping(ctx context.Context, storeCfg config.StoreConfig) error {
+	if storeCfg.Type == config.STORE_TYPE_MYSQL {
+		return pingMySQL(ctx, storeCfg)
+	}
+
+	saveDir := storeCfg.CheckpointDir
+	if saveDir == "" {
+		saveDir = SAVE_DIR
+	}
+
+	if err := os.MkdirAll(saveDir, os.ModePerm); err != nil {
+		return ErrFile.New(fmt.Sprintf("[SaveManager.Ping]%s", err.Error()))
+	}
+
+	file, err := os.CreateTemp(saveDir, PING_FILE_PATTERN)
+	if err != nil {
+		return ErrFile.New(fmt.Sprintf("[SaveManager.Ping]%s", err.Error()))
+	}
+
+	file.Close()
+
+	if err := os.Remove(file.Name()); err != nil {
+		logger.WithContext(ctx).Error("[SaveManager.Ping]fail to remove ping file", zap.Error(err))
+	}
+
+	return nil
+}
+
+func loadFileSaveInfo(ctx context.Context, saveDir string, serverId uint32) (ISaveInfo, error) {
 	var s SaveInfo
 
-	dir := path.Join(SAVE_DIR, strconv.FormatUint(uint64(serverId), BASE10))
+	dir := path.Join(saveDir, strconv.FormatUint(uint64(serverId), BASE10))
 	filePath := path.Join(dir, "save.info")
 
 	s.filePath = filePath
@@ -62,7 +109,12 @@ func LoadSaveInfo(ctx context.Context, serverId uint32) (ISaveInfo, error) {
 	return &s, err
 }
 
+// Save - records pos, writing it to file at most once a second
 func (s *SaveInfo) Save(ctx context.Context, pos mysql.Position) error {
+	return s.save(ctx, pos, false)
+}
+
+func (s *SaveInfo) save(ctx context.Context, pos mysql.Position, isForced bool) error {
 	logger.WithContext(ctx).Debug(fmt.Sprintf("[SaveManager.Save]%s", pos))
 
 	s.mu.Lock()
@@ -76,7 +128,7 @@ func (s *SaveInfo) Save(ctx context.Context, pos mysql.Position) error {
 	}
 
 	n := time.Now()
-	if n.Sub(s.lastSaveTime) < time.Second {
+	if !isForced && n.Sub(s.lastSaveTime) < time.Second {
 		return nil
 	}
 
@@ -107,8 +159,9 @@ func (s *SaveInfo) Position() mysql.Position {
 	}
 }
 
+// Close - writes the last recorded position to file
 func (s *SaveInfo) Close(ctx context.Context) error {
 	pos := s.Position()
 
-	return s.Save(ctx, pos)
+	return s.save(ctx, pos, true)
 }
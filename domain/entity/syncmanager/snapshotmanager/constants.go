@@ -0,0 +1,45 @@
+package snapshotmanager
+
+import "time"
+
+// Snapshot defaults
+const (
+	DEFAULT_CHUNK_SIZE         = 1000
+	DEFAULT_LAG_CHECK_INTERVAL = 1 * time.Second
+	REPLICA_WAIT_TIMEOUT       = 300
+)
+
+// Replica lag backoff
+const (
+	MIN_BACKOFF = 100 * time.Millisecond
+	MAX_BACKOFF = 10 * time.Second
+)
+
+// Format specifiers
+const (
+	TABLE_KEY_FORMAT    = "%s.%s"
+	TABLE_FORMAT        = "`%s`.`%s`"
+	COLUMN_FORMAT       = "`%s`"
+	SELECT_FORMAT       = "SELECT %s FROM %s"
+	WHERE_FORMAT        = " WHERE %s"
+	CONDITION_FORMAT    = "(%s)"
+	PK_AFTER_FORMAT     = "(%s) > (%s)"
+	ORDER_BY_FORMAT     = " ORDER BY %s"
+	LIMIT_FORMAT        = " LIMIT %d"
+	CONDITION_SEPARATOR = " AND "
+	REPLICA_WAIT_SQL    = "SELECT MASTER_POS_WAIT(?, ?, ?)"
+)
+
+// Replica status
+const (
+	// REPLICA_STOPPED_LAG - lag reported when replication is not running
+	REPLICA_STOPPED_LAG = ^uint32(0)
+	// REPLICA_WAIT_TIMED_OUT - MASTER_POS_WAIT result when the timeout is reached
+	REPLICA_WAIT_TIMED_OUT = -1
+)
+
+// Replica status queries and lag columns, newest syntax first
+var (
+	REPLICA_STATUS_SQLS = []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS"}
+	REPLICA_LAG_COLUMNS = []string{"Seconds_Behind_Source", "Seconds_Behind_Master"}
+)
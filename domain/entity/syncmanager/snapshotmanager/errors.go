@@ -0,0 +1,14 @@
+package snapshotmanager
+
+import "github.com/twothicc/common-go/errortype"
+
+const pkg = "domain/entity/syncmanager/snapshotmanager"
+
+//nolint:gomnd // error code
+var (
+	ErrConnect = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrQuery   = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrTable   = errortype.ErrorType{Code: 3, Pkg: pkg}
+	ErrEvent   = errortype.ErrorType{Code: 4, Pkg: pkg}
+	ErrReplica = errortype.ErrorType{Code: 5, Pkg: pkg}
+)
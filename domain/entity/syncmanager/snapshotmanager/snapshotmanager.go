@@ -0,0 +1,590 @@
+package snapshotmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/canal"
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/schema"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/tools/metrics"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+type ISnapshotManager interface {
+	Run(ctx context.Context, tables map[string][]string) (mysql.Position, error)
+}
+
+type snapshotManager struct {
+	canal        *canal.Canal
+	eventHandler canal.EventHandler
+	throttle     *throttle
+	skipTables   map[string]bool
+	tableWhere   map[string]string
+	where        string
+	dbCfg        config.DbConfig
+	dbPass       string
+	snapshotCfg  config.SnapshotConfig
+	serverId     uint32
+}
+
+type tableJob struct {
+	schema string
+	table  string
+}
+
+// NewSnapshotManager - creates a snapshot manager that reads tables with a pool of workers
+//
+// Rows are handed to eventHandler as insert events, the same way canal handles mysqldump output.
+// dbPass is the resolved password of the database in cfg
+func NewSnapshotManager(
+	cfg *config.Config,
+	dbPass string,
+	c *canal.Canal,
+	eventHandler canal.EventHandler,
+) ISnapshotManager {
+	snapshotCfg := cfg.SnapshotConfig
+	if snapshotCfg.ChunkSize <= 0 {
+		snapshotCfg.ChunkSize = DEFAULT_CHUNK_SIZE
+	}
+
+	if snapshotCfg.Workers <= 0 {
+		snapshotCfg.Workers = 1
+	}
+
+	skipTables := make(map[string]bool, len(cfg.DumpConfig.SkipTables))
+	for _, table := range cfg.DumpConfig.SkipTables {
+		skipTables[table] = true
+	}
+
+	return &snapshotManager{
+		canal:        c,
+		skipTables:   skipTables,
+		tableWhere:   cfg.DumpConfig.TableWhere,
+		where:        cfg.DumpConfig.Where,
+		eventHandler: eventHandler,
+		throttle:     newThrottle(snapshotCfg.RowsPerSecond, snapshotCfg.MaxReplicaLag),
+		dbCfg:        cfg.DbConfig,
+		dbPass:       dbPass,
+		snapshotCfg:  snapshotCfg,
+		serverId:     cfg.ServerId,
+	}
+}
+
+// Run - snapshots all given tables and returns the binlog position to stream from
+//
+// tables maps each schema to its tables. Each table is read by a single worker in
+// primary key order, or in a single pass if it has no primary key, so per-table ordering
+// is kept while tables are read concurrently.
+// Tables configured as dump skip tables are not read.
+func (sm *snapshotManager) Run(ctx context.Context, tables map[string][]string) (mysql.Position, error) {
+	logger.WithContext(ctx).Info(
+		"[SnapshotManager.Run]starting snapshot",
+		zap.Uint32("server id", sm.serverId),
+		zap.Int("workers", sm.snapshotCfg.Workers),
+	)
+
+	start := time.Now()
+
+	pos, err := sm.canal.GetMasterPos()
+	if err != nil {
+		logger.WithContext(ctx).Error("[SnapshotManager.Run]fail to get master position", zap.Error(err))
+
+		return pos, ErrQuery.New(fmt.Sprintf("[SnapshotManager.Run]%s", err.Error()))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if sm.snapshotCfg.ReplicaAddr != "" {
+		if waitErr := sm.waitReplica(ctx, pos); waitErr != nil {
+			return pos, waitErr
+		}
+
+		go sm.monitorLag(ctx)
+	}
+
+	jobCh := make(chan tableJob)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for i := 0; i < sm.snapshotCfg.Workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if workerErr := sm.runWorker(ctx, jobCh); workerErr != nil {
+				errOnce.Do(func() {
+					firstErr = workerErr
+
+					cancel()
+				})
+			}
+		}()
+	}
+
+	jobs := []tableJob{}
+
+	for schemaName, schemaTables := range tables {
+		for _, table := range schemaTables {
+			if !sm.skipTables[fmt.Sprintf(TABLE_KEY_FORMAT, schemaName, table)] {
+				jobs = append(jobs, tableJob{schema: schemaName, table: table})
+			}
+		}
+	}
+
+	pipeline := metrics.Pipeline(sm.serverId)
+
+	metrics.SnapshotTables.WithLabelValues(pipeline).Set(float64(len(jobs)))
+	metrics.SnapshotTablesDone.WithLabelValues(pipeline).Set(0)
+
+sendJobs:
+	for _, job := range jobs {
+		select {
+		case jobCh <- job:
+		case <-ctx.Done():
+			break sendJobs
+		}
+	}
+
+	close(jobCh)
+	wg.Wait()
+
+	if firstErr != nil {
+		return pos, firstErr
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return pos, ctxErr
+	}
+
+	logger.WithContext(ctx).Info(
+		"[SnapshotManager.Run]snapshot done",
+		zap.Uint32("server id", sm.serverId),
+		zap.Duration("duration", time.Since(start)),
+		zap.Stringer("position", pos),
+	)
+
+	return pos, nil
+}
+
+// runWorker - snapshots tables from jobCh one at a time over its own connection
+func (sm *snapshotManager) runWorker(ctx context.Context, jobCh <-chan tableJob) error {
+	conn, err := sm.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	for job := range jobCh {
+		if tableErr := sm.snapshotTable(ctx, conn, job); tableErr != nil {
+			return tableErr
+		}
+
+		metrics.SnapshotTablesDone.WithLabelValues(metrics.Pipeline(sm.serverId)).Inc()
+	}
+
+	return nil
+}
+
+// snapshotTable - reads a table in chunks and hands each row to the event handler
+func (sm *snapshotManager) snapshotTable(ctx context.Context, conn *client.Conn, job tableJob) error {
+	logger.WithContext(ctx).Info(
+		"[SnapshotManager.snapshotTable]snapshotting table",
+		zap.Uint32("server id", sm.serverId),
+		zap.String("table", fmt.Sprintf(TABLE_FORMAT, job.schema, job.table)),
+	)
+
+	tableInfo, err := sm.canal.GetTable(job.schema, job.table)
+	if err != nil {
+		logger.WithContext(ctx).Error(
+			"[SnapshotManager.snapshotTable]fail to get table info",
+			zap.String("schema", job.schema),
+			zap.String("table", job.table),
+			zap.Error(err),
+		)
+
+		return ErrTable.New(fmt.Sprintf("[SnapshotManager.snapshotTable]%s", err.Error()))
+	}
+
+	// without a key to page by, chunks could skip or repeat rows
+	if len(tableInfo.PKColumns) == 0 {
+		return sm.streamTable(ctx, conn, tableInfo)
+	}
+
+	var (
+		lastPk []interface{}
+		total  int
+	)
+
+	for {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		query, args := sm.chunkQuery(tableInfo, lastPk)
+
+		res, queryErr := conn.Execute(query, args...)
+		if queryErr != nil {
+			logger.WithContext(ctx).Error(
+				"[SnapshotManager.snapshotTable]fail to query chunk",
+				zap.String("raw sql", query),
+				zap.Error(queryErr),
+			)
+
+			return ErrQuery.New(fmt.Sprintf("[SnapshotManager.snapshotTable]%s", queryErr.Error()))
+		}
+
+		rowCount := res.Resultset.RowNumber()
+
+		for rowNum := 0; rowNum < rowCount; rowNum++ {
+			row := parseRow(res.Resultset, rowNum)
+
+			if eventErr := sm.eventHandler.OnRow(&canal.RowsEvent{
+				Table:  tableInfo,
+				Action: canal.InsertAction,
+				Rows:   [][]interface{}{row},
+			}); eventErr != nil {
+				return ErrEvent.Wrap(eventErr)
+			}
+
+			if rowNum == rowCount-1 {
+				lastPk = make([]interface{}, 0, len(tableInfo.PKColumns))
+				for _, idx := range tableInfo.PKColumns {
+					lastPk = append(lastPk, row[idx])
+				}
+			}
+		}
+
+		res.Close()
+
+		metrics.SnapshotRowsTotal.WithLabelValues(metrics.Pipeline(sm.serverId), job.schema, job.table).Add(float64(rowCount))
+
+		total += rowCount
+
+		if rowCount < sm.snapshotCfg.ChunkSize {
+			break
+		}
+
+		if waitErr := sm.throttle.Wait(ctx, rowCount); waitErr != nil {
+			return waitErr
+		}
+	}
+
+	logger.WithContext(ctx).Info(
+		"[SnapshotManager.snapshotTable]snapshotted table",
+		zap.Uint32("server id", sm.serverId),
+		zap.String("table", fmt.Sprintf(TABLE_FORMAT, job.schema, job.table)),
+		zap.Int("rows", total),
+	)
+
+	return nil
+}
+
+// streamTable - reads a table without a primary key in a single pass, handing each row to the
+// event handler as it arrives
+func (sm *snapshotManager) streamTable(ctx context.Context, conn *client.Conn, tableInfo *schema.Table) error {
+	query, _ := sm.chunkQuery(tableInfo, nil)
+
+	var (
+		res   mysql.Result
+		total int
+	)
+
+	streamErr := conn.ExecuteSelectStreaming(query, &res, func(values []mysql.FieldValue) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		row := make([]interface{}, len(values))
+		for col := range values {
+			row[col] = fieldValue(values[col])
+		}
+
+		if eventErr := sm.eventHandler.OnRow(&canal.RowsEvent{
+			Table:  tableInfo,
+			Action: canal.InsertAction,
+			Rows:   [][]interface{}{row},
+		}); eventErr != nil {
+			return ErrEvent.Wrap(eventErr)
+		}
+
+		total++
+
+		if total%sm.snapshotCfg.ChunkSize != 0 {
+			return nil
+		}
+
+		metrics.SnapshotRowsTotal.WithLabelValues(metrics.Pipeline(sm.serverId), tableInfo.Schema, tableInfo.Name).
+			Add(float64(sm.snapshotCfg.ChunkSize))
+
+		return sm.throttle.Wait(ctx, sm.snapshotCfg.ChunkSize)
+	}, nil)
+
+	metrics.SnapshotRowsTotal.WithLabelValues(metrics.Pipeline(sm.serverId), tableInfo.Schema, tableInfo.Name).
+		Add(float64(total % sm.snapshotCfg.ChunkSize))
+
+	if streamErr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		logger.WithContext(ctx).Error(
+			"[SnapshotManager.streamTable]fail to read table",
+			zap.String("raw sql", query),
+			zap.Error(streamErr),
+		)
+
+		return ErrQuery.New(fmt.Sprintf("[SnapshotManager.streamTable]%s", streamErr.Error()))
+	}
+
+	logger.WithContext(ctx).Info(
+		"[SnapshotManager.streamTable]snapshotted table",
+		zap.Uint32("server id", sm.serverId),
+		zap.String("table", fmt.Sprintf(TABLE_FORMAT, tableInfo.Schema, tableInfo.Name)),
+		zap.Int("rows", total),
+	)
+
+	return nil
+}
+
+// chunkQuery - builds the query for the next chunk of a table
+//
+// Tables with a primary key are paged by key, tables without one are read whole.
+// The dump WHERE filter and the table's own filter, if any, are applied to every chunk.
+func (sm *snapshotManager) chunkQuery(
+	tableInfo *schema.Table,
+	lastPk []interface{},
+) (query string, args []interface{}) {
+	columns := make([]string, 0, len(tableInfo.Columns))
+	for _, column := range tableInfo.Columns {
+		columns = append(columns, fmt.Sprintf(COLUMN_FORMAT, column.Name))
+	}
+
+	var (
+		sb         strings.Builder
+		conditions []string
+	)
+
+	sb.WriteString(fmt.Sprintf(
+		SELECT_FORMAT, strings.Join(columns, ","), fmt.Sprintf(TABLE_FORMAT, tableInfo.Schema, tableInfo.Name),
+	))
+
+	if sm.where != "" {
+		conditions = append(conditions, fmt.Sprintf(CONDITION_FORMAT, sm.where))
+	}
+
+	if where, ok := sm.tableWhere[fmt.Sprintf(TABLE_KEY_FORMAT, tableInfo.Schema, tableInfo.Name)]; ok {
+		conditions = append(conditions, fmt.Sprintf(CONDITION_FORMAT, where))
+	}
+
+	if len(tableInfo.PKColumns) == 0 {
+		if len(conditions) > 0 {
+			sb.WriteString(fmt.Sprintf(WHERE_FORMAT, strings.Join(conditions, CONDITION_SEPARATOR)))
+		}
+
+		return sb.String(), nil
+	}
+
+	pkColumns := make([]string, 0, len(tableInfo.PKColumns))
+	for _, idx := range tableInfo.PKColumns {
+		pkColumns = append(pkColumns, columns[idx])
+	}
+
+	pkList := strings.Join(pkColumns, ",")
+
+	if lastPk != nil {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(lastPk)), ",")
+		conditions = append(conditions, fmt.Sprintf(PK_AFTER_FORMAT, pkList, placeholders))
+	}
+
+	if len(conditions) > 0 {
+		sb.WriteString(fmt.Sprintf(WHERE_FORMAT, strings.Join(conditions, CONDITION_SEPARATOR)))
+	}
+
+	sb.WriteString(fmt.Sprintf(ORDER_BY_FORMAT, pkList))
+	sb.WriteString(fmt.Sprintf(LIMIT_FORMAT, sm.snapshotCfg.ChunkSize))
+
+	return sb.String(), lastPk
+}
+
+// waitReplica - waits until the replica has applied the source up to pos
+//
+// Rows read from the replica are then at least as new as pos, so streaming from pos
+// afterwards does not miss any change
+func (sm *snapshotManager) waitReplica(ctx context.Context, pos mysql.Position) error {
+	conn, err := sm.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	res, err := conn.Execute(REPLICA_WAIT_SQL, pos.Name, pos.Pos, REPLICA_WAIT_TIMEOUT)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SnapshotManager.waitReplica]fail to wait for replica", zap.Error(err))
+
+		return ErrReplica.New(fmt.Sprintf("[SnapshotManager.waitReplica]%s", err.Error()))
+	}
+
+	defer res.Close()
+
+	if isNull, _ := res.IsNull(0, 0); isNull {
+		return ErrReplica.New("[SnapshotManager.waitReplica]replica is not replicating from source")
+	}
+
+	if waited, _ := res.GetInt(0, 0); waited == REPLICA_WAIT_TIMED_OUT {
+		return ErrReplica.New(fmt.Sprintf("[SnapshotManager.waitReplica]replica did not reach %s in time", pos))
+	}
+
+	return nil
+}
+
+// monitorLag - polls replica lag into the throttle until ctx is done
+func (sm *snapshotManager) monitorLag(ctx context.Context) {
+	interval := DEFAULT_LAG_CHECK_INTERVAL
+	if sm.snapshotCfg.LagCheckInterval > 0 {
+		interval = time.Duration(sm.snapshotCfg.LagCheckInterval) * time.Second
+	}
+
+	conn, err := sm.connect(ctx)
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lag, lagErr := replicaLag(conn)
+		if lagErr != nil {
+			logger.WithContext(ctx).Error("[SnapshotManager.monitorLag]fail to get replica lag", zap.Error(lagErr))
+		} else {
+			if sm.throttle.maxLag > 0 && lag > sm.throttle.maxLag {
+				logger.WithContext(ctx).Info(
+					"[SnapshotManager.monitorLag]replica lagging, backing off",
+					zap.Uint32("server id", sm.serverId),
+					zap.Uint32("lag", lag),
+				)
+			}
+
+			sm.throttle.setLag(lag)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (sm *snapshotManager) connect(ctx context.Context) (*client.Conn, error) {
+	addr := sm.dbCfg.Addr
+	if sm.snapshotCfg.ReplicaAddr != "" {
+		addr = sm.snapshotCfg.ReplicaAddr
+	}
+
+	conn, err := client.Connect(addr, sm.dbCfg.User, sm.dbPass, "")
+	if err != nil {
+		logger.WithContext(ctx).Error(
+			"[SnapshotManager.connect]fail to connect",
+			zap.String("addr", addr),
+			zap.Error(err),
+		)
+
+		return nil, ErrConnect.New(fmt.Sprintf("[SnapshotManager.connect]%s", err.Error()))
+	}
+
+	if sm.dbCfg.Charset != "" {
+		if charsetErr := conn.SetCharset(sm.dbCfg.Charset); charsetErr != nil {
+			conn.Close()
+
+			return nil, ErrConnect.New(fmt.Sprintf("[SnapshotManager.connect]%s", charsetErr.Error()))
+		}
+	}
+
+	return conn, nil
+}
+
+// replicaLag - returns the replica's seconds behind source
+func replicaLag(conn *client.Conn) (uint32, error) {
+	var err error
+
+	for _, statusSQL := range REPLICA_STATUS_SQLS {
+		res, execErr := conn.Execute(statusSQL)
+		if execErr != nil {
+			err = execErr
+
+			continue
+		}
+
+		lag, lagErr := parseLag(res)
+		res.Close()
+
+		return lag, lagErr
+	}
+
+	return 0, ErrReplica.New(fmt.Sprintf("[SnapshotManager.replicaLag]%s", err.Error()))
+}
+
+// parseLag - returns the seconds behind source of a replica status result
+func parseLag(res *mysql.Result) (uint32, error) {
+	if res.Resultset.RowNumber() == 0 {
+		return REPLICA_STOPPED_LAG, nil
+	}
+
+	for _, column := range REPLICA_LAG_COLUMNS {
+		if isNull, nullErr := res.IsNullByName(0, column); nullErr != nil {
+			continue
+		} else if isNull {
+			return REPLICA_STOPPED_LAG, nil
+		}
+
+		lag, uintErr := res.GetUintByName(0, column)
+		if uintErr != nil {
+			return 0, ErrReplica.New(fmt.Sprintf("[SnapshotManager.parseLag]%s", uintErr.Error()))
+		}
+
+		return uint32(lag), nil
+	}
+
+	return 0, ErrReplica.New("[SnapshotManager.parseLag]replica lag column not found")
+}
+
+// parseRow - converts a result row into row event values
+func parseRow(res *mysql.Resultset, rowNum int) []interface{} {
+	row := make([]interface{}, res.ColumnNumber())
+
+	for col := range row {
+		row[col] = fieldValue(res.Values[rowNum][col])
+	}
+
+	return row
+}
+
+// fieldValue - converts a result value into a row event value, copying strings so that they
+// outlive the result
+func fieldValue(fv mysql.FieldValue) interface{} {
+	value := fv.Value()
+
+	if raw, ok := value.([]byte); ok {
+		value = string(raw)
+	}
+
+	return value
+}
@@ -0,0 +1,64 @@
+package snapshotmanager
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/twothicc/canal/tools/ratelimit"
+)
+
+// throttle - limits snapshot reads to a shared rows per second budget,
+// backing off while the replica lags behind the source
+type throttle struct {
+	limiter     *ratelimit.Limiter
+	rowInterval time.Duration
+	lag         uint32
+	maxLag      uint32
+}
+
+func newThrottle(rowsPerSecond int, maxLag uint32) *throttle {
+	var rowInterval time.Duration
+	if rowsPerSecond > 0 {
+		rowInterval = time.Second / time.Duration(rowsPerSecond)
+	}
+
+	return &throttle{
+		limiter:     ratelimit.NewLimiter(),
+		rowInterval: rowInterval,
+		maxLag:      maxLag,
+	}
+}
+
+// setLag - records the latest replica lag in seconds
+func (t *throttle) setLag(lag uint32) {
+	atomic.StoreUint32(&t.lag, lag)
+}
+
+// isLagging - indicates whether the replica lag exceeds the configured maximum
+func (t *throttle) isLagging() bool {
+	return t.maxLag > 0 && atomic.LoadUint32(&t.lag) > t.maxLag
+}
+
+// Wait - blocks until rows can be read without exceeding the row budget or replica lag
+//
+// While the replica is lagging, waits with exponential backoff. Once lag is above
+// half the maximum, the row budget is halved so that the replica can catch up.
+func (t *throttle) Wait(ctx context.Context, rows int) error {
+	for backoff := MIN_BACKOFF; t.isLagging(); {
+		if err := ratelimit.Sleep(ctx, backoff); err != nil {
+			return err
+		}
+
+		if backoff *= 2; backoff > MAX_BACKOFF {
+			backoff = MAX_BACKOFF
+		}
+	}
+
+	interval := t.rowInterval
+	if t.maxLag > 0 && atomic.LoadUint32(&t.lag) > t.maxLag/2 {
+		interval *= 2
+	}
+
+	return t.limiter.Wait(ctx, interval*time.Duration(rows))
+}
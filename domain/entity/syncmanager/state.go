@@ -0,0 +1,129 @@
+package syncmanager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/twothicc/canal/tools/errorcode"
+)
+
+// State - lifecycle state of a syncmanager
+type State string
+
+// Lifecycle states
+//
+// Created -> Snapshotting -> Streaming -> Paused/Stopping -> Stopped/Failed/Completed
+const (
+	STATE_CREATED      State = "Created"
+	STATE_SNAPSHOTTING State = "Snapshotting"
+	STATE_STREAMING    State = "Streaming"
+	STATE_PAUSED       State = "Paused"
+	STATE_STOPPING     State = "Stopping"
+	STATE_STOPPED      State = "Stopped"
+	STATE_FAILED       State = "Failed"
+	STATE_COMPLETED    State = "Completed"
+)
+
+// validTransitions - states reachable from each state
+//
+// Failed and Completed are final, closing such a syncmanager only releases its resources
+var validTransitions = map[State][]State{
+	STATE_CREATED:      {STATE_SNAPSHOTTING, STATE_STREAMING, STATE_STOPPING, STATE_FAILED},
+	STATE_SNAPSHOTTING: {STATE_STREAMING, STATE_STOPPING, STATE_FAILED, STATE_COMPLETED},
+	STATE_STREAMING:    {STATE_STOPPING, STATE_FAILED, STATE_COMPLETED},
+	STATE_STOPPING:     {STATE_PAUSED, STATE_STOPPED, STATE_FAILED, STATE_COMPLETED},
+}
+
+// ErrorInfo - last error of a syncmanager along with its errortype details
+type ErrorInfo struct {
+	Time time.Time
+	Pkg  string
+	Msg  string
+	Code int32
+}
+
+// stateMachine - race-free lifecycle state of a syncmanager
+type stateMachine struct {
+	transitions map[State]time.Time
+	lastError   *ErrorInfo
+	state       State
+	reason      string
+	mu          sync.RWMutex
+}
+
+func newStateMachine() *stateMachine {
+	return &stateMachine{
+		state:       STATE_CREATED,
+		reason:      "created",
+		transitions: map[State]time.Time{STATE_CREATED: time.Now()},
+	}
+}
+
+// transition - moves to state to for reason, recording err as the last error if not nil
+//
+// Returns false, leaving the state unchanged, if to cannot be reached from the current state
+func (m *stateMachine) transition(to State, reason string, err error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !isValidTransition(m.state, to) {
+		return false
+	}
+
+	now := time.Now()
+
+	m.state = to
+	m.reason = reason
+	m.transitions[to] = now
+
+	if err != nil {
+		detail := errorcode.Parse(err)
+
+		m.lastError = &ErrorInfo{
+			Time: now,
+			Code: detail.Code,
+			Pkg:  detail.Pkg,
+			Msg:  detail.Msg,
+		}
+	}
+
+	return true
+}
+
+// current - returns the current state
+func (m *stateMachine) current() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state
+}
+
+// fill - copies the state machine into status
+func (m *stateMachine) fill(status *Status) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status.State = m.state
+	status.Reason = m.reason
+	status.IsRunning = m.state == STATE_SNAPSHOTTING || m.state == STATE_STREAMING
+
+	status.Transitions = make(map[State]time.Time, len(m.transitions))
+	for state, at := range m.transitions {
+		status.Transitions[state] = at
+	}
+
+	if m.lastError != nil {
+		lastError := *m.lastError
+		status.LastError = &lastError
+	}
+}
+
+func isValidTransition(from, to State) bool {
+	for _, state := range validTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"regexp"
@@ -13,46 +15,113 @@ import (
 	"github.com/siddontang/go-log/log"
 	"github.com/twothicc/canal/config"
 	"github.com/twothicc/canal/domain/entity/syncmanager/savemanager"
+	"github.com/twothicc/canal/domain/entity/syncmanager/snapshotmanager"
 	"github.com/twothicc/canal/handlers/events/sync"
-	"github.com/twothicc/canal/tools/idgenerator"
+	"github.com/twothicc/canal/tools/metrics"
+	"github.com/twothicc/canal/tools/secret"
 	"github.com/twothicc/common-go/logger"
 	"go.uber.org/zap"
 )
 
+// Status - state of a syncmanager
+//
+// Transitions holds when each state was last entered. Restarts, LastFailure,
+// LastFailureTime and Role are filled in by the supervising SyncController
 type Status struct {
-	Sources   []config.SourceConfig
-	ServerId  uint32
-	IsRunning bool
+	LastFailureTime time.Time
+	Transitions     map[State]time.Time
+	LastError       *ErrorInfo
+	Replication     *Replication
+	State           State
+	Reason          string
+	LastFailure     string
+	Role            string
+	Sources         []config.SourceConfig
+	ServerId        uint32
+	Restarts        uint32
+	IsRunning       bool
 }
 
 // SyncManager - manages data sync
 type SyncManager interface {
 	Run(isLegacySync bool) error
-	Close()
+	Close() *DrainResult
+	Pause() *DrainResult
+	IsClosed() bool
 	GetId() uint32
+	GetConfig() config.Config
+	Tables() map[string][]string
+	SetBackfill(tables map[string][]string)
+	SetStartPosition(pos mysql.Position) error
+	Checkpoint() mysql.Position
 	Status() *Status
+	Events(before uint64, limit int) *sync.EventPage
 }
 
 type syncManager struct {
 	ctx               context.Context
 	eventHandler      sync.SyncEventHandler
 	saveInfo          savemanager.ISaveInfo
+	lastCheckpoint    atomic.Value
+	failure           atomic.Value
 	closeEventHandler sync.CloseEventHandler
 	cancel            context.CancelFunc
 	cfg               *config.Config
 	canal             *canal.Canal
-	syncCh            chan mysql.Position
-	isRunning         bool
+	dbPass            string
+	syncCh            chan sync.Checkpoint
+	stopLoop          chan struct{}
+	loopDone          chan struct{}
+	tables            map[string][]string
+	backfill          map[string][]string
+	state             *stateMachine
+	source            sourceSampler
+	closed            int32
+	canalClosed       int32
+	isStarted         int32
 }
 
-// NewSyncManager - creates a SyncManager
+// NewSyncManager - creates a SyncManager with server id id, which must never have been used
+// by another pipeline, see SyncController.NewSyncManager
+//
+// The SyncManager keeps its own copy of cfg, which is not modified
 func NewSyncManager(
 	ctx context.Context,
+	id uint32,
 	cfg *config.Config,
 ) (SyncManager, error) {
-	cfg.ServerId = idgenerator.GetId()
+	if id == 0 {
+		return nil, ErrParam.New("[SyncManager.NewSyncManager]missing server id")
+	}
+
+	cfg = cfg.Clone()
+	cfg.ServerId = id
 
-	canalCfg := parseCanalCfg(ctx, cfg)
+	return newSyncManager(ctx, cfg)
+}
+
+// RestoreSyncManager - recreates a persisted SyncManager, keeping its server id and checkpoint
+func RestoreSyncManager(
+	ctx context.Context,
+	cfg *config.Config,
+) (SyncManager, error) {
+	if cfg.ServerId == 0 {
+		return nil, ErrParam.New("[SyncManager.RestoreSyncManager]missing server id")
+	}
+
+	return newSyncManager(ctx, cfg.Clone())
+}
+
+func newSyncManager(
+	ctx context.Context,
+	cfg *config.Config,
+) (SyncManager, error) {
+	canalCfg, err := parseCanalCfg(ctx, cfg)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncManager.Run]fail to parse canal config", zap.Error(err))
+
+		return nil, err
+	}
 
 	newCanal, err := canal.NewCanal(canalCfg)
 	if err != nil {
@@ -61,7 +130,8 @@ func NewSyncManager(
 		return nil, ErrConfig.New(fmt.Sprintf("[SyncManager.Run]%s", err.Error()))
 	}
 
-	if err = parseSource(ctx, cfg, newCanal); err != nil {
+	tables, err := parseSource(ctx, cfg, newCanal)
+	if err != nil {
 		logger.WithContext(ctx).Error(
 			"[SyncManager.Run]fail to parse source",
 			zap.Uint32("server id", cfg.ServerId),
@@ -71,33 +141,46 @@ func NewSyncManager(
 		return nil, err
 	}
 
-	if binErr := newCanal.CheckBinlogRowImage("FULL"); err != nil {
+	if quotaErr := checkTableQuota(cfg.QuotaConfig, tables); quotaErr != nil {
+		logger.WithContext(ctx).Error(
+			"[SyncManager.Run]too many tables",
+			zap.Uint32("server id", cfg.ServerId),
+			zap.Error(quotaErr),
+		)
+		newCanal.Close()
+
+		return nil, quotaErr
+	}
+
+	if binErr := newCanal.CheckBinlogRowImage(FULL_ROW_IMAGE); binErr != nil {
 		logger.WithContext(ctx).Error(
 			"[SyncManager.Run]invalid binlog row image",
 			zap.Uint32("server id", cfg.ServerId),
 			zap.Error(binErr),
 		)
+		newCanal.Close()
 
-		return nil, ErrBinlog.New(fmt.Sprintf("[SyncManager.Run]%s", err.Error()))
+		return nil, ErrBinlog.New(fmt.Sprintf("[SyncManager.Run]%s", binErr.Error()))
 	}
 
-	syncCh := make(chan mysql.Position, SYNC_CHANNEL_SIZE)
+	syncCh := make(chan sync.Checkpoint, SYNC_CHANNEL_SIZE)
 
-	saveInfo, saveErr := savemanager.LoadSaveInfo(ctx, cfg.ServerId)
+	saveInfo, saveErr := savemanager.LoadSaveInfo(ctx, cfg.StoreConfig, cfg.ServerId)
 	if saveErr != nil {
 		logger.WithContext(ctx).Error(
 			"[SyncManager.Run]fail to load save info",
 			zap.Uint32("server id", cfg.ServerId),
-			zap.Error(err),
+			zap.Error(saveErr),
 		)
+		newCanal.Close()
 
-		return nil, ErrSave.New(fmt.Sprintf("[SyncManager.Run]%s", err.Error()))
+		return nil, ErrSave.New(fmt.Sprintf("[SyncManager.Run]%s", saveErr.Error()))
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
 
 	eventHandler, closeEventHandler, eventHandlerErr := sync.NewSyncEventHandler(
-		ctx, cfg.KafkaConfig, cfg.ServerId, syncCh)
+		ctx, cfg.KafkaConfig, cfg.QuotaConfig, cfg.EventLogConfig, cfg.ServerId, syncCh)
 	if eventHandlerErr != nil {
 		logger.WithContext(ctx).Error(fmt.Sprintf("[SyncManager.Run]%s", eventHandlerErr.Error()))
 		cancel()
@@ -108,24 +191,33 @@ func NewSyncManager(
 	newCanal.SetEventHandler(eventHandler)
 
 	return &syncManager{
-		isRunning:         false,
+		state:             newStateMachine(),
 		ctx:               ctx,
 		cancel:            cancel,
+		eventHandler:      eventHandler,
 		closeEventHandler: closeEventHandler,
 		cfg:               cfg,
 		canal:             newCanal,
+		dbPass:            canalCfg.Password,
 		saveInfo:          saveInfo,
 		syncCh:            syncCh,
+		stopLoop:          make(chan struct{}),
+		loopDone:          make(chan struct{}),
+		tables:            tables,
 	}, nil
 }
 
-// Status - returns bool indicating whether syncmanager is running
+// Status - returns the lifecycle state and replication progress of the syncmanager
 func (sm *syncManager) Status() *Status {
-	return &Status{
-		ServerId:  sm.cfg.ServerId,
-		IsRunning: sm.isRunning,
-		Sources:   sm.cfg.Sources,
+	status := &Status{
+		ServerId:    sm.cfg.ServerId,
+		Sources:     sm.cfg.Sources,
+		Replication: sm.replication(),
 	}
+
+	sm.state.fill(status)
+
+	return status
 }
 
 // GetId - returns the unique server id of this syncmanager
@@ -133,51 +225,221 @@ func (sm *syncManager) GetId() uint32 {
 	return sm.cfg.ServerId
 }
 
+// GetConfig - returns a copy of the config this syncmanager was created with
+func (sm *syncManager) GetConfig() config.Config {
+	return *sm.cfg
+}
+
+// Tables - returns the resolved tables of each schema being synced
+func (sm *syncManager) Tables() map[string][]string {
+	tables := make(map[string][]string, len(sm.tables))
+	for schema, schemaTables := range sm.tables {
+		tables[schema] = append([]string{}, schemaTables...)
+	}
+
+	return tables
+}
+
+// SetBackfill - sets tables to snapshot before streaming from the checkpoint on the next non-legacy Run
+//
+// Used to fill in existing records of tables added to a running pipeline
+func (sm *syncManager) SetBackfill(tables map[string][]string) {
+	sm.backfill = tables
+}
+
+// SetStartPosition - sets the binlog position a non-legacy Run streams from, replacing the checkpoint
+//
+// Must be set before Run
+func (sm *syncManager) SetStartPosition(pos mysql.Position) error {
+	if atomic.LoadInt32(&sm.isStarted) == 1 {
+		return ErrState.New("[SyncManager.SetStartPosition]cannot set start position after run")
+	}
+
+	if err := sm.saveInfo.Save(sm.ctx, pos); err != nil {
+		return ErrSave.New(fmt.Sprintf("[SyncManager.SetStartPosition]%s", err.Error()))
+	}
+
+	return nil
+}
+
+// Events - returns a page of the latest messages and errors of this syncmanager, newest first
+//
+// The log starts empty whenever the pipeline is rebuilt, e.g. when restarted after a failure
+func (sm *syncManager) Events(before uint64, limit int) *sync.EventPage {
+	return sm.eventHandler.Events().Page(before, limit)
+}
+
+// Checkpoint - returns the last saved binlog position, empty if nothing was saved yet
+func (sm *syncManager) Checkpoint() mysql.Position {
+	return sm.saveInfo.Position()
+}
+
 // Run - starts data sync
 //
 // isLegacySync indicates whether the sync will include existing records
 func (sm *syncManager) Run(isLegacySync bool) error {
 	logger.WithContext(sm.ctx).Info("[SyncManager.Run]running data sync", zap.Uint32("server id", sm.cfg.ServerId))
 
-	sm.isRunning = true
+	initialState := STATE_STREAMING
+	if isLegacySync || len(sm.backfill) > 0 {
+		initialState = STATE_SNAPSHOTTING
+	}
+
+	if !sm.state.transition(initialState, "run requested", nil) {
+		return ErrState.New(fmt.Sprintf("[SyncManager.Run]cannot run from state %s", sm.state.current()))
+	}
+
+	atomic.StoreInt32(&sm.isStarted, 1)
 
 	go sm.syncLoop(mysql.Position{
 		Name: sm.saveInfo.Position().Name,
 		Pos:  sm.saveInfo.Position().Pos,
 	})
 
-	return func() error {
-		var err error
+	go sm.awaitSnapshot()
 
-		if isLegacySync {
-			if runErr := sm.canal.Run(); runErr != nil {
-				err = runErr
+	go sm.sampleSourceLoop()
 
-				sm.cancel()
-			}
+	err := sm.run(isLegacySync)
+
+	// canal stops without error when closed by fail
+	if failure, ok := sm.failure.Load().(error); ok && err == nil {
+		err = failure
+	}
+
+	// Close sets the final state of syncmanagers that were closed
+	if !sm.IsClosed() {
+		if err != nil {
+			sm.state.transition(STATE_FAILED, "run failed", err)
 		} else {
-			pos := sm.saveInfo.Position()
+			sm.state.transition(STATE_COMPLETED, "run ended", nil)
+		}
+	}
+
+	return err
+}
+
+func (sm *syncManager) run(isLegacySync bool) error {
+	var err error
+
+	switch {
+	case isLegacySync && sm.cfg.SnapshotConfig.Workers > 0:
+		pos, snapshotErr := snapshotmanager.NewSnapshotManager(
+			sm.cfg,
+			sm.dbPass,
+			sm.canal,
+			sm.eventHandler,
+		).Run(sm.ctx, sm.tables)
+		if snapshotErr != nil {
+			err = snapshotErr
+
+			sm.cancel()
+
+			break
+		}
 
-			if runErr := sm.canal.RunFrom(pos); runErr != nil {
-				err = runErr
+		sm.syncCh <- sync.Checkpoint{
+			Pos: pos,
+			Seq: sm.eventHandler.LastSeq(),
+		}
+
+		if runErr := sm.canal.RunFrom(pos); runErr != nil {
+			err = ErrRun.New(fmt.Sprintf("[SyncManager.run]%s", runErr.Error()))
+
+			sm.cancel()
+		}
+	case isLegacySync:
+		if runErr := sm.canal.Run(); runErr != nil {
+			err = ErrRun.New(fmt.Sprintf("[SyncManager.run]%s", runErr.Error()))
+
+			sm.cancel()
+		}
+	default:
+		pos := sm.saveInfo.Position()
+
+		if len(sm.backfill) > 0 {
+			if _, snapshotErr := snapshotmanager.NewSnapshotManager(
+				sm.cfg,
+				sm.dbPass,
+				sm.canal,
+				sm.eventHandler,
+			).Run(sm.ctx, sm.backfill); snapshotErr != nil {
+				err = snapshotErr
 
 				sm.cancel()
+
+				break
 			}
 		}
 
-		sm.isRunning = false
+		if runErr := sm.canal.RunFrom(pos); runErr != nil {
+			err = ErrRun.New(fmt.Sprintf("[SyncManager.run]%s", runErr.Error()))
 
-		return err
-	}()
+			sm.cancel()
+		}
+	}
+
+	return err
+}
+
+// awaitSnapshot - moves from Snapshotting to Streaming once canal is done dumping
+//
+// canal signals dump done even when the dump is skipped
+func (sm *syncManager) awaitSnapshot() {
+	select {
+	case <-sm.canal.WaitDumpDone():
+		if sm.state.current() == STATE_SNAPSHOTTING {
+			sm.state.transition(STATE_STREAMING, "snapshot done", nil)
+		}
+	case <-sm.ctx.Done():
+	}
+}
+
+// Close - drains and closes underlying canal and producer, stopping data sync
+//
+// Only the first call to Close or Pause has any effect, later calls return nil
+func (sm *syncManager) Close() *DrainResult {
+	return sm.close(STATE_STOPPED, "stop requested")
+}
+
+// Pause - closes like Close, leaving the syncmanager Paused rather than Stopped
+func (sm *syncManager) Pause() *DrainResult {
+	return sm.close(STATE_PAUSED, "pause requested")
 }
 
-// Close - closes underlying canal, stopping data sync immediately
-func (sm *syncManager) Close() {
+// close - drains and releases the syncmanager, leaving it in finalState
+//
+// Binlog reading is stopped first, then in-flight messages are given until the drain
+// timeout to be acknowledged, so that the final saved checkpoint only covers delivered
+// messages. The producer and canal are released last.
+func (sm *syncManager) close(finalState State, reason string) *DrainResult {
+	if !atomic.CompareAndSwapInt32(&sm.closed, 0, 1) {
+		return nil
+	}
+
 	logger.WithContext(sm.ctx).Info("[SyncManager.Close]closing", zap.Uint32("server id", sm.cfg.ServerId))
 
-	sm.isRunning = false
+	start := time.Now()
+	deadline := start.Add(drainTimeout(sm.cfg.DrainConfig))
+
+	sm.state.transition(STATE_STOPPING, reason, nil)
 
+	// stop reading binlog and snapshots
 	sm.cancel()
+	sm.closeCanal()
+
+	isDrained := sm.waitInFlight(deadline)
+
+	// save the final acknowledged checkpoint
+	close(sm.stopLoop)
+
+	if atomic.LoadInt32(&sm.isStarted) == 1 {
+		select {
+		case <-sm.loopDone:
+		case <-time.After(time.Until(deadline)):
+			isDrained = false
+		}
+	}
 
 	if saveErr := sm.saveInfo.Close(sm.ctx); saveErr != nil {
 		logger.WithContext(sm.ctx).Error(
@@ -185,6 +447,16 @@ func (sm *syncManager) Close() {
 			zap.Error(saveErr),
 			zap.Uint32("server id", sm.cfg.ServerId),
 		)
+
+		isDrained = false
+	}
+
+	result := &DrainResult{
+		Checkpoint:  sm.saveInfo.Position(),
+		InFlight:    sm.eventHandler.InFlight(),
+		Failed:      sm.eventHandler.Failed(),
+		Duration:    time.Since(start),
+		IsCompleted: isDrained && sm.eventHandler.Failed() == 0,
 	}
 
 	if eventErr := sm.closeEventHandler(); eventErr != nil {
@@ -195,113 +467,281 @@ func (sm *syncManager) Close() {
 		)
 	}
 
-	sm.canal.Close()
+	sm.state.transition(finalState, reason, nil)
+
+	logger.WithContext(sm.ctx).Info(
+		"[SyncManager.Close]closed",
+		zap.Uint32("server id", sm.cfg.ServerId),
+		zap.Duration("drain duration", result.Duration),
+		zap.Bool("is drained", result.IsCompleted),
+		zap.Int("in flight", result.InFlight),
+		zap.Int("failed", result.Failed),
+		zap.Stringer("checkpoint", result.Checkpoint),
+	)
+
+	return result
+}
+
+// closeCanal - closes canal once, as closing it again panics
+func (sm *syncManager) closeCanal() {
+	if atomic.CompareAndSwapInt32(&sm.canalClosed, 0, 1) {
+		sm.canal.Close()
+	}
+}
+
+// fail - stops reading binlog and snapshots, so that Run returns err and the supervisor
+// restarts the syncmanager from its last saved checkpoint
+func (sm *syncManager) fail(err error) {
+	sm.failure.CompareAndSwap(nil, err)
+
+	sm.cancel()
+	sm.closeCanal()
+}
+
+// waitInFlight - waits until every produced message is acknowledged or deadline passes
+//
+// Returns false if messages were still in flight at the deadline
+func (sm *syncManager) waitInFlight(deadline time.Time) bool {
+	ticker := time.NewTicker(DRAIN_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for sm.eventHandler.InFlight() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		<-ticker.C
+	}
+
+	return true
+}
+
+// IsClosed - indicates whether Close was called, after which the syncmanager cannot run again
+func (sm *syncManager) IsClosed() bool {
+	return atomic.LoadInt32(&sm.closed) == 1
 }
 
 // syncLoop - saves binlog position to file in intervals
+//
+// A position is only saved once every message produced before it is acknowledged.
+// On stop, the last acknowledged position is saved before returning. Once kafka rejects a
+// message, the position before it is saved and the syncmanager fails.
 func (sm *syncManager) syncLoop(initPos mysql.Position) {
+	defer close(sm.loopDone)
+
 	ticker := time.NewTicker(SAVE_INTERVAL)
 	defer ticker.Stop()
 
 	currPos := initPos
+	pending := []sync.Checkpoint{}
 
 	for {
-		isSavePos := false
+		isStopping := false
 
 		select {
-		case pos := <-sm.syncCh:
-			currPos = pos
+		case checkpoint := <-sm.syncCh:
+			pending = append(pending, checkpoint)
+
+			continue
 		case <-ticker.C:
-			isSavePos = true
-		case <-sm.ctx.Done():
+		case <-sm.stopLoop:
+			isStopping = true
+
+			pending = append(pending, sm.drainSyncCh()...)
+		}
+
+		currPos, pending = ackedPosition(currPos, pending, sm.eventHandler.AckedSeq())
+
+		if err := sm.saveCheckpoint(currPos); err != nil {
+			logger.WithContext(sm.ctx).Error("[SyncManager.syncLoop]fail to save", zap.Error(err))
+			sm.fail(err)
+
 			return
 		}
 
-		if isSavePos {
-			if err := sm.saveInfo.Save(sm.ctx, currPos); err != nil {
-				logger.WithContext(sm.ctx).Error("[SyncManager.syncLoop]fail to save", zap.Error(err))
-				sm.cancel()
+		if isStopping {
+			return
+		}
 
-				return
-			}
+		if produceErr := sm.eventHandler.Err(); produceErr != nil {
+			logger.WithContext(sm.ctx).Error(
+				"[SyncManager.syncLoop]kafka rejected a message, failing from the last acknowledged checkpoint",
+				zap.Uint32("server id", sm.cfg.ServerId),
+				zap.Stringer("checkpoint", currPos),
+				zap.Error(produceErr),
+			)
+
+			sm.fail(produceErr)
 
-			isSavePos = false
+			return
+		}
+	}
+}
+
+// saveCheckpoint - saves pos, recording when it was saved along with the save latency and failures
+func (sm *syncManager) saveCheckpoint(pos mysql.Position) error {
+	pipeline := metrics.Pipeline(sm.cfg.ServerId)
+	start := time.Now()
+
+	err := sm.saveInfo.Save(sm.ctx, pos)
+
+	metrics.CheckpointSaveLatency.WithLabelValues(pipeline).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		metrics.CheckpointFailuresTotal.WithLabelValues(pipeline).Inc()
+
+		return err
+	}
+
+	sm.lastCheckpoint.Store(time.Now())
+
+	return nil
+}
+
+// drainSyncCh - returns checkpoints left in the sync channel without blocking
+func (sm *syncManager) drainSyncCh() []sync.Checkpoint {
+	checkpoints := []sync.Checkpoint{}
+
+	for {
+		select {
+		case checkpoint := <-sm.syncCh:
+			checkpoints = append(checkpoints, checkpoint)
+		default:
+			return checkpoints
 		}
 	}
 }
 
+// ackedPosition - returns the latest pending position whose messages are all acknowledged,
+// along with the checkpoints still awaiting acknowledgement
+func ackedPosition(
+	currPos mysql.Position,
+	pending []sync.Checkpoint,
+	ackedSeq uint64,
+) (mysql.Position, []sync.Checkpoint) {
+	idx := 0
+
+	for ; idx < len(pending) && pending[idx].Seq <= ackedSeq; idx++ {
+		currPos = pending[idx].Pos
+	}
+
+	return currPos, pending[idx:]
+}
+
+// checkTableQuota - rejects pipelines syncing more tables than allowed, counting resolved wildcards
+func checkTableQuota(quotaCfg config.QuotaConfig, tables map[string][]string) error {
+	if quotaCfg.MaxTables == 0 {
+		return nil
+	}
+
+	if count := countTables(tables); count > int(quotaCfg.MaxTables) {
+		return ErrQuota.New(fmt.Sprintf(
+			"[SyncManager.checkTableQuota]%d tables exceed the quota of %d tables per pipeline",
+			count,
+			quotaCfg.MaxTables,
+		))
+	}
+
+	return nil
+}
+
 // parseSource - parses special characters in tables from config source into valid tables
-func parseSource(ctx context.Context, cfg *config.Config, c *canal.Canal) error {
+//
+// Returns the resolved tables of each schema, which are also added to canal's dump tables
+func parseSource(ctx context.Context, cfg *config.Config, c *canal.Canal) (map[string][]string, error) {
 	logger.WithContext(ctx).Info("[SyncManager.parseSource]parsing source", zap.Uint32("server id", cfg.ServerId))
 
 	if c == nil {
 		logger.WithContext(ctx).Error("[SyncManager.parseSource]canal not initialized")
 
-		return ErrNoCanal.New("[SyncManager.parseSource]canal not initialized")
+		return nil, ErrNoCanal.New("[SyncManager.parseSource]canal not initialized")
+	}
+
+	resolvedTables, err := resolveTables(ctx, c, cfg.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	for schema, tables := range resolvedTables {
+		c.AddDumpTables(schema, tables...)
 	}
 
-	wildCardTables := make(map[string][]string, len(cfg.Sources))
+	return resolvedTables, nil
+}
 
-	for _, source := range cfg.Sources {
+// executor - runs queries against the source database, e.g. canal or a client connection
+type executor interface {
+	Execute(cmd string, args ...interface{}) (*mysql.Result, error)
+}
+
+// resolveTables - resolves wildcard tables of sources into the matching tables of their schema
+func resolveTables(ctx context.Context, c executor, sources []config.SourceConfig) (map[string][]string, error) {
+	wildCardTables := make(map[string][]string, len(sources))
+	resolvedTables := make(map[string][]string, len(sources))
+
+	for _, source := range sources {
 		if !isValidTable(source.Tables) {
 			logger.WithContext(ctx).Error(
-				"[SyncManager.parseSource]invalid tables",
+				"[SyncManager.resolveTables]invalid tables",
 				zap.Strings("tables", source.Tables),
 			)
 
-			return ErrConfig.New("[SyncManager.parseSource]invalid tables")
+			return nil, ErrConfig.New("[SyncManager.resolveTables]invalid tables")
 		}
 
 		for _, table := range source.Tables {
 			// QuoteMeta escapes regex metacharacters
-			if regexp.QuoteMeta(table) != table {
-				key := sourceKey(source.Schema, table)
-
-				if _, ok := wildCardTables[key]; ok {
-					logger.WithContext(ctx).Error(
-						"[SyncManager.parseSource]duplicate wildcard table",
-						zap.String("source key", key),
-					)
-
-					return ErrConfig.New(fmt.Sprintf("[SyncManager.parseSource]duplicate wildcard table %s", key))
-				}
-
-				tableParam := table
-				if table == WILDCARD {
-					tableParam = ANY_TABLE
-				}
-
-				res, err := c.Execute(WILDCARD_TABLE_SQL, tableParam, source.Schema)
-				if err != nil {
-					logger.WithContext(ctx).Error(
-						"[SyncManager.parseSource]fail to query table info",
-						zap.String("raw sql", fmt.Sprintf(WILDCARD_TABLE_SQL, tableParam, source.Schema)),
-						zap.Error(err),
-					)
-
-					return ErrQuery.New(fmt.Sprintf("[SyncManager.parseSource]%s", err.Error()))
-				}
-
-				tables := []string{}
-
-				for rowNum := 0; rowNum < res.Resultset.RowNumber(); rowNum++ {
-					tableName, _ := res.GetString(rowNum, 0)
-					tables = append(tables, tableName)
-				}
-
-				c.AddDumpTables(source.Schema, tables...)
-
-				wildCardTables[key] = tables
-			} else {
-				c.AddDumpTables(source.Schema, table)
+			if regexp.QuoteMeta(table) == table {
+				resolvedTables[source.Schema] = append(resolvedTables[source.Schema], table)
+
+				continue
+			}
+
+			key := sourceKey(source.Schema, table)
+
+			if _, ok := wildCardTables[key]; ok {
+				logger.WithContext(ctx).Error(
+					"[SyncManager.resolveTables]duplicate wildcard table",
+					zap.String("source key", key),
+				)
+
+				return nil, ErrConfig.New(fmt.Sprintf("[SyncManager.resolveTables]duplicate wildcard table %s", key))
+			}
+
+			tableParam := table
+			if table == WILDCARD {
+				tableParam = ANY_TABLE
+			}
+
+			res, err := c.Execute(WILDCARD_TABLE_SQL, tableParam, source.Schema)
+			if err != nil {
+				logger.WithContext(ctx).Error(
+					"[SyncManager.resolveTables]fail to query table info",
+					zap.String("raw sql", WILDCARD_TABLE_SQL),
+					zap.String("table", tableParam),
+					zap.String("schema", source.Schema),
+					zap.Error(err),
+				)
+
+				return nil, ErrQuery.New(fmt.Sprintf("[SyncManager.resolveTables]%s", err.Error()))
 			}
+
+			tables := []string{}
+
+			for rowNum := 0; rowNum < res.Resultset.RowNumber(); rowNum++ {
+				tableName, _ := res.GetString(rowNum, 0)
+				tables = append(tables, tableName)
+			}
+
+			wildCardTables[key] = tables
+			resolvedTables[source.Schema] = append(resolvedTables[source.Schema], tables...)
 		}
 	}
 
-	return nil
+	return resolvedTables, nil
 }
 
-func parseCanalCfg(ctx context.Context, cfg *config.Config) *canal.Config {
+func parseCanalCfg(ctx context.Context, cfg *config.Config) (*canal.Config, error) {
 	logger.WithContext(ctx).Info(
 		"[SyncManager.parseCanalCfg]parsing canal configs",
 		zap.Uint32("server id", cfg.ServerId),
@@ -310,9 +750,16 @@ func parseCanalCfg(ctx context.Context, cfg *config.Config) *canal.Config {
 	canalCfg := canal.NewDefaultConfig()
 
 	dbCfg := cfg.DbConfig
+
+	// the password is only ever held resolved by canal and the syncmanager, never in cfg
+	password, err := secret.Resolve(ctx, dbCfg.Pass)
+	if err != nil {
+		return nil, ErrConfig.New(fmt.Sprintf("[SyncManager.parseCanalCfg]fail to resolve password: %s", err.Error()))
+	}
+
 	canalCfg.Addr = dbCfg.Addr
 	canalCfg.User = dbCfg.User
-	canalCfg.Password = dbCfg.Pass
+	canalCfg.Password = password
 	canalCfg.Charset = dbCfg.Charset
 
 	canalCfg.ServerID = cfg.ServerId
@@ -329,8 +776,9 @@ func parseCanalCfg(ctx context.Context, cfg *config.Config) *canal.Config {
 
 	canalCfg.Logger = canalLogger
 
-	dumpCfg := cfg.DumpConfig
-	canalCfg.Dump.ExecutionPath = dumpCfg.DumpExecPath
+	if err := parseDumpCfg(cfg, &canalCfg.Dump); err != nil {
+		return nil, err
+	}
 
 	for _, source := range cfg.Sources {
 		for _, table := range source.Tables {
@@ -341,7 +789,49 @@ func parseCanalCfg(ctx context.Context, cfg *config.Config) *canal.Config {
 		}
 	}
 
-	return canalCfg
+	return canalCfg, nil
+}
+
+// parseDumpCfg - maps the dump config onto canal's mysqldump config
+func parseDumpCfg(cfg *config.Config, canalDumpCfg *canal.DumpConfig) error {
+	dumpCfg := cfg.DumpConfig
+
+	canalDumpCfg.ExecutionPath = dumpCfg.DumpExecPath
+	canalDumpCfg.Where = dumpCfg.Where
+	canalDumpCfg.MaxAllowedPacketMB = dumpCfg.MaxAllowedPacketMB
+	canalDumpCfg.ExtraOptions = append(canalDumpCfg.ExtraOptions, dumpCfg.ExtraOptions...)
+
+	// mysqldump only supports a single WHERE for all tables
+	if len(dumpCfg.TableWhere) > 0 && cfg.SnapshotConfig.Workers <= 0 {
+		return ErrConfig.New("[SyncManager.parseDumpCfg]table_where requires snapshot workers")
+	}
+
+	for _, skipTable := range dumpCfg.SkipTables {
+		schema, table, ok := splitTableKey(skipTable)
+		if !ok {
+			return ErrConfig.New(fmt.Sprintf("[SyncManager.parseDumpCfg]invalid skip table %s", skipTable))
+		}
+
+		canalDumpCfg.IgnoreTables = append(canalDumpCfg.IgnoreTables, fmt.Sprintf(IGNORE_TABLE_FORMAT, schema, table))
+	}
+
+	// mysqldump is run with --single-transaction --skip-lock-tables, later options override them
+	switch dumpCfg.LockMode {
+	case "", config.LOCK_MODE_SINGLE_TRANSACTION:
+	case config.LOCK_MODE_LOCK_TABLES:
+		canalDumpCfg.ExtraOptions = append(canalDumpCfg.ExtraOptions, SKIP_SINGLE_TRANSACTION_OPTION, LOCK_TABLES_OPTION)
+	case config.LOCK_MODE_LOCK_ALL_TABLES:
+		canalDumpCfg.ExtraOptions = append(canalDumpCfg.ExtraOptions, SKIP_SINGLE_TRANSACTION_OPTION, LOCK_ALL_TABLES_OPTION)
+	default:
+		return ErrConfig.New(fmt.Sprintf("[SyncManager.parseDumpCfg]invalid lock mode %s", dumpCfg.LockMode))
+	}
+
+	// mysqldump uses the connection charset unless overridden
+	if dumpCfg.Charset != "" {
+		canalDumpCfg.ExtraOptions = append(canalDumpCfg.ExtraOptions, fmt.Sprintf(DUMP_CHARSET_OPTION_FORMAT, dumpCfg.Charset))
+	}
+
+	return nil
 }
 
 // isValidTable - checks if tables provided in config are valid
@@ -363,6 +853,16 @@ func sourceKey(schema, table string) string {
 	return fmt.Sprintf(SOURCE_KEY_FORMAT, schema, table)
 }
 
+// splitTableKey - splits a schema.table key into its schema and table
+func splitTableKey(key string) (schema, table string, ok bool) {
+	parts := strings.SplitN(key, TABLE_KEY_SEPARATOR, TABLE_KEY_PARTS)
+	if len(parts) != TABLE_KEY_PARTS || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
 func initLogger(ctx context.Context, cfg *config.Config) (*log.Logger, error) {
 	logger.WithContext(ctx).Info("[SyncManager.initLogger]initializing logger", zap.Uint32("server id", cfg.ServerId))
 
@@ -0,0 +1,269 @@
+package syncmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/handlers/events/kafka"
+	"github.com/twothicc/canal/tools/secret"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// CheckResult - outcome of a single validation check
+type CheckResult struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// ValidationReport - outcome of validating a pipeline config without creating it
+//
+// IsValid is false if any check failed, warnings do not make a config invalid
+type ValidationReport struct {
+	Checks  []CheckResult
+	IsValid bool
+}
+
+// Validate - dry runs cfg against its source database and kafka, reporting every check
+//
+// Checks that depend on a failed check are skipped
+func Validate(ctx context.Context, cfg *config.Config) *ValidationReport {
+	report := &ValidationReport{
+		IsValid: true,
+	}
+
+	validateMySQL(ctx, cfg, report)
+	validateKafka(ctx, cfg.KafkaConfig, report)
+
+	return report
+}
+
+func (r *ValidationReport) add(name, status, detail string) {
+	r.Checks = append(r.Checks, CheckResult{
+		Name:   name,
+		Status: status,
+		Detail: detail,
+	})
+
+	if status == CHECK_FAIL {
+		r.IsValid = false
+	}
+}
+
+func (r *ValidationReport) skip(reason string, names ...string) {
+	for _, name := range names {
+		r.add(name, CHECK_SKIPPED, reason)
+	}
+}
+
+func validateMySQL(ctx context.Context, cfg *config.Config, report *ValidationReport) {
+	dbCfg := cfg.DbConfig
+
+	conn, err := connect(ctx, dbCfg)
+	if err != nil {
+		logger.WithContext(ctx).Error("[SyncManager.Validate]fail to connect", zap.Error(err))
+		report.add(CHECK_CONNECT, CHECK_FAIL, err.Error())
+		report.skip(
+			"mysql is unreachable",
+			CHECK_PRIVILEGES,
+			CHECK_BINLOG_FORMAT,
+			CHECK_BINLOG_IMAGE,
+			CHECK_GTID_MODE,
+			CHECK_TABLES,
+			CHECK_PRIMARY_KEYS,
+		)
+
+		return
+	}
+
+	defer conn.Close()
+
+	report.add(CHECK_CONNECT, CHECK_OK, fmt.Sprintf("connected to %s as %s", dbCfg.Addr, dbCfg.User))
+
+	validatePrivileges(conn, report)
+	validateVariable(conn, report, CHECK_BINLOG_FORMAT, BINLOG_FORMAT_VAR, ROW_BINLOG_FORMAT, CHECK_FAIL)
+	validateVariable(conn, report, CHECK_BINLOG_IMAGE, BINLOG_IMAGE_VAR, FULL_ROW_IMAGE, CHECK_FAIL)
+	validateVariable(conn, report, CHECK_GTID_MODE, GTID_MODE_VAR, GTID_MODE_ON, CHECK_WARN)
+
+	tables, err := resolveTables(ctx, conn, cfg.Sources)
+	if err != nil {
+		report.add(CHECK_TABLES, CHECK_FAIL, err.Error())
+		report.skip("source tables could not be resolved", CHECK_PRIMARY_KEYS)
+
+		return
+	}
+
+	if quotaErr := checkTableQuota(cfg.QuotaConfig, tables); quotaErr != nil {
+		report.add(CHECK_TABLES, CHECK_FAIL, quotaErr.Error())
+	} else if resolved := countTables(tables); resolved == 0 {
+		report.add(CHECK_TABLES, CHECK_FAIL, "no tables match the sources")
+	} else {
+		report.add(CHECK_TABLES, CHECK_OK, fmt.Sprintf("%d tables resolved: %s", resolved, formatTables(tables)))
+	}
+
+	validatePrimaryKeys(conn, tables, report)
+}
+
+// validatePrivileges - checks the grants needed to read binlog and snapshot tables
+func validatePrivileges(conn *client.Conn, report *ValidationReport) {
+	res, err := conn.Execute(SHOW_GRANTS_SQL)
+	if err != nil {
+		report.add(CHECK_PRIVILEGES, CHECK_FAIL, err.Error())
+
+		return
+	}
+
+	defer res.Close()
+
+	grants := make([]string, 0, res.Resultset.RowNumber())
+
+	for rowNum := 0; rowNum < res.Resultset.RowNumber(); rowNum++ {
+		grant, _ := res.GetString(rowNum, 0)
+		grants = append(grants, strings.ToUpper(grant))
+	}
+
+	allGrants := strings.Join(grants, "\n")
+	if strings.Contains(allGrants, ALL_PRIVILEGES) {
+		report.add(CHECK_PRIVILEGES, CHECK_OK, "user has all privileges")
+
+		return
+	}
+
+	missing := []string{}
+
+	for _, privilege := range []string{PRIVILEGE_REPL, PRIVILEGE_CLIENT, PRIVILEGE_SELECT} {
+		if !strings.Contains(allGrants, privilege) {
+			missing = append(missing, privilege)
+		}
+	}
+
+	if len(missing) > 0 {
+		report.add(CHECK_PRIVILEGES, CHECK_FAIL, fmt.Sprintf("missing %s", strings.Join(missing, ", ")))
+
+		return
+	}
+
+	report.add(CHECK_PRIVILEGES, CHECK_OK, "user has REPLICATION SLAVE, REPLICATION CLIENT and SELECT")
+}
+
+// validateVariable - checks that a global variable has the expected value, reporting
+// mismatchStatus otherwise
+func validateVariable(
+	conn *client.Conn,
+	report *ValidationReport,
+	name string,
+	variable string,
+	expected string,
+	mismatchStatus string,
+) {
+	res, err := conn.Execute(SHOW_VARIABLE_SQL, variable)
+	if err != nil {
+		report.add(name, CHECK_FAIL, err.Error())
+
+		return
+	}
+
+	defer res.Close()
+
+	if res.Resultset.RowNumber() == 0 {
+		report.add(name, mismatchStatus, fmt.Sprintf("%s is not supported by the server", variable))
+
+		return
+	}
+
+	value, _ := res.GetString(0, 1)
+
+	if !strings.EqualFold(value, expected) {
+		report.add(name, mismatchStatus, fmt.Sprintf("%s is %s, expected %s", variable, value, expected))
+
+		return
+	}
+
+	report.add(name, CHECK_OK, fmt.Sprintf("%s is %s", variable, value))
+}
+
+// validatePrimaryKeys - warns about tables without a primary key, whose messages have no key
+// and whose snapshots cannot be chunked by key
+func validatePrimaryKeys(conn *client.Conn, tables map[string][]string, report *ValidationReport) {
+	missing := []string{}
+
+	for schema, schemaTables := range tables {
+		for _, table := range schemaTables {
+			res, err := conn.Execute(PRIMARY_KEY_SQL, schema, table)
+			if err != nil {
+				report.add(CHECK_PRIMARY_KEYS, CHECK_FAIL, err.Error())
+
+				return
+			}
+
+			count, _ := res.GetInt(0, 0)
+			res.Close()
+
+			if count == 0 {
+				missing = append(missing, sourceKey(schema, table))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		report.add(CHECK_PRIMARY_KEYS, CHECK_WARN, fmt.Sprintf("tables without primary key: %s", strings.Join(missing, ", ")))
+
+		return
+	}
+
+	report.add(CHECK_PRIMARY_KEYS, CHECK_OK, "every table has a primary key")
+}
+
+func validateKafka(ctx context.Context, kafkaCfg config.KafkaConfig, report *ValidationReport) {
+	err := kafka.CheckTopic(ctx, kafkaCfg)
+
+	switch {
+	case err == nil:
+		report.add(CHECK_KAFKA_BROKERS, CHECK_OK, fmt.Sprintf("reached %s", strings.Join(kafkaCfg.BrokerList, ", ")))
+		report.add(CHECK_KAFKA_TOPIC, CHECK_OK, fmt.Sprintf("topic %s exists", kafkaCfg.Topic))
+	case kafka.ErrTopic.Is(err):
+		report.add(CHECK_KAFKA_BROKERS, CHECK_OK, fmt.Sprintf("reached %s", strings.Join(kafkaCfg.BrokerList, ", ")))
+		report.add(CHECK_KAFKA_TOPIC, CHECK_FAIL, err.Error())
+	default:
+		report.add(CHECK_KAFKA_BROKERS, CHECK_FAIL, err.Error())
+		report.skip("kafka is unreachable", CHECK_KAFKA_TOPIC)
+	}
+}
+
+func countTables(tables map[string][]string) int {
+	count := 0
+	for _, schemaTables := range tables {
+		count += len(schemaTables)
+	}
+
+	return count
+}
+
+func formatTables(tables map[string][]string) string {
+	keys := []string{}
+
+	for schema, schemaTables := range tables {
+		for _, table := range schemaTables {
+			keys = append(keys, sourceKey(schema, table))
+		}
+	}
+
+	sort.Strings(keys)
+
+	return strings.Join(keys, ", ")
+}
+
+// connect - connects to the database in dbCfg, resolving its password reference
+func connect(ctx context.Context, dbCfg config.DbConfig) (*client.Conn, error) {
+	password, err := secret.Resolve(ctx, dbCfg.Pass)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Connect(dbCfg.Addr, dbCfg.User, password, "")
+}
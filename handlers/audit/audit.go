@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/infra/auditlog"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+type AuditResponse struct {
+	Page *auditlog.Page
+}
+
+// NewAuditHandler - GET /audit, pages through the latest control-plane calls, newest first
+//
+// The before query is the Next cursor of the previous page, limit the page size
+func NewAuditHandler(ctx context.Context, auditLog *auditlog.AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		before, limit, err := parsePage(c)
+		if err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewAuditHandler]fail to abort after invalid page", zap.Error(err))
+			}
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, AuditResponse{
+			Page: auditLog.Page(before, limit),
+		})
+	}
+}
+
+// parsePage - parses the before cursor and the limit, capped at MAX_LIMIT
+func parsePage(c *gin.Context) (before uint64, limit int, err error) {
+	limit = DEFAULT_LIMIT
+
+	if rawBefore := c.Query(BEFORE_QUERY); rawBefore != "" {
+		before, err = strconv.ParseUint(rawBefore, BASE10, BIT64)
+		if err != nil {
+			return 0, 0, ErrParam.New(fmt.Sprintf("[audit.parsePage]invalid before %q", rawBefore))
+		}
+	}
+
+	if rawLimit := c.Query(LIMIT_QUERY); rawLimit != "" {
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			return 0, 0, ErrParam.New(fmt.Sprintf("[audit.parsePage]invalid limit %q", rawLimit))
+		}
+	}
+
+	if limit > MAX_LIMIT {
+		limit = MAX_LIMIT
+	}
+
+	return before, limit, nil
+}
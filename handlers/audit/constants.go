@@ -0,0 +1,18 @@
+package audit
+
+// Query parameters
+const (
+	BEFORE_QUERY = "before"
+	LIMIT_QUERY  = "limit"
+)
+
+const (
+	BASE10 = 10
+	BIT64  = 64
+)
+
+// Page limits
+const (
+	DEFAULT_LIMIT = 50
+	MAX_LIMIT     = 1000
+)
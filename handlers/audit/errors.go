@@ -0,0 +1,12 @@
+package audit
+
+import (
+	"github.com/twothicc/common-go/errortype"
+)
+
+const pkg = "handlers/audit"
+
+//nolint:gomnd // error code
+var (
+	ErrParam = errortype.ErrorType{Code: 1, Pkg: pkg}
+)
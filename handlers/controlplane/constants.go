@@ -0,0 +1,8 @@
+package controlplane
+
+import "time"
+
+const (
+	// WATCH_POLL_INTERVAL - how often watched pipelines are checked for changes
+	WATCH_POLL_INTERVAL = 500 * time.Millisecond
+)
@@ -0,0 +1,110 @@
+package controlplane
+
+import (
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	pb "github.com/twothicc/canal/proto/controlplanepb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toPipeline(status *syncmanager.Status) *pb.Pipeline {
+	pipeline := &pb.Pipeline{
+		Id:          status.ServerId,
+		State:       string(status.State),
+		Reason:      status.Reason,
+		Role:        status.Role,
+		Sources:     toSources(status.Sources),
+		Restarts:    status.Restarts,
+		LastFailure: status.LastFailure,
+		IsRunning:   status.IsRunning,
+	}
+
+	if !status.LastFailureTime.IsZero() {
+		pipeline.LastFailureTime = timestamppb.New(status.LastFailureTime)
+	}
+
+	if status.LastError != nil {
+		pipeline.LastError = &pb.ErrorInfo{
+			Time: timestamppb.New(status.LastError.Time),
+			Pkg:  status.LastError.Pkg,
+			Msg:  status.LastError.Msg,
+			Code: status.LastError.Code,
+		}
+	}
+
+	return pipeline
+}
+
+func toSources(sources []config.SourceConfig) []*pb.Source {
+	res := make([]*pb.Source, 0, len(sources))
+
+	for _, source := range sources {
+		res = append(res, &pb.Source{
+			Schema: source.Schema,
+			Tables: source.Tables,
+		})
+	}
+
+	return res
+}
+
+func fromSources(sources []*pb.Source) []config.SourceConfig {
+	res := make([]config.SourceConfig, 0, len(sources))
+
+	for _, source := range sources {
+		res = append(res, config.SourceConfig{
+			Schema: source.GetSchema(),
+			Tables: source.GetTables(),
+		})
+	}
+
+	return res
+}
+
+func fromKafka(kafka *pb.KafkaConfig) config.KafkaConfig {
+	return config.KafkaConfig{
+		Topic:      kafka.GetTopic(),
+		BrokerList: kafka.GetBrokerList(),
+		Retry:      kafka.GetRetry(),
+		Flush:      kafka.GetFlush(),
+	}
+}
+
+func toDrain(drain *syncmanager.DrainResult) *pb.DrainResult {
+	if drain == nil {
+		return nil
+	}
+
+	return &pb.DrainResult{
+		Checkpoint: &pb.BinlogPosition{
+			Name: drain.Checkpoint.Name,
+			Pos:  drain.Checkpoint.Pos,
+		},
+		DurationMs:  drain.Duration.Milliseconds(),
+		InFlight:    int32(drain.InFlight),
+		IsCompleted: drain.IsCompleted,
+	}
+}
+
+func toCluster(cluster *synccontroller.ClusterStatus) *pb.ClusterStatus {
+	if cluster == nil {
+		return nil
+	}
+
+	res := &pb.ClusterStatus{
+		InstanceId: cluster.InstanceId,
+		Assignment: cluster.Assignment,
+	}
+
+	for _, instance := range cluster.Instances {
+		res.Instances = append(res.Instances, &pb.Instance{
+			Id:        instance.Id,
+			Addr:      instance.Addr,
+			Pipelines: instance.Pipelines,
+			Heartbeat: instance.Heartbeat,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,230 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	pb "github.com/twothicc/canal/proto/controlplanepb"
+	"github.com/twothicc/canal/tools/secret"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server - gRPC control plane backed by the same SyncController as the HTTP API
+//
+// Pipelines are created from the defaults in cfg, which is not modified. Pipelines run under
+// ctx rather than the context of the call that created them
+type Server struct {
+	pb.UnimplementedControlPlaneServer
+	ctx            context.Context
+	cfg            *config.Config
+	syncController synccontroller.SyncController
+	closing        chan struct{}
+	once           sync.Once
+}
+
+func NewServer(ctx context.Context, cfg *config.Config, syncController synccontroller.SyncController) *Server {
+	return &Server{
+		ctx:            ctx,
+		cfg:            cfg,
+		syncController: syncController,
+		closing:        make(chan struct{}),
+	}
+}
+
+// Close - ends open Watch streams, which would otherwise hold back a graceful stop
+func (s *Server) Close() {
+	s.once.Do(func() {
+		close(s.closing)
+	})
+}
+
+func (s *Server) Create(_ context.Context, req *pb.CreateRequest) (*pb.Pipeline, error) {
+	if req.GetStartPosition() != nil && req.GetIsLegacySync() {
+		return nil, toStatusError(ErrParam.New("[ControlPlane.Create]start_position cannot be combined with is_legacy_sync"))
+	}
+
+	if err := secret.Validate(req.GetPass()); err != nil {
+		return nil, toStatusError(ErrParam.New(fmt.Sprintf("[ControlPlane.Create]invalid pass: %s", err.Error())))
+	}
+
+	db := config.DbConfig{
+		Addr:    req.GetAddr(),
+		User:    req.GetUser(),
+		Pass:    req.GetPass(),
+		Charset: req.GetCharset(),
+		Flavor:  req.GetFlavor(),
+	}
+
+	if err := s.cfg.CheckLogin(db); err != nil {
+		return nil, toStatusError(ErrParam.New(fmt.Sprintf("[ControlPlane.Create]%s", err.Error())))
+	}
+
+	pipelineCfg := s.cfg.Pipeline(db, fromKafka(req.GetKafka()), fromSources(req.GetSources()))
+
+	manager, err := s.syncController.NewSyncManager(s.ctx, pipelineCfg)
+	if err != nil {
+		logger.WithContext(s.ctx).Error("[ControlPlane.Create]fail to create syncmanager", zap.Error(err))
+
+		return nil, toStatusError(err)
+	}
+
+	if pos := req.GetStartPosition(); pos != nil {
+		if err := manager.SetStartPosition(mysql.Position{Name: pos.GetName(), Pos: pos.GetPos()}); err != nil {
+			manager.Close()
+
+			return nil, toStatusError(err)
+		}
+	}
+
+	id := manager.GetId()
+
+	if err := s.syncController.Add(s.ctx, id, manager); err != nil {
+		manager.Close()
+
+		return nil, toStatusError(err)
+	}
+
+	if req.GetStart() {
+		if err := s.syncController.Start(s.ctx, id, req.GetIsLegacySync()); err != nil {
+			logger.WithContext(s.ctx).Error("[ControlPlane.Create]fail to start syncmanager", zap.Uint32("server id", id), zap.Error(err))
+
+			return nil, toStatusError(err)
+		}
+	}
+
+	return s.get(id)
+}
+
+func (s *Server) Get(_ context.Context, req *pb.GetRequest) (*pb.Pipeline, error) {
+	return s.get(req.GetId())
+}
+
+func (s *Server) List(_ context.Context, _ *pb.ListRequest) (*pb.ListResponse, error) {
+	return &pb.ListResponse{
+		Pipelines: s.pipelines(),
+	}, nil
+}
+
+func (s *Server) Start(_ context.Context, req *pb.StartRequest) (*pb.Pipeline, error) {
+	if _, err := s.lookup(req.GetId()); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if err := s.syncController.Start(s.ctx, req.GetId(), req.GetIsLegacySync()); err != nil {
+		logger.WithContext(s.ctx).Error("[ControlPlane.Start]fail to start syncmanager", zap.Uint32("server id", req.GetId()), zap.Error(err))
+
+		return nil, toStatusError(err)
+	}
+
+	return s.get(req.GetId())
+}
+
+func (s *Server) Stop(_ context.Context, req *pb.StopRequest) (*pb.StopResponse, error) {
+	if _, err := s.lookup(req.GetId()); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	drain, err := s.syncController.Stop(s.ctx, req.GetId())
+	if err != nil {
+		logger.WithContext(s.ctx).Error("[ControlPlane.Stop]fail to stop syncmanager", zap.Uint32("server id", req.GetId()), zap.Error(err))
+
+		return nil, toStatusError(err)
+	}
+
+	pipeline, err := s.get(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.StopResponse{
+		Pipeline: pipeline,
+		Drain:    toDrain(drain),
+	}, nil
+}
+
+func (s *Server) Delete(_ context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	if _, err := s.lookup(req.GetId()); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if err := s.syncController.Remove(s.ctx, req.GetId()); err != nil {
+		logger.WithContext(s.ctx).Error("[ControlPlane.Delete]fail to remove syncmanager", zap.Uint32("server id", req.GetId()), zap.Error(err))
+
+		return nil, toStatusError(err)
+	}
+
+	return &pb.DeleteResponse{
+		Id: req.GetId(),
+	}, nil
+}
+
+func (s *Server) Status(_ context.Context, _ *pb.StatusRequest) (*pb.StatusResponse, error) {
+	return &pb.StatusResponse{
+		Pipelines: s.pipelines(),
+		Cluster:   toCluster(s.syncController.Cluster()),
+	}, nil
+}
+
+func (s *Server) get(id uint32) (*pb.Pipeline, error) {
+	pipelineStatus, err := s.lookup(id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toPipeline(pipelineStatus), nil
+}
+
+// lookup - returns the status of pipeline id, or ErrNotFound
+func (s *Server) lookup(id uint32) (*syncmanager.Status, error) {
+	pipelineStatus, ok := s.syncController.Status()[id]
+	if !ok {
+		return nil, ErrNotFound.New(fmt.Sprintf("[ControlPlane.lookup]pipeline %d does not exist", id))
+	}
+
+	return pipelineStatus, nil
+}
+
+// pipelines - returns every pipeline ordered by id
+func (s *Server) pipelines() []*pb.Pipeline {
+	statuses := s.syncController.Status()
+
+	pipelines := make([]*pb.Pipeline, 0, len(statuses))
+	for _, pipelineStatus := range statuses {
+		pipelines = append(pipelines, toPipeline(pipelineStatus))
+	}
+
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].Id < pipelines[j].Id
+	})
+
+	return pipelines
+}
+
+// toStatusError - returns err as a gRPC status error with the code matching its errortype
+func toStatusError(err error) error {
+	code := codes.Internal
+
+	switch {
+	case ErrNotFound.Is(err):
+		code = codes.NotFound
+	case synccontroller.ErrExists.Is(err):
+		code = codes.AlreadyExists
+	case ErrParam.Is(err), synccontroller.ErrParam.Is(err), syncmanager.ErrParam.Is(err), syncmanager.ErrConfig.Is(err):
+		code = codes.InvalidArgument
+	case synccontroller.ErrQuota.Is(err), syncmanager.ErrQuota.Is(err):
+		code = codes.ResourceExhausted
+	case synccontroller.ErrBusy.Is(err):
+		code = codes.Aborted
+	}
+
+	return status.Error(code, err.Error())
+}
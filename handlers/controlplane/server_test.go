@@ -0,0 +1,531 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	pb "github.com/twothicc/canal/proto/controlplanepb"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	BUF_SIZE       = 1 << 20
+	CREATED_ID     = 101
+	UNKNOWN_ID     = 999
+	WATCH_DEADLINE = 5 * time.Second
+)
+
+func TestMain(m *testing.M) {
+	// the logger writes server.log to the working directory
+	dir, err := os.MkdirTemp("", "controlplane")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+
+	logger.InitLogger(zapcore.FatalLevel)
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type fakeSyncManager struct {
+	syncmanager.SyncManager
+	cfg       config.Config
+	startPos  *mysql.Position
+	isRunning bool
+	isClosed  bool
+}
+
+func (m *fakeSyncManager) GetId() uint32 {
+	return m.cfg.ServerId
+}
+
+func (m *fakeSyncManager) Close() *syncmanager.DrainResult {
+	m.isClosed = true
+	m.isRunning = false
+
+	return &syncmanager.DrainResult{
+		Checkpoint:  mysql.Position{Name: "mysql-bin.000001", Pos: 4},
+		IsCompleted: true,
+	}
+}
+
+func (m *fakeSyncManager) SetStartPosition(pos mysql.Position) error {
+	m.startPos = &pos
+
+	return nil
+}
+
+func (m *fakeSyncManager) Status() *syncmanager.Status {
+	state := syncmanager.STATE_STOPPED
+	if m.isRunning {
+		state = syncmanager.STATE_STREAMING
+	}
+
+	return &syncmanager.Status{
+		ServerId:  m.cfg.ServerId,
+		Sources:   m.cfg.Sources,
+		State:     state,
+		IsRunning: m.isRunning,
+	}
+}
+
+// fakeSyncController - keeps pipelines in memory, failing like the SyncController does
+type fakeSyncController struct {
+	synccontroller.SyncController
+	managers map[uint32]*fakeSyncManager
+	newErr   error
+	addErr   error
+	mu       sync.Mutex
+}
+
+func newFakeSyncController(managers ...*fakeSyncManager) *fakeSyncController {
+	s := &fakeSyncController{
+		managers: make(map[uint32]*fakeSyncManager),
+	}
+
+	for _, manager := range managers {
+		s.managers[manager.GetId()] = manager
+	}
+
+	return s
+}
+
+// NewSyncManager - gives every new pipeline CREATED_ID, or fails with newErr if set
+func (s *fakeSyncController) NewSyncManager(_ context.Context, cfg *config.Config) (syncmanager.SyncManager, error) {
+	if s.newErr != nil {
+		return nil, s.newErr
+	}
+
+	manager := &fakeSyncManager{cfg: *cfg}
+	manager.cfg.ServerId = CREATED_ID
+
+	return manager, nil
+}
+
+func (s *fakeSyncController) Add(_ context.Context, id uint32, manager syncmanager.SyncManager) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.addErr != nil {
+		return s.addErr
+	}
+
+	if _, ok := s.managers[id]; ok {
+		return synccontroller.ErrExists.New(fmt.Sprintf("pipeline %d already exists", id))
+	}
+
+	s.managers[id] = manager.(*fakeSyncManager)
+
+	return nil
+}
+
+func (s *fakeSyncController) Remove(_ context.Context, id uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.managers[id]; !ok {
+		return synccontroller.ErrParam.New(fmt.Sprintf("id %d does not exist", id))
+	}
+
+	delete(s.managers, id)
+
+	return nil
+}
+
+func (s *fakeSyncController) Start(_ context.Context, id uint32, _ bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manager, ok := s.managers[id]
+	if !ok {
+		return synccontroller.ErrParam.New(fmt.Sprintf("id %d does not exist", id))
+	}
+
+	manager.isRunning = true
+
+	return nil
+}
+
+func (s *fakeSyncController) Stop(_ context.Context, id uint32) (*syncmanager.DrainResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manager, ok := s.managers[id]
+	if !ok {
+		return nil, synccontroller.ErrParam.New(fmt.Sprintf("id %d does not exist", id))
+	}
+
+	return manager.Close(), nil
+}
+
+func (s *fakeSyncController) Status() map[uint32]*syncmanager.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make(map[uint32]*syncmanager.Status, len(s.managers))
+	for id, manager := range s.managers {
+		res[id] = manager.Status()
+	}
+
+	return res
+}
+
+func (s *fakeSyncController) Cluster() *synccontroller.ClusterStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assignment := make(map[uint32]string, len(s.managers))
+	for id := range s.managers {
+		assignment[id] = "instance-1"
+	}
+
+	return &synccontroller.ClusterStatus{
+		InstanceId: "instance-1",
+		Assignment: assignment,
+	}
+}
+
+func newFakeSyncManager(id uint32, isRunning bool) *fakeSyncManager {
+	return &fakeSyncManager{
+		cfg: config.Config{
+			ServerId: id,
+			Sources:  []config.SourceConfig{{Schema: "test", Tables: []string{"test_table"}}},
+		},
+		isRunning: isRunning,
+	}
+}
+
+// newClient - serves a control plane backed by syncController over an in-memory connection
+func newClient(t *testing.T, syncController synccontroller.SyncController) pb.ControlPlaneClient {
+	t.Helper()
+
+	ctx := context.Background()
+	listener := bufconn.Listen(BUF_SIZE)
+
+	controlPlane := NewServer(ctx, &config.Config{}, syncController)
+	server := grpc.NewServer()
+	pb.RegisterControlPlaneServer(server, controlPlane)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("fail to dial control plane: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		controlPlane.Close()
+		server.Stop()
+	})
+
+	return pb.NewControlPlaneClient(conn)
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != want {
+		t.Fatalf("got code %s, want %s: %v", got, want, err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	syncController := newFakeSyncController()
+	client := newClient(t, syncController)
+
+	pipeline, err := client.Create(context.Background(), &pb.CreateRequest{
+		Sources:       []*pb.Source{{Schema: "orders", Tables: []string{"orders"}}},
+		StartPosition: &pb.BinlogPosition{Name: "mysql-bin.000002", Pos: 120},
+		Start:         true,
+	})
+	if err != nil {
+		t.Fatalf("fail to create: %v", err)
+	}
+
+	if pipeline.GetId() != CREATED_ID || !pipeline.GetIsRunning() {
+		t.Fatalf("got pipeline %d running %t, want %d running", pipeline.GetId(), pipeline.GetIsRunning(), CREATED_ID)
+	}
+
+	if sources := pipeline.GetSources(); len(sources) != 1 || sources[0].GetSchema() != "orders" {
+		t.Fatalf("got sources %v, want orders", sources)
+	}
+
+	if pos := syncController.managers[CREATED_ID].startPos; pos == nil || pos.Name != "mysql-bin.000002" || pos.Pos != 120 {
+		t.Fatalf("got start position %v, want mysql-bin.000002:120", pos)
+	}
+}
+
+func TestCreateInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *pb.CreateRequest
+		newErr error
+		addErr error
+		want   codes.Code
+	}{
+		{
+			name: "start position with legacy sync",
+			req: &pb.CreateRequest{
+				StartPosition: &pb.BinlogPosition{Name: "mysql-bin.000001", Pos: 4},
+				IsLegacySync:  true,
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "plain text password",
+			req:  &pb.CreateRequest{Pass: "hunter2"},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "reference that is not allowed",
+			req:  &pb.CreateRequest{Pass: "env:AUTH_ADMIN_SECRET"},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "other login without pass",
+			req:  &pb.CreateRequest{Addr: "other-db:3306", User: "other"},
+			want: codes.InvalidArgument,
+		},
+		{
+			name:   "invalid config",
+			req:    &pb.CreateRequest{},
+			newErr: syncmanager.ErrConfig.New("no sources"),
+			want:   codes.InvalidArgument,
+		},
+		{
+			name:   "over quota",
+			req:    &pb.CreateRequest{},
+			addErr: synccontroller.ErrQuota.New("instance already has the maximum of 1 pipelines"),
+			want:   codes.ResourceExhausted,
+		},
+		{
+			name:   "taken id",
+			req:    &pb.CreateRequest{},
+			addErr: synccontroller.ErrExists.New(fmt.Sprintf("pipeline %d already exists", CREATED_ID)),
+			want:   codes.AlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncController := newFakeSyncController()
+			syncController.newErr = tt.newErr
+			syncController.addErr = tt.addErr
+
+			client := newClient(t, syncController)
+
+			_, err := client.Create(context.Background(), tt.req)
+			assertCode(t, err, tt.want)
+
+			if len(syncController.managers) > 0 {
+				t.Fatalf("got %d pipelines, want none added", len(syncController.managers))
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	client := newClient(t, newFakeSyncController(newFakeSyncManager(1, true)))
+
+	pipeline, err := client.Get(context.Background(), &pb.GetRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("fail to get: %v", err)
+	}
+
+	if pipeline.GetId() != 1 || pipeline.GetState() != string(syncmanager.STATE_STREAMING) {
+		t.Fatalf("got pipeline %d in %s, want 1 in %s", pipeline.GetId(), pipeline.GetState(), syncmanager.STATE_STREAMING)
+	}
+
+	_, err = client.Get(context.Background(), &pb.GetRequest{Id: UNKNOWN_ID})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestList(t *testing.T) {
+	client := newClient(t, newFakeSyncController(
+		newFakeSyncManager(3, false),
+		newFakeSyncManager(1, true),
+		newFakeSyncManager(2, false),
+	))
+
+	res, err := client.List(context.Background(), &pb.ListRequest{})
+	if err != nil {
+		t.Fatalf("fail to list: %v", err)
+	}
+
+	ids := make([]uint32, 0, len(res.GetPipelines()))
+	for _, pipeline := range res.GetPipelines() {
+		ids = append(ids, pipeline.GetId())
+	}
+
+	if len(ids) != 3 || !sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] }) {
+		t.Fatalf("got ids %v, want 1, 2 and 3 in order", ids)
+	}
+}
+
+func TestStart(t *testing.T) {
+	client := newClient(t, newFakeSyncController(newFakeSyncManager(1, false)))
+
+	pipeline, err := client.Start(context.Background(), &pb.StartRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("fail to start: %v", err)
+	}
+
+	if !pipeline.GetIsRunning() {
+		t.Fatal("got pipeline not running, want running")
+	}
+
+	_, err = client.Start(context.Background(), &pb.StartRequest{Id: UNKNOWN_ID})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestStop(t *testing.T) {
+	client := newClient(t, newFakeSyncController(newFakeSyncManager(1, true)))
+
+	res, err := client.Stop(context.Background(), &pb.StopRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("fail to stop: %v", err)
+	}
+
+	if res.GetPipeline().GetIsRunning() {
+		t.Fatal("got pipeline running, want stopped")
+	}
+
+	if drain := res.GetDrain(); !drain.GetIsCompleted() || drain.GetCheckpoint().GetName() != "mysql-bin.000001" {
+		t.Fatalf("got drain %v, want completed at mysql-bin.000001", drain)
+	}
+
+	_, err = client.Stop(context.Background(), &pb.StopRequest{Id: UNKNOWN_ID})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestDelete(t *testing.T) {
+	syncController := newFakeSyncController(newFakeSyncManager(1, true))
+	client := newClient(t, syncController)
+
+	res, err := client.Delete(context.Background(), &pb.DeleteRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("fail to delete: %v", err)
+	}
+
+	if res.GetId() != 1 || len(syncController.managers) != 0 {
+		t.Fatalf("got deleted id %d with %d pipelines left, want 1 with none left", res.GetId(), len(syncController.managers))
+	}
+
+	_, err = client.Delete(context.Background(), &pb.DeleteRequest{Id: 1})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestStatus(t *testing.T) {
+	client := newClient(t, newFakeSyncController(newFakeSyncManager(1, true), newFakeSyncManager(2, false)))
+
+	res, err := client.Status(context.Background(), &pb.StatusRequest{})
+	if err != nil {
+		t.Fatalf("fail to get status: %v", err)
+	}
+
+	if len(res.GetPipelines()) != 2 {
+		t.Fatalf("got %d pipelines, want 2", len(res.GetPipelines()))
+	}
+
+	if cluster := res.GetCluster(); cluster.GetInstanceId() != "instance-1" || len(cluster.GetAssignment()) != 2 {
+		t.Fatalf("got cluster %v, want both pipelines assigned to instance-1", cluster)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	syncController := newFakeSyncController(newFakeSyncManager(1, true), newFakeSyncManager(2, true))
+	client := newClient(t, syncController)
+
+	ctx, cancel := context.WithTimeout(context.Background(), WATCH_DEADLINE)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &pb.WatchRequest{Ids: []uint32{1}})
+	if err != nil {
+		t.Fatalf("fail to watch: %v", err)
+	}
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("fail to receive: %v", err)
+	}
+
+	if event.GetPipeline().GetId() != 1 || event.GetIsRemoved() {
+		t.Fatalf("got event of pipeline %d removed %t, want pipeline 1", event.GetPipeline().GetId(), event.GetIsRemoved())
+	}
+
+	if err := syncController.Remove(ctx, 1); err != nil {
+		t.Fatalf("fail to remove: %v", err)
+	}
+
+	event, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("fail to receive: %v", err)
+	}
+
+	if event.GetPipeline().GetId() != 1 || !event.GetIsRemoved() {
+		t.Fatalf("got event of pipeline %d removed %t, want pipeline 1 removed", event.GetPipeline().GetId(), event.GetIsRemoved())
+	}
+}
+
+func TestUnknownIdStatusCodes(t *testing.T) {
+	client := newClient(t, newFakeSyncController())
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"Get": func() error {
+			_, err := client.Get(ctx, &pb.GetRequest{Id: UNKNOWN_ID})
+			return err
+		},
+		"Start": func() error {
+			_, err := client.Start(ctx, &pb.StartRequest{Id: UNKNOWN_ID})
+			return err
+		},
+		"Stop": func() error {
+			_, err := client.Stop(ctx, &pb.StopRequest{Id: UNKNOWN_ID})
+			return err
+		},
+		"Delete": func() error {
+			_, err := client.Delete(ctx, &pb.DeleteRequest{Id: UNKNOWN_ID})
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			assertCode(t, call(), codes.NotFound)
+		})
+	}
+}
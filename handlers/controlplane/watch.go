@@ -0,0 +1,70 @@
+package controlplane
+
+import (
+	"time"
+
+	pb "github.com/twothicc/canal/proto/controlplanepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Watch - streams the watched pipelines as they are, then again whenever they change
+//
+// Pipelines are polled every WATCH_POLL_INTERVAL, so changes in between are coalesced.
+// Deleted pipelines are sent once with is_removed set
+func (s *Server) Watch(req *pb.WatchRequest, stream pb.ControlPlane_WatchServer) error {
+	watched := make(map[uint32]bool)
+	for _, id := range req.GetIds() {
+		watched[id] = true
+	}
+
+	last := make(map[uint32]*pb.Pipeline)
+
+	ticker := time.NewTicker(WATCH_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		now := timestamppb.Now()
+		statuses := s.syncController.Status()
+
+		for id, pipelineStatus := range statuses {
+			if len(watched) > 0 && !watched[id] {
+				continue
+			}
+
+			pipeline := toPipeline(pipelineStatus)
+
+			if prev, ok := last[id]; ok && proto.Equal(prev, pipeline) {
+				continue
+			}
+
+			if err := stream.Send(&pb.WatchEvent{Pipeline: pipeline, Time: now}); err != nil {
+				return err
+			}
+
+			last[id] = pipeline
+		}
+
+		for id, prev := range last {
+			if _, ok := statuses[id]; ok {
+				continue
+			}
+
+			if err := stream.Send(&pb.WatchEvent{Pipeline: prev, Time: now, IsRemoved: true}); err != nil {
+				return err
+			}
+
+			delete(last, id)
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.closing:
+			return status.Error(codes.Unavailable, "[ControlPlane.Watch]server is shutting down")
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+
+	sarama "gopkg.in/Shopify/sarama.v1"
+)
+
+// CheckTopic - connects to the brokers of kafkaCfg and checks that its topic exists
+//
+// Returns ErrConnect if no broker is reachable and ErrTopic if the topic does not exist
+func CheckTopic(ctx context.Context, kafkaCfg config.KafkaConfig) error {
+	saramaCfg := sarama.NewConfig()
+
+	saramaCfg.Net.DialTimeout = checkTimeout
+	saramaCfg.Net.ReadTimeout = checkTimeout
+	saramaCfg.Metadata.Retry.Max = 0
+
+	client, err := sarama.NewClient(kafkaCfg.BrokerList, saramaCfg)
+	if err != nil {
+		logger.WithContext(ctx).Error("[CheckTopic]fail to connect to brokers", zap.Error(err))
+
+		return ErrConnect.New(fmt.Sprintf("[CheckTopic]%s", err.Error()))
+	}
+
+	defer client.Close()
+
+	topics, err := client.Topics()
+	if err != nil {
+		return ErrConnect.New(fmt.Sprintf("[CheckTopic]%s", err.Error()))
+	}
+
+	for _, topic := range topics {
+		if topic == kafkaCfg.Topic {
+			return nil
+		}
+	}
+
+	return ErrTopic.New(fmt.Sprintf("[CheckTopic]topic %s does not exist", kafkaCfg.Topic))
+}
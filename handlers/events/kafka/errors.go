@@ -10,4 +10,6 @@ const pkg = "handlers/events/kafka"
 var (
 	ErrConstructor = errortype.ErrorType{Code: 1, Pkg: pkg}
 	ErrProduce     = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrConnect     = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrTopic       = errortype.ErrorType{Code: 3, Pkg: pkg}
 )
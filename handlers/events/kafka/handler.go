@@ -3,8 +3,10 @@ package kafka
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	gometrics "github.com/rcrowley/go-metrics"
 	"github.com/twothicc/canal/config"
 	"github.com/twothicc/common-go/logger"
 	"go.uber.org/zap"
@@ -14,18 +16,47 @@ import (
 
 type MessageProducer struct {
 	sarama.AsyncProducer
-	inputCh chan<- *sarama.ProducerMessage
-	topic   string
+	metricRegistry gometrics.Registry
+	inputCh        chan<- *sarama.ProducerMessage
+	pending        map[uint64]time.Time
+	failed         map[uint64]error
+	onResult       func(ProduceResult)
+	topic          string
+	seq            uint64
+	mu             sync.Mutex
+	once           sync.Once
+}
+
+// ProduceResult - outcome of a produced message once kafka acknowledged or rejected it
+//
+// Err is nil when the message was stored at Partition and Offset, otherwise an ErrProduce
+type ProduceResult struct {
+	Msg       IMessage
+	Err       error
+	Latency   time.Duration
+	Seq       uint64
+	Offset    int64
+	Partition int32
 }
 
 type IMessageProducer interface {
 	sarama.AsyncProducer
-	Produce(ctx context.Context, msg IMessage)
+	Produce(ctx context.Context, msg IMessage) uint64
+	LastSeq() uint64
+	AckedSeq() uint64
+	InFlight() int
+	Failed() int
+	Err() error
+	MetricRegistry() gometrics.Registry
 }
 
+// NewMessageProducer - creates a producer to the topic in kafkaCfg
+//
+// onResult, if not nil, is called with the outcome of every produced message
 func NewMessageProducer(
 	ctx context.Context,
 	kafkaCfg config.KafkaConfig,
+	onResult func(ProduceResult),
 ) (IMessageProducer, error) {
 	saramaCfg := sarama.NewConfig()
 
@@ -43,43 +74,191 @@ func NewMessageProducer(
 	}
 
 	return &MessageProducer{
-		AsyncProducer: producer,
-		topic:         kafkaCfg.Topic,
+		AsyncProducer:  producer,
+		metricRegistry: saramaCfg.MetricRegistry,
+		pending:        make(map[uint64]time.Time),
+		failed:         make(map[uint64]error),
+		onResult:       onResult,
+		topic:          kafkaCfg.Topic,
 	}, nil
 }
 
-func (m *MessageProducer) Produce(ctx context.Context, msg IMessage) {
-	if m.inputCh == nil {
+// Produce - sends msg to the producer asynchronously, returning its sequence number
+//
+// Each message is given a sequence number, tracked as in flight until kafka acknowledges
+// or rejects it. Safe for concurrent use, e.g. by parallel snapshot workers
+func (m *MessageProducer) Produce(ctx context.Context, msg IMessage) uint64 {
+	m.once.Do(func() {
 		go func() {
-			for {
+			successCh, errorCh := m.Successes(), m.Errors()
+
+			for successCh != nil || errorCh != nil {
 				select {
-				case successMsg := <-m.Successes():
-					if successMsg != nil {
-						logger.WithContext(ctx).Debug(
-							fmt.Sprintf("[MessageProducer.Produce]msg stored in topic(%s)/partition(%d)/offset(%d)",
-								successMsg.Topic, successMsg.Partition, successMsg.Offset,
-							),
-						)
+				case successMsg, ok := <-successCh:
+					if !ok {
+						successCh = nil
+
+						continue
 					}
-				case errorMsg := <-m.Errors():
-					if errorMsg != nil {
-						logger.WithContext(ctx).Error(
-							"[MessageProducer.Produce]failed to produce message",
-							zap.Error(errorMsg.Err),
-						)
+
+					m.ack(successMsg, nil)
+
+					logger.WithContext(ctx).Debug(
+						fmt.Sprintf("[MessageProducer.Produce]msg stored in topic(%s)/partition(%d)/offset(%d)",
+							successMsg.Topic, successMsg.Partition, successMsg.Offset,
+						),
+					)
+				case errorMsg, ok := <-errorCh:
+					if !ok {
+						errorCh = nil
+
+						continue
 					}
+
+					m.ack(errorMsg.Msg, errorMsg.Err)
+
+					logger.WithContext(ctx).Error(
+						"[MessageProducer.Produce]failed to produce message",
+						zap.Error(errorMsg.Err),
+					)
 				}
 			}
 		}()
 
 		m.inputCh = m.Input()
-	}
+	})
+
+	m.mu.Lock()
+	m.seq++
+	seq := m.seq
+	m.pending[seq] = time.Now()
+	m.mu.Unlock()
 
 	producerMessage := &sarama.ProducerMessage{
-		Topic: m.topic,
-		Key:   sarama.StringEncoder(fmt.Sprintf(syncMsgFormat, m.topic, msg.Key())),
-		Value: msg,
+		Topic:    m.topic,
+		Key:      sarama.StringEncoder(fmt.Sprintf(syncMsgFormat, m.topic, msg.Key())),
+		Value:    msg,
+		Metadata: seq,
 	}
 
 	m.inputCh <- producerMessage
+
+	return seq
+}
+
+// LastSeq - returns the sequence number of the last produced message
+func (m *MessageProducer) LastSeq() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.seq
+}
+
+// AckedSeq - returns the highest sequence number up to which every message was acknowledged
+//
+// Rejected messages are never acknowledged, so AckedSeq stays below the first of them
+func (m *MessageProducer) AckedSeq() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acked := m.seq
+
+	for seq := range m.pending {
+		if seq <= acked {
+			acked = seq - 1
+		}
+	}
+
+	for seq := range m.failed {
+		if seq <= acked {
+			acked = seq - 1
+		}
+	}
+
+	return acked
+}
+
+// Failed - returns the number of produced messages kafka rejected
+func (m *MessageProducer) Failed() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.failed)
+}
+
+// Err - returns the error of the first message kafka rejected, nil if none was
+func (m *MessageProducer) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var (
+		first uint64
+		err   error
+	)
+
+	for seq, failErr := range m.failed {
+		if err == nil || seq < first {
+			first, err = seq, failErr
+		}
+	}
+
+	return err
+}
+
+// InFlight - returns the number of produced messages not yet acknowledged or rejected
+func (m *MessageProducer) InFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.pending)
+}
+
+// MetricRegistry - returns the registry of the producer's sarama metrics
+func (m *MessageProducer) MetricRegistry() gometrics.Registry {
+	return m.metricRegistry
+}
+
+// ack - stops tracking msg as in flight and reports its outcome
+//
+// Rejected messages are kept as failed, so that no checkpoint moves past them
+func (m *MessageProducer) ack(msg *sarama.ProducerMessage, err error) {
+	if msg == nil {
+		return
+	}
+
+	seq, ok := msg.Metadata.(uint64)
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		err = ErrProduce.New(fmt.Sprintf("[MessageProducer.ack]%s", err.Error()))
+	}
+
+	m.mu.Lock()
+	producedAt := m.pending[seq]
+	delete(m.pending, seq)
+
+	if err != nil {
+		m.failed[seq] = err
+	}
+	m.mu.Unlock()
+
+	if m.onResult == nil {
+		return
+	}
+
+	result := ProduceResult{
+		Err:       err,
+		Latency:   time.Since(producedAt),
+		Seq:       seq,
+		Offset:    msg.Offset,
+		Partition: msg.Partition,
+	}
+
+	if value, ok := msg.Value.(IMessage); ok {
+		result.Msg = value
+	}
+
+	m.onResult(result)
 }
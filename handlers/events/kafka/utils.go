@@ -1,5 +1,11 @@
 package kafka
 
+import "time"
+
 const (
 	syncMsgFormat = "%s-%s"
 )
+
+const (
+	checkTimeout = 5 * time.Second
+)
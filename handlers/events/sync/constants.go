@@ -23,3 +23,18 @@ const (
 	RETRY_SECONDS   = 10
 	FLUSH_FREQUENCY = 100 * time.Millisecond
 )
+
+// Event log record results
+const (
+	RESULT_PENDING = "pending"
+	RESULT_ACKED   = "acked"
+	RESULT_FAILED  = "failed"
+	RESULT_ERROR   = "error"
+)
+
+const (
+	DEFAULT_EVENT_LOG_SIZE = 1000
+)
+
+// THROUGHPUT_WINDOW - seconds that produced event rates are averaged over
+const THROUGHPUT_WINDOW = 10
@@ -0,0 +1,153 @@
+package sync
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/handlers/events/kafka"
+)
+
+// EventRecord - a message a pipeline produced, or failed to build, and what became of it
+//
+// Result is one of RESULT_PENDING, RESULT_ACKED, RESULT_FAILED or RESULT_ERROR. Latency is
+// from producing the message until kafka acknowledged or rejected it. Pos has an empty name
+// for snapshotted rows
+type EventRecord struct {
+	Time      time.Time
+	Pos       mysql.Position
+	Schema    string
+	Table     string
+	Action    string
+	Result    string
+	Error     string
+	Message   json.RawMessage
+	Latency   time.Duration
+	Id        uint64
+	Offset    int64
+	Partition int32
+	msg       kafka.IMessage
+}
+
+// EventPage - records of an event log, newest first
+//
+// Next is the cursor to the following, older, page and 0 on the last page
+type EventPage struct {
+	Records []EventRecord
+	Next    uint64
+	Total   int
+}
+
+// EventLog - bounded log of the latest records of a pipeline, overwriting the oldest when full
+type EventLog struct {
+	records []EventRecord
+	byMsg   map[kafka.IMessage]uint64
+	nextId  uint64
+	mu      sync.Mutex
+}
+
+// NewEventLog - creates an EventLog holding the latest size records
+func NewEventLog(size int) *EventLog {
+	if size <= 0 {
+		size = DEFAULT_EVENT_LOG_SIZE
+	}
+
+	return &EventLog{
+		records: make([]EventRecord, size),
+		byMsg:   make(map[kafka.IMessage]uint64),
+		nextId:  1,
+	}
+}
+
+// Page - returns up to limit records older than the record id before, newest first
+//
+// A before of 0 starts from the newest record
+func (l *EventLog) Page(before uint64, limit int) *EventPage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	oldest := l.oldestId()
+
+	if before == 0 || before > l.nextId {
+		before = l.nextId
+	}
+
+	page := &EventPage{
+		Records: []EventRecord{},
+		Total:   int(l.nextId - oldest),
+	}
+
+	id := before
+	for ; id > oldest && len(page.Records) < limit; id-- {
+		page.Records = append(page.Records, l.records[l.slot(id-1)])
+	}
+
+	if id > oldest {
+		page.Next = id
+	}
+
+	return page
+}
+
+// add - appends record, overwriting the oldest record when full
+func (l *EventLog) add(record EventRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slot := l.slot(l.nextId)
+
+	if overwritten := l.records[slot].msg; overwritten != nil {
+		delete(l.byMsg, overwritten)
+	}
+
+	record.Id = l.nextId
+
+	if record.msg != nil {
+		l.byMsg[record.msg] = record.Id
+	}
+
+	l.records[slot] = record
+	l.nextId++
+}
+
+// resolve - records the outcome of a produced message, unless it was already overwritten
+func (l *EventLog) resolve(result kafka.ProduceResult) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id, ok := l.byMsg[result.Msg]
+	if !ok {
+		return
+	}
+
+	delete(l.byMsg, result.Msg)
+
+	record := &l.records[l.slot(id)]
+
+	record.Latency = result.Latency
+	record.msg = nil
+
+	if result.Err != nil {
+		record.Result = RESULT_FAILED
+		record.Error = result.Err.Error()
+
+		return
+	}
+
+	record.Result = RESULT_ACKED
+	record.Partition = result.Partition
+	record.Offset = result.Offset
+}
+
+func (l *EventLog) oldestId() uint64 {
+	if size := uint64(len(l.records)); l.nextId > size {
+		return l.nextId - size
+	}
+
+	return 1
+}
+
+func (l *EventLog) slot(id uint64) int {
+	return int(id % uint64(len(l.records)))
+}
@@ -4,25 +4,55 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-mysql-org/go-mysql/canal"
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
 	"github.com/twothicc/canal/config"
 	"github.com/twothicc/canal/handlers/events/kafka"
+	"github.com/twothicc/canal/tools/errorcode"
+	"github.com/twothicc/canal/tools/metrics"
 	"github.com/twothicc/common-go/logger"
 	"go.uber.org/zap"
 )
 
 type SyncEventHandler interface {
 	canal.EventHandler
+	// LastSeq - returns the sequence number of the last message produced
+	LastSeq() uint64
+	// AckedSeq - returns the sequence number up to which all messages were acknowledged
+	AckedSeq() uint64
+	// InFlight - returns the number of messages awaiting acknowledgement
+	InFlight() int
+	// Failed - returns the number of messages kafka rejected
+	Failed() int
+	// Err - returns the error of the first message kafka rejected, after which no more rows
+	// are produced
+	Err() error
+	// Events - returns the log of the latest messages and errors
+	Events() *EventLog
+	// Stats - returns the produced event rates and the time of the latest streamed event
+	Stats() Stats
+}
+
+// Checkpoint - binlog position that is safe to save once every message up to Seq is acknowledged
+type Checkpoint struct {
+	Pos mysql.Position
+	Seq uint64
 }
 
 type syncEventHandler struct {
 	canal.DummyEventHandler
 	ctx         context.Context
 	msgProducer kafka.IMessageProducer
-	syncCh      chan mysql.Position
+	limiter     *rateLimiter
+	events      *EventLog
+	throughput  *throughput
+	binlogName  atomic.Value
+	syncCh      chan Checkpoint
+	pipeline    string
 	serverId    uint32
 }
 
@@ -31,20 +61,36 @@ type CloseEventHandler func() error
 func NewSyncEventHandler(
 	ctx context.Context,
 	kafkaCfg config.KafkaConfig,
+	quotaCfg config.QuotaConfig,
+	eventLogCfg config.EventLogConfig,
 	serverId uint32,
-	syncCh chan mysql.Position,
+	syncCh chan Checkpoint,
 ) (SyncEventHandler, CloseEventHandler, error) {
-	msgProducer, err := kafka.NewMessageProducer(ctx, kafkaCfg)
+	events := NewEventLog(int(eventLogCfg.Size))
+	pipeline := metrics.Pipeline(serverId)
+
+	msgProducer, err := kafka.NewMessageProducer(ctx, kafkaCfg, func(result kafka.ProduceResult) {
+		events.resolve(result)
+		observeProduce(pipeline, result)
+	})
 	if err != nil {
 		return nil, nil, ErrConstructor.Wrap(err)
 	}
 
+	metrics.RegisterSarama(serverId, msgProducer.MetricRegistry())
+
 	return &syncEventHandler{
 			ctx:         ctx,
 			msgProducer: msgProducer,
+			limiter:     newRateLimiter(quotaCfg.MaxEventsPerSecond, quotaCfg.MaxBytesPerSecond),
+			events:      events,
+			throughput:  newThroughput(),
+			pipeline:    pipeline,
 			serverId:    serverId,
 			syncCh:      syncCh,
 		}, func() error {
+			metrics.UnregisterSarama(serverId)
+
 			if closeErr := msgProducer.Close(); closeErr != nil {
 				logger.WithContext(ctx).Error(
 					"[NewSyncEventHandler]fail to close message producer. Possible memory leak",
@@ -63,21 +109,59 @@ func (se *syncEventHandler) OnRotate(e *replication.RotateEvent) error {
 		Pos:  uint32(e.Position),
 	}
 
-	se.syncCh <- pos
+	se.binlogName.Store(pos.Name)
+
+	se.syncCh <- se.checkpoint(pos)
 
 	return se.ctx.Err()
 }
 
 func (se *syncEventHandler) OnDDL(nextPos mysql.Position, _ *replication.QueryEvent) error {
-	se.syncCh <- nextPos
+	se.syncCh <- se.checkpoint(nextPos)
 	return se.ctx.Err()
 }
 
 func (se *syncEventHandler) OnXID(nextPos mysql.Position) error {
-	se.syncCh <- nextPos
+	se.syncCh <- se.checkpoint(nextPos)
 	return se.ctx.Err()
 }
 
+func (se *syncEventHandler) LastSeq() uint64 {
+	return se.msgProducer.LastSeq()
+}
+
+func (se *syncEventHandler) AckedSeq() uint64 {
+	return se.msgProducer.AckedSeq()
+}
+
+func (se *syncEventHandler) InFlight() int {
+	return se.msgProducer.InFlight()
+}
+
+func (se *syncEventHandler) Failed() int {
+	return se.msgProducer.Failed()
+}
+
+func (se *syncEventHandler) Err() error {
+	return se.msgProducer.Err()
+}
+
+func (se *syncEventHandler) Events() *EventLog {
+	return se.events
+}
+
+func (se *syncEventHandler) Stats() Stats {
+	return se.throughput.stats(time.Now())
+}
+
+// checkpoint - pairs pos with the last produced message, which must be acknowledged before pos is saved
+func (se *syncEventHandler) checkpoint(pos mysql.Position) Checkpoint {
+	return Checkpoint{
+		Pos: pos,
+		Seq: se.msgProducer.LastSeq(),
+	}
+}
+
 func (se *syncEventHandler) OnRow(e *canal.RowsEvent) error {
 	logger.WithContext(se.ctx).Info("[SyncEventHandler.OnRow]handling rows event", zap.Uint32("server id", se.serverId))
 
@@ -90,14 +174,116 @@ func (se *syncEventHandler) OnRow(e *canal.RowsEvent) error {
 		return ErrEvent.New("[SyncEventHandler.OnRow]rows event is nil")
 	}
 
+	// rows after a rejected message are produced again once restarted from the checkpoint
+	if produceErr := se.msgProducer.Err(); produceErr != nil {
+		return produceErr
+	}
+
 	msg, err := se.parseRowsEvent(e)
-	if err == nil {
-		se.msgProducer.Produce(se.ctx, msg)
+	if err != nil {
+		se.events.add(se.eventRecord(e, nil, err))
+
+		metrics.ErrorsTotal.WithLabelValues(
+			se.pipeline, metrics.STAGE_PARSE, metrics.Code(errorcode.Parse(err).Code),
+		).Inc()
+
+		return nil
+	}
+
+	// holding back the binlog reader keeps the pipeline within its rate quota
+	if waitErr := se.limiter.Wait(se.ctx, msg.Length()); waitErr != nil {
+		return waitErr
+	}
+
+	// recorded before producing, so the record is there when kafka acknowledges the message
+	se.events.add(se.eventRecord(e, msg, nil))
+
+	se.msgProducer.Produce(se.ctx, msg)
+
+	var eventTime time.Time
+	if e.Header != nil {
+		eventTime = time.Unix(int64(e.Header.Timestamp), 0)
+	}
+
+	se.throughput.add(e.Action, msg.Length(), time.Now(), eventTime)
+
+	metrics.EventsTotal.WithLabelValues(se.pipeline, e.Table.Schema, e.Table.Name, e.Action).Inc()
+
+	if hasTaps() {
+		se.tap(e, msg)
 	}
 
 	return nil
 }
 
+// eventRecord - returns the event log record of msg built from e, or of err if msg could not be built
+func (se *syncEventHandler) eventRecord(e *canal.RowsEvent, msg kafka.IMessage, err error) EventRecord {
+	record := EventRecord{
+		Time:   time.Now(),
+		Schema: e.Table.Schema,
+		Table:  e.Table.Name,
+		Action: e.Action,
+		Result: RESULT_PENDING,
+		msg:    msg,
+	}
+
+	if e.Header != nil {
+		name, _ := se.binlogName.Load().(string)
+
+		record.Pos = mysql.Position{
+			Name: name,
+			Pos:  e.Header.LogPos,
+		}
+	}
+
+	if err != nil {
+		record.Result = RESULT_ERROR
+		record.Error = err.Error()
+
+		return record
+	}
+
+	if encoded, encodeErr := msg.Encode(); encodeErr == nil {
+		record.Message = encoded
+	}
+
+	return record
+}
+
+// observeProduce - records the latency and any error of a produced message of pipeline
+func observeProduce(pipeline string, result kafka.ProduceResult) {
+	metrics.ProduceLatency.WithLabelValues(pipeline).Observe(result.Latency.Seconds())
+
+	if result.Err != nil {
+		metrics.ErrorsTotal.WithLabelValues(
+			pipeline, metrics.STAGE_PRODUCE, metrics.Code(errorcode.Parse(result.Err).Code),
+		).Inc()
+	}
+}
+
+// tap - publishes the encoded msg of e to subscribers tapping this pipeline
+func (se *syncEventHandler) tap(e *canal.RowsEvent, msg kafka.IMessage) {
+	encoded, err := msg.Encode()
+	if err != nil {
+		return
+	}
+
+	event := TapEvent{
+		Time:     time.Now(),
+		Schema:   e.Table.Schema,
+		Table:    e.Table.Name,
+		Action:   e.Action,
+		Message:  encoded,
+		ServerId: se.serverId,
+	}
+
+	if e.Header != nil {
+		event.LogPos = e.Header.LogPos
+	}
+
+	publish(se.serverId, event)
+}
+
 func (se *syncEventHandler) parseRowsEvent(e *canal.RowsEvent) (kafka.IMessage, error) {
 	// parse primary keys
 	pk := []string{}
@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/twothicc/canal/tools/ratelimit"
+)
+
+// rateLimiter - caps the events and bytes per second produced by a pipeline
+//
+// Events are scheduled back to back at the capped rates, so bursts are smoothed out
+type rateLimiter struct {
+	limiter         *ratelimit.Limiter
+	eventsPerSecond uint32
+	bytesPerSecond  uint32
+}
+
+func newRateLimiter(eventsPerSecond, bytesPerSecond uint32) *rateLimiter {
+	return &rateLimiter{
+		limiter:         ratelimit.NewLimiter(),
+		eventsPerSecond: eventsPerSecond,
+		bytesPerSecond:  bytesPerSecond,
+	}
+}
+
+// Wait - blocks until an event of size bytes can be produced within both caps
+func (r *rateLimiter) Wait(ctx context.Context, size int) error {
+	var interval time.Duration
+
+	if r.eventsPerSecond > 0 {
+		interval = time.Second / time.Duration(r.eventsPerSecond)
+	}
+
+	if r.bytesPerSecond > 0 {
+		if byteInterval := time.Duration(int64(size) * int64(time.Second) / int64(r.bytesPerSecond)); byteInterval > interval {
+			interval = byteInterval
+		}
+	}
+
+	return r.limiter.Wait(ctx, interval)
+}
@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate - events and bytes produced per second
+type Rate struct {
+	EventsPerSecond float64
+	BytesPerSecond  float64
+}
+
+// Stats - throughput of a pipeline over the last THROUGHPUT_WINDOW seconds
+//
+// Rates are keyed by action. LastEventTime is the binlog timestamp of the latest streamed rows
+// event, zero until one is streamed as snapshotted rows have none
+type Stats struct {
+	LastEventTime time.Time
+	Rates         map[string]Rate
+}
+
+type rateBucket struct {
+	second int64
+	events uint64
+	bytes  uint64
+}
+
+// throughput - per second counts of the produced events of each action, kept for THROUGHPUT_WINDOW seconds
+type throughput struct {
+	lastEventTime time.Time
+	buckets       map[string][]rateBucket
+	mu            sync.Mutex
+}
+
+func newThroughput() *throughput {
+	return &throughput{
+		buckets: make(map[string][]rateBucket),
+	}
+}
+
+// add - counts an event of action of size bytes produced at now, with the binlog timestamp
+// eventTime, which is zero for snapshotted rows
+func (t *throughput) add(action string, size int, now, eventTime time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !eventTime.IsZero() {
+		t.lastEventTime = eventTime
+	}
+
+	buckets, ok := t.buckets[action]
+	if !ok {
+		// one more bucket for the current, incomplete second
+		buckets = make([]rateBucket, THROUGHPUT_WINDOW+1)
+		t.buckets[action] = buckets
+	}
+
+	second := now.Unix()
+
+	bucket := &buckets[second%int64(len(buckets))]
+	if bucket.second != second {
+		*bucket = rateBucket{second: second}
+	}
+
+	bucket.events++
+	bucket.bytes += uint64(size)
+}
+
+// stats - returns the average rates over the last THROUGHPUT_WINDOW whole seconds before now
+func (t *throughput) stats(now time.Time) Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := Stats{
+		LastEventTime: t.lastEventTime,
+		Rates:         make(map[string]Rate, len(t.buckets)),
+	}
+
+	current := now.Unix()
+
+	for action, buckets := range t.buckets {
+		var events, bytes uint64
+
+		for _, bucket := range buckets {
+			if bucket.second < current && bucket.second >= current-THROUGHPUT_WINDOW {
+				events += bucket.events
+				bytes += bucket.bytes
+			}
+		}
+
+		stats.Rates[action] = Rate{
+			EventsPerSecond: float64(events) / THROUGHPUT_WINDOW,
+			BytesPerSecond:  float64(bytes) / THROUGHPUT_WINDOW,
+		}
+	}
+
+	return stats
+}
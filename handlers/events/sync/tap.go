@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TapEvent - an encoded message as it was produced to kafka
+//
+// LogPos is the binlog position of the rows event, 0 for snapshotted rows
+type TapEvent struct {
+	Time     time.Time
+	Schema   string
+	Table    string
+	Action   string
+	Message  json.RawMessage
+	LogPos   uint32
+	ServerId uint32
+}
+
+// TapFilter - selects the tapped messages of a pipeline, empty fields match every message
+//
+// SampleRate is the fraction of matching messages kept, 0 keeps every message
+type TapFilter struct {
+	Schema     string
+	Table      string
+	Action     string
+	SampleRate float64
+}
+
+// TapSubscription - receives the tapped messages of a pipeline on C
+//
+// Messages are dropped instead of waiting when C is full, so a slow subscriber never holds
+// back the pipeline. C is closed when the subscription is closed
+type TapSubscription struct {
+	C       <-chan TapEvent
+	ch      chan TapEvent
+	filter  TapFilter
+	dropped uint64
+	once    sync.Once
+}
+
+type tapHub struct {
+	subs  map[uint32]map[*TapSubscription]struct{}
+	mu    sync.RWMutex
+	count int32
+}
+
+var taps = &tapHub{
+	subs: make(map[uint32]map[*TapSubscription]struct{}),
+}
+
+// Subscribe - taps the messages produced by the pipeline serverId that match filter
+//
+// Subscriptions outlive restarts of the pipeline and must be closed with Unsubscribe
+func Subscribe(serverId uint32, filter TapFilter, bufferSize int) *TapSubscription {
+	ch := make(chan TapEvent, bufferSize)
+
+	sub := &TapSubscription{
+		C:      ch,
+		ch:     ch,
+		filter: filter,
+	}
+
+	taps.mu.Lock()
+	defer taps.mu.Unlock()
+
+	if taps.subs[serverId] == nil {
+		taps.subs[serverId] = make(map[*TapSubscription]struct{})
+	}
+
+	taps.subs[serverId][sub] = struct{}{}
+
+	atomic.AddInt32(&taps.count, 1)
+
+	return sub
+}
+
+// Unsubscribe - stops tapping messages for sub and closes its channel
+func Unsubscribe(serverId uint32, sub *TapSubscription) {
+	taps.mu.Lock()
+	defer taps.mu.Unlock()
+
+	if _, ok := taps.subs[serverId][sub]; !ok {
+		return
+	}
+
+	delete(taps.subs[serverId], sub)
+
+	if len(taps.subs[serverId]) == 0 {
+		delete(taps.subs, serverId)
+	}
+
+	atomic.AddInt32(&taps.count, -1)
+
+	sub.close()
+}
+
+// CloseTaps - closes every subscription, ending the streams reading from them
+func CloseTaps() {
+	taps.mu.Lock()
+	defer taps.mu.Unlock()
+
+	for serverId, subs := range taps.subs {
+		for sub := range subs {
+			sub.close()
+		}
+
+		delete(taps.subs, serverId)
+	}
+
+	atomic.StoreInt32(&taps.count, 0)
+}
+
+// Dropped - returns the number of matching messages dropped because the subscriber was behind
+func (s *TapSubscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *TapSubscription) close() {
+	s.once.Do(func() {
+		close(s.ch)
+	})
+}
+
+func (s *TapSubscription) matches(event *TapEvent) bool {
+	if s.filter.Schema != "" && s.filter.Schema != event.Schema {
+		return false
+	}
+
+	if s.filter.Table != "" && s.filter.Table != event.Table {
+		return false
+	}
+
+	if s.filter.Action != "" && s.filter.Action != event.Action {
+		return false
+	}
+
+	//nolint:gosec // sampling does not need a secure source
+	return s.filter.SampleRate <= 0 || s.filter.SampleRate >= 1 || rand.Float64() < s.filter.SampleRate
+}
+
+// hasTaps - returns whether anything is tapping, so that pipelines skip building events otherwise
+func hasTaps() bool {
+	return atomic.LoadInt32(&taps.count) > 0
+}
+
+// publish - hands event to the matching subscribers of serverId without blocking
+func publish(serverId uint32, event TapEvent) {
+	taps.mu.RLock()
+	defer taps.mu.RUnlock()
+
+	for sub := range taps.subs[serverId] {
+		if !sub.matches(&event) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package health
+
+import "time"
+
+// Check results
+const (
+	CHECK_OK   = "ok"
+	CHECK_FAIL = "fail"
+)
+
+// Check names
+const (
+	CHECK_HTTP             = "http"
+	CHECK_PIPELINE_STATE   = "pipeline state"
+	CHECK_PIPELINE_LAG     = "pipeline lag"
+	CHECK_CHECKPOINT_STORE = "checkpoint store"
+	CHECK_KAFKA            = "kafka"
+)
+
+const (
+	KAFKA_TARGET_FORMAT = "%s/%s"
+	BROKER_SEPARATOR    = ","
+)
+
+// Readiness checks
+const (
+	DEFAULT_CHECK_TTL = 5 * time.Second
+	CHECK_TIMEOUT     = 5 * time.Second
+)
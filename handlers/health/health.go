@@ -0,0 +1,257 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/domain/entity/syncmanager/savemanager"
+	"github.com/twothicc/canal/handlers/events/kafka"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// NewHealthzHandler - reports that the process is alive and serving HTTP
+func NewHealthzHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(httpcode.HTTP_OK, HealthResponse{
+			Status: CHECK_OK,
+			Checks: []CheckResult{{Name: CHECK_HTTP, Status: CHECK_OK}},
+		})
+	}
+}
+
+// Readiness - runs the readiness checks, caching their results for a short time so that
+// probes and anonymous callers do not dial the store and kafka on every request
+type Readiness struct {
+	cfg            *config.Config
+	syncController synccontroller.SyncController
+	checkedAt      time.Time
+	res            *HealthResponse
+	ttl            time.Duration
+	mu             sync.Mutex
+}
+
+// NewReadiness - creates the readiness checks of the instance, cached per cfg
+func NewReadiness(cfg *config.Config, syncController synccontroller.SyncController) *Readiness {
+	ttl := DEFAULT_CHECK_TTL
+	if cfg.HealthConfig.CheckTtl > 0 {
+		ttl = time.Duration(cfg.HealthConfig.CheckTtl) * time.Millisecond
+	}
+
+	return &Readiness{
+		cfg:            cfg,
+		syncController: syncController,
+		ttl:            ttl,
+	}
+}
+
+// NewReadyzHandler - reports whether the instance is ready, responding 503 if any check failed
+//
+// Only the outcome of each check is reported, as anyone may call it. The detail is reported
+// by NewReadyzDetailHandler
+func NewReadyzHandler(ctx context.Context, readiness *Readiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := readiness.Check(ctx)
+
+		c.JSON(res.statusCode(), res.summary())
+	}
+}
+
+// NewReadyzDetailHandler - reports whether the instance is ready with the detail of each check,
+// responding 503 if any check failed
+func NewReadyzDetailHandler(ctx context.Context, readiness *Readiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := readiness.Check(ctx)
+
+		c.JSON(res.statusCode(), res)
+	}
+}
+
+// Check - returns the results of the readiness checks, run again once they are older than the TTL
+//
+// The instance is not ready when a pipeline failed or lags past the thresholds of cfg, or
+// when the checkpoint store or the kafka of any pipeline is unreachable
+func (r *Readiness) Check(ctx context.Context) *HealthResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.res != nil && time.Since(r.checkedAt) < r.ttl {
+		return r.res
+	}
+
+	// not bound to a request, as the results are shared with other callers
+	checkCtx, cancel := context.WithTimeout(ctx, CHECK_TIMEOUT)
+	defer cancel()
+
+	r.res = r.check(checkCtx)
+	r.checkedAt = time.Now()
+
+	if r.res.Status != CHECK_OK {
+		logger.WithContext(ctx).Warn("[Readiness.Check]not ready", zap.Any("checks", r.res.Checks))
+	}
+
+	return r.res
+}
+
+func (r *Readiness) check(ctx context.Context) *HealthResponse {
+	res := &HealthResponse{
+		Status: CHECK_OK,
+	}
+
+	checkPipelines(r.syncController.Status(), r.cfg.HealthConfig, res)
+
+	var (
+		wg           sync.WaitGroup
+		storeResult  CheckResult
+		kafkaTargets = kafkaConfigs(r.cfg.KafkaConfig, r.syncController.Configs())
+		kafkaResults = make([]CheckResult, len(kafkaTargets))
+	)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		storeResult = checkStore(ctx, r.cfg.StoreConfig)
+	}()
+
+	for idx, kafkaCfg := range kafkaTargets {
+		wg.Add(1)
+
+		go func(idx int, kafkaCfg config.KafkaConfig) {
+			defer wg.Done()
+
+			kafkaResults[idx] = checkKafka(ctx, kafkaCfg)
+		}(idx, kafkaCfg)
+	}
+
+	wg.Wait()
+
+	res.add(storeResult)
+
+	for _, result := range kafkaResults {
+		res.add(result)
+	}
+
+	return res
+}
+
+// checkPipelines - fails pipelines that failed or lag past the thresholds of healthCfg
+func checkPipelines(statuses map[uint32]*syncmanager.Status, healthCfg config.HealthConfig, res *HealthResponse) {
+	ids := make([]uint32, 0, len(statuses))
+	for id := range statuses {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		status := statuses[id]
+
+		stateResult := CheckResult{
+			Name:     CHECK_PIPELINE_STATE,
+			Status:   CHECK_OK,
+			Detail:   string(status.State),
+			Pipeline: id,
+		}
+
+		if status.State == syncmanager.STATE_FAILED {
+			stateResult.Status = CHECK_FAIL
+
+			if status.LastError != nil {
+				stateResult.Detail = fmt.Sprintf("%s: %s", status.State, status.LastError.Msg)
+			}
+		}
+
+		res.add(stateResult)
+
+		if status.IsRunning && status.Replication != nil {
+			res.add(checkLag(id, status.Replication, healthCfg))
+		}
+	}
+}
+
+// checkLag - fails a pipeline past either lag threshold, MaxLagSeconds only counting while
+// there is binlog left to read
+func checkLag(id uint32, replication *syncmanager.Replication, healthCfg config.HealthConfig) CheckResult {
+	result := CheckResult{
+		Name:     CHECK_PIPELINE_LAG,
+		Status:   CHECK_OK,
+		Detail:   fmt.Sprintf("%d bytes, %.0f seconds", replication.LagBytes, replication.LagSeconds),
+		Pipeline: id,
+	}
+
+	if healthCfg.MaxLagBytes > 0 && replication.LagBytes > healthCfg.MaxLagBytes {
+		result.Status = CHECK_FAIL
+		result.Detail = fmt.Sprintf("%d bytes behind, exceeds %d bytes", replication.LagBytes, healthCfg.MaxLagBytes)
+	}
+
+	if healthCfg.MaxLagSeconds > 0 && replication.LagBytes > 0 &&
+		replication.LagSeconds > float64(healthCfg.MaxLagSeconds) {
+		result.Status = CHECK_FAIL
+		result.Detail = fmt.Sprintf("%.0f seconds behind, exceeds %d seconds", replication.LagSeconds, healthCfg.MaxLagSeconds)
+	}
+
+	return result
+}
+
+func checkStore(ctx context.Context, storeCfg config.StoreConfig) CheckResult {
+	if err := savemanager.Ping(ctx, storeCfg); err != nil {
+		return CheckResult{Name: CHECK_CHECKPOINT_STORE, Status: CHECK_FAIL, Detail: err.Error()}
+	}
+
+	return CheckResult{Name: CHECK_CHECKPOINT_STORE, Status: CHECK_OK}
+}
+
+func checkKafka(ctx context.Context, kafkaCfg config.KafkaConfig) CheckResult {
+	result := CheckResult{
+		Name:   CHECK_KAFKA,
+		Status: CHECK_OK,
+		Detail: kafkaTarget(kafkaCfg),
+	}
+
+	if err := kafka.CheckTopic(ctx, kafkaCfg); err != nil {
+		result.Status = CHECK_FAIL
+		result.Detail = fmt.Sprintf("%s: %s", result.Detail, err.Error())
+	}
+
+	return result
+}
+
+// kafkaConfigs - returns the distinct brokers and topics of the defaults and of each pipeline
+func kafkaConfigs(defaults config.KafkaConfig, configs map[uint32]config.Config) []config.KafkaConfig {
+	targets := map[string]config.KafkaConfig{
+		kafkaTarget(defaults): defaults,
+	}
+
+	for _, cfg := range configs {
+		targets[kafkaTarget(cfg.KafkaConfig)] = cfg.KafkaConfig
+	}
+
+	keys := make([]string, 0, len(targets))
+	for key := range targets {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	res := make([]config.KafkaConfig, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, targets[key])
+	}
+
+	return res
+}
+
+func kafkaTarget(kafkaCfg config.KafkaConfig) string {
+	return fmt.Sprintf(KAFKA_TARGET_FORMAT, strings.Join(kafkaCfg.BrokerList, BROKER_SEPARATOR), kafkaCfg.Topic)
+}
@@ -0,0 +1,54 @@
+package health
+
+import "github.com/twothicc/canal/tools/httpcode"
+
+// CheckResult - outcome of a single health check
+//
+// Pipeline is set for checks of a single pipeline. Detail may name brokers and errors, so it
+// is only reported to authenticated callers
+type CheckResult struct {
+	Name     string
+	Status   string
+	Detail   string `json:",omitempty"`
+	Pipeline uint32 `json:",omitempty"`
+}
+
+// HealthResponse - outcome of every health check, Status is CHECK_FAIL if any check failed
+type HealthResponse struct {
+	Status string
+	Checks []CheckResult
+}
+
+func (r *HealthResponse) add(result CheckResult) {
+	r.Checks = append(r.Checks, result)
+
+	if result.Status == CHECK_FAIL {
+		r.Status = CHECK_FAIL
+	}
+}
+
+// summary - returns the outcome of each check of r without its detail
+func (r *HealthResponse) summary() *HealthResponse {
+	res := &HealthResponse{
+		Status: r.Status,
+		Checks: make([]CheckResult, 0, len(r.Checks)),
+	}
+
+	for _, check := range r.Checks {
+		res.Checks = append(res.Checks, CheckResult{
+			Name:     check.Name,
+			Status:   check.Status,
+			Pipeline: check.Pipeline,
+		})
+	}
+
+	return res
+}
+
+func (r *HealthResponse) statusCode() int {
+	if r.Status == CHECK_OK {
+		return httpcode.HTTP_OK
+	}
+
+	return httpcode.HTTP_SERVICE_UNAVAILABLE
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	canalmetrics "github.com/twothicc/canal/tools/metrics"
+)
+
+// pipelineCollector - reads the replication progress of each pipeline from its status when scraped
+type pipelineCollector struct {
+	syncController synccontroller.SyncController
+	running        *prometheus.Desc
+	lagSeconds     *prometheus.Desc
+	lagBytes       *prometheus.Desc
+	inFlight       *prometheus.Desc
+}
+
+func newPipelineCollector(syncController synccontroller.SyncController) *pipelineCollector {
+	labels := []string{canalmetrics.LABEL_PIPELINE}
+
+	return &pipelineCollector{
+		syncController: syncController,
+		running: prometheus.NewDesc(
+			prometheus.BuildFQName(canalmetrics.NAMESPACE, "", "pipeline_running"),
+			"Whether the pipeline is snapshotting or streaming.",
+			labels, nil,
+		),
+		lagSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(canalmetrics.NAMESPACE, "", "replication_lag_seconds"),
+			"Seconds since the binlog timestamp of the latest streamed event.",
+			labels, nil,
+		),
+		lagBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(canalmetrics.NAMESPACE, "", "replication_lag_bytes"),
+			"Bytes of binlog between the pipeline and the latest sampled source position.",
+			labels, nil,
+		),
+		inFlight: prometheus.NewDesc(
+			prometheus.BuildFQName(canalmetrics.NAMESPACE, "", "kafka_in_flight"),
+			"Messages produced to kafka and not yet acknowledged.",
+			labels, nil,
+		),
+	}
+}
+
+func (p *pipelineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.running
+	ch <- p.lagSeconds
+	ch <- p.lagBytes
+	ch <- p.inFlight
+}
+
+func (p *pipelineCollector) Collect(ch chan<- prometheus.Metric) {
+	for id, status := range p.syncController.Status() {
+		pipeline := canalmetrics.Pipeline(id)
+
+		running := 0.0
+		if status.IsRunning {
+			running = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(p.running, prometheus.GaugeValue, running, pipeline)
+
+		if replication := status.Replication; replication != nil {
+			ch <- prometheus.MustNewConstMetric(p.lagSeconds, prometheus.GaugeValue, replication.LagSeconds, pipeline)
+			ch <- prometheus.MustNewConstMetric(p.lagBytes, prometheus.GaugeValue, float64(replication.LagBytes), pipeline)
+			ch <- prometheus.MustNewConstMetric(p.inFlight, prometheus.GaugeValue, float64(replication.InFlight), pipeline)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	canalmetrics "github.com/twothicc/canal/tools/metrics"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// NewMetricsHandler - serves the metrics of every pipeline and of the Go runtime to Prometheus
+//
+// Replication lag, in-flight messages and whether each pipeline runs are read from
+// syncController when scraped
+func NewMetricsHandler(ctx context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	if err := canalmetrics.Register(newPipelineCollector(syncController)); err != nil {
+		logger.WithContext(ctx).Error("[NewMetricsHandler]fail to register pipeline metrics", zap.Error(err))
+	}
+
+	handler := canalmetrics.Handler()
+
+	return func(c *gin.Context) {
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
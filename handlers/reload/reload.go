@@ -0,0 +1,21 @@
+package reload
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/configreloader"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+type ReloadStatusResponse struct {
+	Status *configreloader.ReloadStatus
+}
+
+// NewReloadStatusHandler - GET /config/reload-status, reports the result of the latest reload
+// of the config file
+func NewReloadStatusHandler(configReloader configreloader.ConfigReloader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(httpcode.HTTP_OK, ReloadStatusResponse{
+			Status: configReloader.Status(),
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package sync
+
+import "time"
+
+const (
+	ID_PARAM     = "id"
+	SCHEMA_QUERY = "schema"
+	TABLE_QUERY  = "table"
+	ACTION_QUERY = "action"
+	SAMPLE_QUERY = "sample"
+	BEFORE_QUERY = "before"
+	LIMIT_QUERY  = "limit"
+)
+
+const (
+	BASE10 = 10
+	BIT32  = 32
+	BIT64  = 64
+)
+
+const (
+	DEFAULT_EVENTS_LIMIT = 50
+	MAX_EVENTS_LIMIT     = 1000
+)
+
+const (
+	// TAIL_BUFFER_SIZE - messages held for a tail client before further messages are dropped
+	TAIL_BUFFER_SIZE = 256
+	// TAIL_HEARTBEAT_INTERVAL - how often a tail client is sent the number of dropped messages
+	TAIL_HEARTBEAT_INTERVAL = 15 * time.Second
+	CONTENT_TYPE_HEADER     = "Content-Type"
+	CACHE_CONTROL_HEADER    = "Cache-Control"
+	SSE_CONTENT_TYPE        = "text/event-stream"
+	NO_CACHE                = "no-cache"
+	TAIL_EVENT              = "message"
+	TAIL_HEARTBEAT_EVENT    = "heartbeat"
+)
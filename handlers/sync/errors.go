@@ -0,0 +1,13 @@
+package sync
+
+import (
+	"github.com/twothicc/common-go/errortype"
+)
+
+const pkg = "handlers/sync"
+
+//nolint:gomnd // error code
+var (
+	ErrParam    = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrNotFound = errortype.ErrorType{Code: 2, Pkg: pkg}
+)
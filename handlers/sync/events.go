@@ -0,0 +1,82 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// NewEventsHandler - GET /sync/{id}/events, pages through the latest messages and errors of a
+// pipeline, newest first
+//
+// The before query is the Next cursor of the previous page, limit the page size
+func NewEventsHandler(ctx context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := parseId(syncController, c.Param(ID_PARAM))
+		if err != nil {
+			if abortErr := c.AbortWithError(errorStatus(err, httpcode.HTTP_BAD_REQUEST), err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewEventsHandler]fail to abort after invalid pipeline id", zap.Error(err))
+			}
+
+			return
+		}
+
+		before, limit, err := parsePage(c)
+		if err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewEventsHandler]fail to abort after invalid page", zap.Error(err))
+			}
+
+			return
+		}
+
+		page, err := syncController.Events(id, before, limit)
+		if err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_NOT_FOUND, err); abortErr != nil {
+				logger.WithContext(ctx).Error(
+					"[NewEventsHandler]fail to abort after failed events lookup",
+					zap.Error(err),
+					zap.Uint32("server id", id),
+				)
+			}
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, EventsResponse{
+			ServerId: id,
+			Page:     page,
+		})
+	}
+}
+
+// parsePage - parses the before cursor and the limit, capped at MAX_EVENTS_LIMIT
+func parsePage(c *gin.Context) (before uint64, limit int, err error) {
+	limit = DEFAULT_EVENTS_LIMIT
+
+	if rawBefore := c.Query(BEFORE_QUERY); rawBefore != "" {
+		before, err = strconv.ParseUint(rawBefore, BASE10, BIT64)
+		if err != nil {
+			return 0, 0, ErrParam.New(fmt.Sprintf("[sync.parsePage]invalid before %q", rawBefore))
+		}
+	}
+
+	if rawLimit := c.Query(LIMIT_QUERY); rawLimit != "" {
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			return 0, 0, ErrParam.New(fmt.Sprintf("[sync.parsePage]invalid limit %q", rawLimit))
+		}
+	}
+
+	if limit > MAX_EVENTS_LIMIT {
+		limit = MAX_EVENTS_LIMIT
+	}
+
+	return before, limit, nil
+}
@@ -0,0 +1,24 @@
+package sync
+
+import (
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+// errorStatus - returns the HTTP status for err, rejecting requests over quota with 429 and
+// requests racing another change of the pipeline with 409
+func errorStatus(err error, defaultStatus int) int {
+	switch {
+	case synccontroller.ErrQuota.Is(err), syncmanager.ErrQuota.Is(err):
+		return httpcode.HTTP_TOO_MANY_REQUESTS
+	case synccontroller.ErrBusy.Is(err), synccontroller.ErrExists.Is(err):
+		return httpcode.HTTP_CONFLICT
+	case ErrNotFound.Is(err):
+		return httpcode.HTTP_NOT_FOUND
+	case ErrParam.Is(err):
+		return httpcode.HTTP_BAD_REQUEST
+	default:
+		return defaultStatus
+	}
+}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+func NewPauseHandler(ctx context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PauseRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewPauseHandler]fail to abort after failed JSON bind", zap.Error(err))
+			}
+
+			return
+		}
+
+		drain, err := syncController.Pause(ctx, req.ServerId)
+		if err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
+				logger.WithContext(ctx).Error(
+					"[NewPauseHandler]fail to abort after failed syncmanager pause",
+					zap.Error(err),
+					zap.Uint32("server id", req.ServerId),
+				)
+			}
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, PauseResponse{
+			ServerId: req.ServerId,
+			Drain:    drain,
+			Msg:      fmt.Sprintf("server %d successfully paused", req.ServerId),
+		})
+	}
+}
@@ -1,15 +1,62 @@
 package sync
 
-import "github.com/twothicc/canal/config"
+import (
+	"fmt"
 
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/tools/secret"
+)
+
+// RunRequest - settings of a new pipeline, merged onto the defaults in conf/app.toml
+//
+// Unset fields keep their defaults. Pass is a secret reference, env:NAME or file:/path, and is
+// required when Addr or User differ from the defaults.
+// StartPosition, if set, is the binlog position to stream from and cannot be combined with
+// IsLegacySync, which snapshots existing records first
 type RunRequest struct {
-	Addr    string
-	User    string
-	Pass    string
-	Charset string
-	flavor  string
-	Sources []config.SourceConfig
-	Kafka   config.KafkaConfig
+	StartPosition *mysql.Position
+	Addr          string
+	User          string
+	Pass          string
+	Charset       string
+	Flavor        string
+	Sources       []config.SourceConfig
+	Kafka         config.KafkaConfig
+	IsLegacySync  bool
+}
+
+// pipelineConfig - returns the config of the requested pipeline, leaving defaults unchanged
+func (r *RunRequest) pipelineConfig(defaults *config.Config) *config.Config {
+	return defaults.Pipeline(
+		config.DbConfig{
+			Addr:    r.Addr,
+			User:    r.User,
+			Pass:    r.Pass,
+			Charset: r.Charset,
+			Flavor:  r.Flavor,
+		},
+		r.Kafka,
+		r.Sources,
+	)
+}
+
+// validate - checks that the requested options can be combined, and that a pass is given if
+// the login differs from the defaults
+func (r *RunRequest) validate(defaults *config.Config) error {
+	if r.StartPosition != nil && r.IsLegacySync {
+		return ErrParam.New("[RunRequest.validate]StartPosition cannot be combined with IsLegacySync")
+	}
+
+	if err := secret.Validate(r.Pass); err != nil {
+		return ErrParam.New(fmt.Sprintf("[RunRequest.validate]invalid Pass: %s", err.Error()))
+	}
+
+	if err := defaults.CheckLogin(config.DbConfig{Addr: r.Addr, User: r.User, Pass: r.Pass}); err != nil {
+		return ErrParam.New(fmt.Sprintf("[RunRequest.validate]%s", err.Error()))
+	}
+
+	return nil
 }
 
 type StopRequest struct {
@@ -19,3 +66,17 @@ type StopRequest struct {
 type DeleteRequest struct {
 	ServerId uint32
 }
+
+type PauseRequest struct {
+	ServerId uint32
+}
+
+type ResumeRequest struct {
+	ServerId uint32
+}
+
+type UpdateRequest struct {
+	Sources    []config.SourceConfig
+	ServerId   uint32
+	IsBackfill bool
+}
@@ -1,6 +1,10 @@
 package sync
 
-import "github.com/twothicc/canal/domain/entity/syncmanager"
+import (
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	eventsync "github.com/twothicc/canal/handlers/events/sync"
+)
 
 type RunResponse struct {
 	Msg      string
@@ -8,6 +12,7 @@ type RunResponse struct {
 }
 
 type StopResponse struct {
+	Drain    *syncmanager.DrainResult
 	Msg      string
 	ServerId uint32
 }
@@ -17,6 +22,32 @@ type DeleteResponse struct {
 	ServerId uint32
 }
 
+type PauseResponse struct {
+	Drain    *syncmanager.DrainResult
+	Msg      string
+	ServerId uint32
+}
+
+type ResumeResponse struct {
+	Msg      string
+	ServerId uint32
+}
+
+type UpdateResponse struct {
+	Msg      string
+	ServerId uint32
+}
+
 type StatusResponse struct {
 	Statuses map[uint32]syncmanager.Status
+	Cluster  *synccontroller.ClusterStatus
+}
+
+type EventsResponse struct {
+	Page     *eventsync.EventPage
+	ServerId uint32
+}
+
+type ValidateResponse struct {
+	Report *syncmanager.ValidationReport
 }
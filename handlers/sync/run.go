@@ -7,12 +7,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/twothicc/canal/config"
 	"github.com/twothicc/canal/domain/entity/synccontroller"
-	"github.com/twothicc/canal/domain/entity/syncmanager"
 	"github.com/twothicc/canal/tools/httpcode"
 	"github.com/twothicc/common-go/logger"
 	"go.uber.org/zap"
 )
 
+// NewRunHandler - creates and starts a pipeline from the request merged onto the defaults in cfg
+//
+// cfg is shared by every pipeline and is not modified
 func NewRunHandler(
 	ctx context.Context,
 	cfg *config.Config,
@@ -29,22 +31,20 @@ func NewRunHandler(
 			return
 		}
 
-		cfg.DbConfig.Addr = req.Addr
-		cfg.DbConfig.User = req.User
-		cfg.DbConfig.Pass = req.Pass
-		cfg.DbConfig.Charset = req.Charset
-		cfg.DbConfig.Flavor = req.flavor
-
-		cfg.KafkaConfig = req.Kafka
+		if err := req.validate(cfg); err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewRunHandler]fail to abort after invalid request", zap.Error(err))
+			}
 
-		cfg.Sources = req.Sources
+			return
+		}
 
-		syncManager, err := syncmanager.NewSyncManager(
+		syncManager, err := syncController.NewSyncManager(
 			ctx,
-			cfg,
+			req.pipelineConfig(cfg),
 		)
 		if err != nil {
-			if abortErr := c.AbortWithError(httpcode.HTTP_INTERNAL_SERVER_ERROR, err); abortErr != nil {
+			if abortErr := c.AbortWithError(errorStatus(err, httpcode.HTTP_INTERNAL_SERVER_ERROR), err); abortErr != nil {
 				logger.WithContext(ctx).Error(
 					"[NewRunHandler]fail to abort after failed syncmanager creation",
 					zap.Error(err),
@@ -54,9 +54,37 @@ func NewRunHandler(
 			return
 		}
 
-		syncController.Add(ctx, syncManager.GetId(), syncManager)
+		if req.StartPosition != nil {
+			if err := syncManager.SetStartPosition(*req.StartPosition); err != nil {
+				syncManager.Close()
+
+				if abortErr := c.AbortWithError(httpcode.HTTP_INTERNAL_SERVER_ERROR, err); abortErr != nil {
+					logger.WithContext(ctx).Error(
+						"[NewRunHandler]fail to abort after failed start position",
+						zap.Error(err),
+						zap.Uint32("server id", syncManager.GetId()),
+					)
+				}
+
+				return
+			}
+		}
+
+		if err := syncController.Add(ctx, syncManager.GetId(), syncManager); err != nil {
+			syncManager.Close()
+
+			if abortErr := c.AbortWithError(errorStatus(err, httpcode.HTTP_INTERNAL_SERVER_ERROR), err); abortErr != nil {
+				logger.WithContext(ctx).Error(
+					"[NewRunHandler]fail to abort after failed syncmanager add",
+					zap.Error(err),
+					zap.Uint32("server id", syncManager.GetId()),
+				)
+			}
+
+			return
+		}
 
-		if err := syncController.Start(ctx, syncManager.GetId(), false); err != nil {
+		if err := syncController.Start(ctx, syncManager.GetId(), req.IsLegacySync); err != nil {
 			if abortErr := c.AbortWithError(httpcode.HTTP_INTERNAL_SERVER_ERROR, err); abortErr != nil {
 				logger.WithContext(ctx).Error(
 					"[NewRunHandler]fail to abort after failed syncmanager start",
@@ -20,6 +20,7 @@ func NewStatusHandler(ctx context.Context, syncController synccontroller.SyncCon
 
 		c.JSON(httpcode.HTTP_OK, StatusResponse{
 			Statuses: respData,
+			Cluster:  syncController.Cluster(),
 		})
 	}
 }
@@ -23,7 +23,8 @@ func NewStopHandler(ctx context.Context, syncController synccontroller.SyncContr
 			return
 		}
 
-		if err := syncController.Stop(ctx, req.ServerId); err != nil {
+		drain, err := syncController.Stop(ctx, req.ServerId)
+		if err != nil {
 			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
 				logger.WithContext(ctx).Error(
 					"[NewStopHandler]fail to abort after failed syncmanager stop",
@@ -37,6 +38,7 @@ func NewStopHandler(ctx context.Context, syncController synccontroller.SyncContr
 
 		c.JSON(httpcode.HTTP_OK, StopResponse{
 			ServerId: req.ServerId,
+			Drain:    drain,
 			Msg:      fmt.Sprintf("server %d successfully stopped", req.ServerId),
 		})
 	}
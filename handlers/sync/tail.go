@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	eventsync "github.com/twothicc/canal/handlers/events/sync"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// TailHeartbeat - sent to tail clients periodically, with how many messages they were too slow to receive
+type TailHeartbeat struct {
+	Dropped uint64
+}
+
+// NewTailHandler - GET /sync/{id}/tail, streams the messages a pipeline produces as server-sent events
+//
+// Messages can be filtered by the schema, table and action queries and sampled by the sample
+// query, a fraction between 0 and 1. Messages a client is too slow to receive are dropped
+func NewTailHandler(ctx context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := parseId(syncController, c.Param(ID_PARAM))
+		if err != nil {
+			if abortErr := c.AbortWithError(errorStatus(err, httpcode.HTTP_BAD_REQUEST), err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewTailHandler]fail to abort after invalid pipeline id", zap.Error(err))
+			}
+
+			return
+		}
+
+		filter, err := parseTapFilter(c)
+		if err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewTailHandler]fail to abort after invalid filter", zap.Error(err))
+			}
+
+			return
+		}
+
+		sub := eventsync.Subscribe(id, filter, TAIL_BUFFER_SIZE)
+		defer eventsync.Unsubscribe(id, sub)
+
+		logger.WithContext(ctx).Info(
+			"[NewTailHandler]tailing pipeline",
+			zap.Uint32("server id", id),
+			zap.String("client ip", c.ClientIP()),
+			zap.Any("filter", filter),
+		)
+
+		// opens the stream before the first message, which may be a while
+		c.Header(CONTENT_TYPE_HEADER, SSE_CONTENT_TYPE)
+		c.Header(CACHE_CONTROL_HEADER, NO_CACHE)
+		c.Status(httpcode.HTTP_OK)
+		c.Writer.Flush()
+
+		ticker := time.NewTicker(TAIL_HEARTBEAT_INTERVAL)
+		defer ticker.Stop()
+
+		c.Stream(func(_ io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case event, ok := <-sub.C:
+				if !ok {
+					return false
+				}
+
+				c.SSEvent(TAIL_EVENT, event)
+
+				return true
+			case <-ticker.C:
+				c.SSEvent(TAIL_HEARTBEAT_EVENT, TailHeartbeat{
+					Dropped: sub.Dropped(),
+				})
+
+				return true
+			}
+		})
+
+		logger.WithContext(ctx).Info(
+			"[NewTailHandler]stopped tailing pipeline",
+			zap.Uint32("server id", id),
+			zap.String("client ip", c.ClientIP()),
+			zap.Uint64("dropped", sub.Dropped()),
+		)
+	}
+}
+
+func parseTapFilter(c *gin.Context) (eventsync.TapFilter, error) {
+	filter := eventsync.TapFilter{
+		Schema: c.Query(SCHEMA_QUERY),
+		Table:  c.Query(TABLE_QUERY),
+		Action: c.Query(ACTION_QUERY),
+	}
+
+	switch filter.Action {
+	case "", eventsync.INSERT, eventsync.UPDATE, eventsync.DELETE:
+	default:
+		return filter, ErrParam.New(fmt.Sprintf("[sync.parseTapFilter]invalid action %q", filter.Action))
+	}
+
+	if rawSample := c.Query(SAMPLE_QUERY); rawSample != "" {
+		sample, err := strconv.ParseFloat(rawSample, BIT64)
+		if err != nil || sample <= 0 || sample > 1 {
+			return filter, ErrParam.New(fmt.Sprintf("[sync.parseTapFilter]sample %q is not in (0, 1]", rawSample))
+		}
+
+		filter.SampleRate = sample
+	}
+
+	return filter, nil
+}
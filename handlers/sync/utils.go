@@ -0,0 +1,22 @@
+package sync
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+)
+
+// parseId - parses a pipeline id path parameter of a pipeline that exists
+func parseId(syncController synccontroller.SyncController, rawId string) (uint32, error) {
+	id, err := strconv.ParseUint(rawId, BASE10, BIT32)
+	if err != nil || id == 0 {
+		return 0, ErrParam.New(fmt.Sprintf("[sync.parseId]invalid pipeline id %q", rawId))
+	}
+
+	if _, ok := syncController.Status()[uint32(id)]; !ok {
+		return 0, ErrNotFound.New(fmt.Sprintf("[sync.parseId]pipeline %d does not exist", id))
+	}
+
+	return uint32(id), nil
+}
@@ -0,0 +1,31 @@
+package sync
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// NewValidateHandler - dry runs a run request, reporting every check without creating a pipeline
+func NewValidateHandler(ctx context.Context, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RunRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			if abortErr := c.AbortWithError(httpcode.HTTP_BAD_REQUEST, err); abortErr != nil {
+				logger.WithContext(ctx).Error("[NewValidateHandler]fail to abort after failed JSON bind", zap.Error(err))
+			}
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, ValidateResponse{
+			Report: syncmanager.Validate(ctx, req.pipelineConfig(cfg)),
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+// NewActionHandler - POST /v1/pipelines/{id}:start and POST /v1/pipelines/{id}:stop
+//
+// gin cannot match a literal suffix within a path segment, so the action is split off the id
+func NewActionHandler(ctx context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawId, action, _ := strings.Cut(c.Param(ID_PARAM), ACTION_SEPARATOR)
+
+		id, err := parseId(rawId)
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		if _, err := lookup(syncController, id); err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		var (
+			drain *syncmanager.DrainResult
+			msg   string
+		)
+
+		switch action {
+		case ACTION_START:
+			err = syncController.Start(ctx, id, false)
+			msg = fmt.Sprintf("pipeline %d successfully started", id)
+		case ACTION_STOP:
+			drain, err = syncController.Stop(ctx, id)
+			msg = fmt.Sprintf("pipeline %d successfully stopped", id)
+		default:
+			err = ErrNotFound.New(fmt.Sprintf("[NewActionHandler]unknown action %q", action))
+		}
+
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, ActionResponse{
+			Drain:    drain,
+			ServerId: id,
+			Msg:      msg,
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package v1
+
+const (
+	ID_PARAM         = "id"
+	ACTION_SEPARATOR = ":"
+	ACTION_START     = "start"
+	ACTION_STOP      = "stop"
+)
+
+const (
+	BASE10 = 10
+	BIT32  = 32
+)
@@ -0,0 +1,39 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+// NewDeleteHandler - DELETE /v1/pipelines/{id}, stops and removes a pipeline
+func NewDeleteHandler(ctx context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := parseId(c.Param(ID_PARAM))
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		if _, err := lookup(syncController, id); err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		if err := syncController.Remove(ctx, id); err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, ActionResponse{
+			ServerId: id,
+			Msg:      fmt.Sprintf("pipeline %d successfully deleted", id),
+		})
+	}
+}
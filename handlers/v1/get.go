@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+// NewGetHandler - GET /v1/pipelines/{id}
+func NewGetHandler(_ context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := parseId(c.Param(ID_PARAM))
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		status, err := lookup(syncController, id)
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, PipelineResponse{
+			Pipeline: *status,
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+// NewListHandler - GET /v1/pipelines, lists pipelines ordered by id
+func NewListHandler(_ context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses := syncController.Status()
+
+		pipelines := make([]syncmanager.Status, 0, len(statuses))
+		for _, status := range statuses {
+			pipelines = append(pipelines, *status)
+		}
+
+		sort.Slice(pipelines, func(i, j int) bool {
+			return pipelines[i].ServerId < pipelines[j].ServerId
+		})
+
+		c.JSON(httpcode.HTTP_OK, ListResponse{
+			Pipelines: pipelines,
+		})
+	}
+}
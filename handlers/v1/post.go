@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// NewPostHandler - POST /v1/pipelines, creates a stopped pipeline with an id assigned by the server
+//
+// New pipelines start from the defaults in cfg, which is not modified
+func NewPostHandler(
+	ctx context.Context,
+	cfg *config.Config,
+	syncController synccontroller.SyncController,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PipelineRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abort(c, ErrParam.Wrap(err))
+
+			return
+		}
+
+		if err := req.validate(true); err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		id, err := create(ctx, cfg, syncController, &req)
+		if err != nil {
+			logger.WithContext(ctx).Error("[NewPostHandler]fail to create pipeline", zap.Error(err))
+			abort(c, err)
+
+			return
+		}
+
+		status, err := lookup(syncController, id)
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_CREATED, PipelineResponse{
+			Pipeline: *status,
+		})
+	}
+}
+
+// create - creates and registers a stopped pipeline from req, returning its id
+func create(
+	ctx context.Context,
+	cfg *config.Config,
+	syncController synccontroller.SyncController,
+	req *PipelineRequest,
+) (uint32, error) {
+	db := config.DbConfig{
+		Addr:    req.Addr,
+		User:    req.User,
+		Pass:    req.Pass,
+		Charset: req.Charset,
+		Flavor:  req.Flavor,
+	}
+
+	if err := cfg.CheckLogin(db); err != nil {
+		return 0, ErrParam.New(fmt.Sprintf("[v1.create]%s", err.Error()))
+	}
+
+	pipelineCfg := cfg.Pipeline(db, req.Kafka, req.Sources)
+
+	manager, err := syncController.NewSyncManager(ctx, pipelineCfg)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := syncController.Add(ctx, manager.GetId(), manager); err != nil {
+		manager.Close()
+
+		return 0, err
+	}
+
+	return manager.GetId(), nil
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// NewPutHandler - PUT /v1/pipelines/{id}, replaces the sources of an existing pipeline
+//
+// Pipelines are created with POST /v1/pipelines, as ids are assigned by the server
+func NewPutHandler(ctx context.Context, syncController synccontroller.SyncController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := parseId(c.Param(ID_PARAM))
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		var req PipelineRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abort(c, ErrParam.Wrap(err))
+
+			return
+		}
+
+		if _, err := lookup(syncController, id); err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		if err := req.validate(false); err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		if err := syncController.Update(ctx, id, req.Sources, req.IsBackfill); err != nil {
+			logger.WithContext(ctx).Error("[NewPutHandler]fail to put pipeline", zap.Uint32("server id", id), zap.Error(err))
+			abort(c, err)
+
+			return
+		}
+
+		status, err := lookup(syncController, id)
+		if err != nil {
+			abort(c, err)
+
+			return
+		}
+
+		c.JSON(httpcode.HTTP_OK, PipelineResponse{
+			Pipeline: *status,
+		})
+	}
+}
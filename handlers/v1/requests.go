@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"fmt"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/tools/secret"
+)
+
+// PipelineRequest - desired definition of a pipeline
+//
+// Connection and kafka settings are only used by POST to create a pipeline, PUT only
+// replaces the sources of an existing one. Pass is a secret reference, env:NAME or file:/path,
+// and is required when Addr or User differ from the defaults
+type PipelineRequest struct {
+	Addr       string
+	User       string
+	Pass       string
+	Charset    string
+	Flavor     string
+	Sources    []config.SourceConfig `binding:"required,min=1"`
+	Kafka      config.KafkaConfig
+	IsBackfill bool
+}
+
+// validate - checks the sources, and the connection and kafka settings if isCreate
+func (r *PipelineRequest) validate(isCreate bool) error {
+	for _, source := range r.Sources {
+		if source.Schema == "" || len(source.Tables) == 0 {
+			return ErrParam.New("[PipelineRequest.validate]sources need a schema and tables")
+		}
+	}
+
+	if !isCreate {
+		return nil
+	}
+
+	if err := secret.Validate(r.Pass); err != nil {
+		return ErrParam.New(fmt.Sprintf("[PipelineRequest.validate]invalid Pass: %s", err.Error()))
+	}
+
+	if r.Addr == "" || r.User == "" {
+		return ErrParam.New("[PipelineRequest.validate]Addr and User are required to create a pipeline")
+	}
+
+	if r.Kafka.Topic == "" || len(r.Kafka.BrokerList) == 0 {
+		return ErrParam.New(fmt.Sprintf(
+			"[PipelineRequest.validate]Kafka topic and broker list are required to create a pipeline, got %+v",
+			r.Kafka,
+		))
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package v1
+
+import "github.com/twothicc/canal/domain/entity/syncmanager"
+
+type ListResponse struct {
+	Pipelines []syncmanager.Status
+}
+
+type PipelineResponse struct {
+	Pipeline syncmanager.Status
+}
+
+type ActionResponse struct {
+	Drain    *syncmanager.DrainResult
+	Msg      string
+	ServerId uint32
+}
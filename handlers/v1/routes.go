@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/canal/tools/openapi"
+)
+
+// Route - a /v1 route along with its OpenAPI description
+//
+// GinPath is the path the handler is registered on. Routes sharing a method and GinPath
+// share a handler, which is only registered once
+type Route struct {
+	openapi.Operation
+	Handler gin.HandlerFunc
+	GinPath string
+}
+
+// NewRoutes - returns the /v1 routes, which the router registers and documents
+func NewRoutes(ctx context.Context, cfg *config.Config, syncController synccontroller.SyncController) []Route {
+	actionHandler := NewActionHandler(ctx, syncController)
+
+	return []Route{
+		{
+			Operation: openapi.Operation{
+				Method:   http.MethodGet,
+				Path:     "/v1/pipelines",
+				Summary:  "Lists pipelines",
+				Response: ListResponse{},
+				Statuses: []int{httpcode.HTTP_OK},
+			},
+			GinPath: "/v1/pipelines",
+			Handler: NewListHandler(ctx, syncController),
+		},
+		{
+			Operation: openapi.Operation{
+				Method:   http.MethodPost,
+				Path:     "/v1/pipelines",
+				Summary:  "Creates a stopped pipeline with an id assigned by the server",
+				Request:  PipelineRequest{},
+				Response: PipelineResponse{},
+				Statuses: []int{httpcode.HTTP_CREATED},
+				Errors: []int{
+					httpcode.HTTP_BAD_REQUEST,
+					httpcode.HTTP_CONFLICT,
+					httpcode.HTTP_TOO_MANY_REQUESTS,
+					httpcode.HTTP_INTERNAL_SERVER_ERROR,
+				},
+			},
+			GinPath: "/v1/pipelines",
+			Handler: NewPostHandler(ctx, cfg, syncController),
+		},
+		{
+			Operation: openapi.Operation{
+				Method:   http.MethodGet,
+				Path:     "/v1/pipelines/{id}",
+				Summary:  "Gets a pipeline",
+				Response: PipelineResponse{},
+				Statuses: []int{httpcode.HTTP_OK},
+				Errors:   []int{httpcode.HTTP_BAD_REQUEST, httpcode.HTTP_NOT_FOUND},
+			},
+			GinPath: "/v1/pipelines/:id",
+			Handler: NewGetHandler(ctx, syncController),
+		},
+		{
+			Operation: openapi.Operation{
+				Method:   http.MethodPut,
+				Path:     "/v1/pipelines/{id}",
+				Summary:  "Replaces the sources of a pipeline",
+				Request:  PipelineRequest{},
+				Response: PipelineResponse{},
+				Statuses: []int{httpcode.HTTP_OK},
+				Errors: []int{
+					httpcode.HTTP_BAD_REQUEST,
+					httpcode.HTTP_NOT_FOUND,
+					httpcode.HTTP_CONFLICT,
+					httpcode.HTTP_INTERNAL_SERVER_ERROR,
+				},
+			},
+			GinPath: "/v1/pipelines/:id",
+			Handler: NewPutHandler(ctx, syncController),
+		},
+		{
+			Operation: openapi.Operation{
+				Method:   http.MethodDelete,
+				Path:     "/v1/pipelines/{id}",
+				Summary:  "Stops and deletes a pipeline",
+				Response: ActionResponse{},
+				Statuses: []int{httpcode.HTTP_OK},
+				Errors:   []int{httpcode.HTTP_BAD_REQUEST, httpcode.HTTP_NOT_FOUND, httpcode.HTTP_CONFLICT},
+			},
+			GinPath: "/v1/pipelines/:id",
+			Handler: NewDeleteHandler(ctx, syncController),
+		},
+		{
+			Operation: openapi.Operation{
+				Method:   http.MethodPost,
+				Path:     "/v1/pipelines/{id}:start",
+				Summary:  "Starts a pipeline from its checkpoint",
+				Response: ActionResponse{},
+				Statuses: []int{httpcode.HTTP_OK},
+				Errors: []int{
+					httpcode.HTTP_BAD_REQUEST,
+					httpcode.HTTP_NOT_FOUND,
+					httpcode.HTTP_CONFLICT,
+					httpcode.HTTP_INTERNAL_SERVER_ERROR,
+				},
+			},
+			GinPath: "/v1/pipelines/:id",
+			Handler: actionHandler,
+		},
+		{
+			Operation: openapi.Operation{
+				Method:   http.MethodPost,
+				Path:     "/v1/pipelines/{id}:stop",
+				Summary:  "Drains and stops a pipeline",
+				Response: ActionResponse{},
+				Statuses: []int{httpcode.HTTP_OK},
+				Errors: []int{
+					httpcode.HTTP_BAD_REQUEST,
+					httpcode.HTTP_NOT_FOUND,
+					httpcode.HTTP_CONFLICT,
+					httpcode.HTTP_INTERNAL_SERVER_ERROR,
+				},
+			},
+			GinPath: "/v1/pipelines/:id",
+			Handler: actionHandler,
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+// parseId - parses a pipeline id path parameter
+func parseId(rawId string) (uint32, error) {
+	id, err := strconv.ParseUint(rawId, BASE10, BIT32)
+	if err != nil || id == 0 {
+		return 0, ErrParam.New(fmt.Sprintf("[v1.parseId]invalid pipeline id %q", rawId))
+	}
+
+	return uint32(id), nil
+}
+
+// lookup - returns the status of pipeline id, or ErrNotFound
+func lookup(syncController synccontroller.SyncController, id uint32) (*syncmanager.Status, error) {
+	status, ok := syncController.Status()[id]
+	if !ok {
+		return nil, ErrNotFound.New(fmt.Sprintf("[v1.lookup]pipeline %d does not exist", id))
+	}
+
+	return status, nil
+}
+
+// abort - ends the request with err, rendered by the router's error handler
+func abort(c *gin.Context, err error) {
+	_ = c.AbortWithError(errorStatus(err), err)
+}
+
+// errorStatus - returns the HTTP status for err
+func errorStatus(err error) int {
+	switch {
+	case ErrNotFound.Is(err):
+		return httpcode.HTTP_NOT_FOUND
+	case ErrParam.Is(err), synccontroller.ErrParam.Is(err), syncmanager.ErrParam.Is(err), syncmanager.ErrConfig.Is(err):
+		return httpcode.HTTP_BAD_REQUEST
+	case synccontroller.ErrQuota.Is(err), syncmanager.ErrQuota.Is(err):
+		return httpcode.HTTP_TOO_MANY_REQUESTS
+	case synccontroller.ErrBusy.Is(err), synccontroller.ErrExists.Is(err):
+		return httpcode.HTTP_CONFLICT
+	default:
+		return httpcode.HTTP_INTERNAL_SERVER_ERROR
+	}
+}
@@ -0,0 +1,262 @@
+package auditlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+
+	sarama "gopkg.in/Shopify/sarama.v1"
+)
+
+// Record - a mutating control-plane call
+//
+// Operation is the method and route of HTTP calls and the full method of gRPC calls. Params
+// are the request parameters with secrets redacted. Pipeline is 0 when the call did not name
+// or create a pipeline
+type Record struct {
+	Time      time.Time
+	Params    json.RawMessage
+	Caller    string
+	ClientIp  string
+	Transport string
+	Operation string
+	Outcome   string
+	Error     string `json:",omitempty"`
+	Id        uint64
+	Pipeline  uint32 `json:",omitempty"`
+}
+
+// Page - records of the audit log, newest first
+//
+// Next is the cursor to the following, older, page and 0 on the last page
+type Page struct {
+	Records []Record
+	Next    uint64
+	Total   int
+}
+
+// AuditLog - append-only log of control-plane calls
+//
+// Every record is appended to a JSONL file and, if a topic is configured, produced to kafka.
+// The latest records are also kept in memory to be paged through, reloaded from the file on start
+type AuditLog struct {
+	ctx      context.Context
+	file     *os.File
+	producer sarama.AsyncProducer
+	topic    string
+	recent   []Record
+	nextId   uint64
+	mu       sync.Mutex
+}
+
+// NewAuditLog - opens the audit log configured by auditCfg, producing to the brokers of kafkaCfg
+func NewAuditLog(ctx context.Context, auditCfg config.AuditConfig, kafkaCfg config.KafkaConfig) (*AuditLog, error) {
+	path := auditCfg.Path
+	if path == "" {
+		path = DEFAULT_PATH
+	}
+
+	size := int(auditCfg.Size)
+	if size <= 0 {
+		size = DEFAULT_RECENT_SIZE
+	}
+
+	a := &AuditLog{
+		ctx:    ctx,
+		topic:  auditCfg.Topic,
+		recent: make([]Record, size),
+		nextId: 1,
+	}
+
+	if err := a.load(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, FILE_PERMISSION)
+	if err != nil {
+		return nil, ErrFile.New(fmt.Sprintf("[AuditLog.NewAuditLog]%s", err.Error()))
+	}
+
+	a.file = file
+
+	if a.topic == "" {
+		return a, nil
+	}
+
+	saramaCfg := sarama.NewConfig()
+	saramaCfg.Producer.RequiredAcks = sarama.WaitForAll
+	saramaCfg.Producer.Return.Errors = true
+
+	producer, err := sarama.NewAsyncProducer(kafkaCfg.BrokerList, saramaCfg)
+	if err != nil {
+		file.Close()
+
+		return nil, ErrKafka.New(fmt.Sprintf("[AuditLog.NewAuditLog]%s", err.Error()))
+	}
+
+	a.producer = producer
+
+	go func() {
+		for produceErr := range producer.Errors() {
+			logger.WithContext(ctx).Error("[AuditLog.NewAuditLog]fail to produce audit record", zap.Error(produceErr.Err))
+		}
+	}()
+
+	return a, nil
+}
+
+// Write - appends record, setting its id and, if unset, its time
+//
+// Records are produced to kafka without waiting for acknowledgement, and dropped from kafka
+// rather than holding up the call when the producer is behind
+func (a *AuditLog) Write(record Record) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	a.mu.Lock()
+
+	record.Id = a.nextId
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		a.mu.Unlock()
+
+		logger.WithContext(a.ctx).Error("[AuditLog.Write]fail to marshal audit record", zap.Error(err))
+
+		return
+	}
+
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		logger.WithContext(a.ctx).Error(
+			"[AuditLog.Write]fail to append audit record",
+			zap.ByteString("record", line),
+			zap.Error(err),
+		)
+	}
+
+	a.add(record)
+	a.mu.Unlock()
+
+	if a.producer == nil {
+		return
+	}
+
+	select {
+	case a.producer.Input() <- &sarama.ProducerMessage{
+		Topic: a.topic,
+		Value: sarama.ByteEncoder(line),
+	}:
+	default:
+		logger.WithContext(a.ctx).Error("[AuditLog.Write]audit producer is behind, record not produced", zap.Uint64("id", record.Id))
+	}
+}
+
+// Page - returns up to limit records older than the record id before, newest first
+//
+// A before of 0 starts from the newest record
+func (a *AuditLog) Page(before uint64, limit int) *Page {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	oldest := a.oldestId()
+
+	if before == 0 || before > a.nextId {
+		before = a.nextId
+	}
+
+	page := &Page{
+		Records: []Record{},
+		Total:   int(a.nextId - oldest),
+	}
+
+	id := before
+	for ; id > oldest && len(page.Records) < limit; id-- {
+		page.Records = append(page.Records, a.recent[a.slot(id-1)])
+	}
+
+	if id > oldest {
+		page.Next = id
+	}
+
+	return page
+}
+
+// Close - stops producing to kafka and closes the file
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.producer != nil {
+		if err := a.producer.Close(); err != nil {
+			logger.WithContext(a.ctx).Error("[AuditLog.Close]fail to close audit producer", zap.Error(err))
+		}
+	}
+
+	if err := a.file.Close(); err != nil {
+		return ErrFile.New(fmt.Sprintf("[AuditLog.Close]%s", err.Error()))
+	}
+
+	return nil
+}
+
+// load - keeps the latest records of the file at path in memory, continuing their ids
+func (a *AuditLog) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return ErrFile.New(fmt.Sprintf("[AuditLog.load]%s", err.Error()))
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MAX_LINE_SIZE)
+
+	for scanner.Scan() {
+		var record Record
+
+		// a torn last line is skipped rather than failing start up
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Id < a.nextId {
+			continue
+		}
+
+		a.nextId = record.Id
+		a.add(record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ErrFile.New(fmt.Sprintf("[AuditLog.load]%s", err.Error()))
+	}
+
+	return nil
+}
+
+// add - keeps record in memory, overwriting the oldest record when full. Must be called with a.mu held
+func (a *AuditLog) add(record Record) {
+	a.recent[a.slot(record.Id)] = record
+	a.nextId = record.Id + 1
+}
+
+func (a *AuditLog) oldestId() uint64 {
+	if size := uint64(len(a.recent)); a.nextId > size {
+		return a.nextId - size
+	}
+
+	return 1
+}
+
+func (a *AuditLog) slot(id uint64) int {
+	return int(id % uint64(len(a.recent)))
+}
@@ -0,0 +1,33 @@
+package auditlog
+
+const (
+	DEFAULT_PATH        = "./audit.jsonl"
+	DEFAULT_RECENT_SIZE = 1000
+	FILE_PERMISSION     = 0o644
+	MAX_LINE_SIZE       = 1 << 20
+)
+
+// Record outcomes, denied for calls rejected by auth
+const (
+	OUTCOME_OK     = "ok"
+	OUTCOME_FAILED = "failed"
+	OUTCOME_DENIED = "denied"
+)
+
+// Record transports
+const (
+	TRANSPORT_HTTP = "http"
+	TRANSPORT_GRPC = "grpc"
+)
+
+// REDACTED - replaces the value of secret request parameters
+const REDACTED = "[REDACTED]"
+
+// redactedParams - lowercased names of request parameters whose values are never recorded
+var redactedParams = map[string]bool{
+	"pass":          true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+}
@@ -0,0 +1,13 @@
+package auditlog
+
+import (
+	"github.com/twothicc/common-go/errortype"
+)
+
+const pkg = "infra/auditlog"
+
+//nolint:gomnd // error code
+var (
+	ErrFile  = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrKafka = errortype.ErrorType{Code: 2, Pkg: pkg}
+)
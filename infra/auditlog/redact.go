@@ -0,0 +1,49 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Redact - returns the JSON params with the values of secret fields replaced, at any depth
+//
+// Params that are not valid JSON are dropped rather than risk recording a secret
+func Redact(params []byte) json.RawMessage {
+	if len(params) == 0 {
+		return nil
+	}
+
+	var value interface{}
+
+	if err := json.Unmarshal(params, &value); err != nil {
+		return nil
+	}
+
+	redacted, err := json.Marshal(redact(value))
+	if err != nil {
+		return nil
+	}
+
+	return redacted
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedParams[strings.ToLower(key)] {
+				v[key] = REDACTED
+
+				continue
+			}
+
+			v[key] = redact(field)
+		}
+	case []interface{}:
+		for idx, item := range v {
+			v[idx] = redact(item)
+		}
+	}
+
+	return value
+}
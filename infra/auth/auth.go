@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+//nolint:gomnd // role rank
+var roleRanks = map[string]int{
+	ROLE_VIEWER:   1,
+	ROLE_OPERATOR: 2,
+	ROLE_ADMIN:    3,
+}
+
+// Authenticator - identifies callers of the control plane by API token or HMAC signature,
+// shared by the HTTP and gRPC servers
+type Authenticator struct {
+	keys      map[string]config.AuthKey
+	maxSkew   time.Duration
+	isEnabled bool
+}
+
+// NewAuthenticator - creates an Authenticator of the keys in authCfg
+//
+// Auth is disabled when authCfg has no keys. Invalid keys are ignored
+func NewAuthenticator(ctx context.Context, authCfg config.AuthConfig) *Authenticator {
+	a := &Authenticator{
+		keys:      make(map[string]config.AuthKey),
+		maxSkew:   time.Duration(authCfg.MaxSkew) * time.Millisecond,
+		isEnabled: len(authCfg.Keys) > 0,
+	}
+
+	if a.maxSkew <= 0 {
+		a.maxSkew = DEFAULT_MAX_SKEW
+	}
+
+	for _, key := range authCfg.Keys {
+		if _, ok := roleRanks[key.Role]; !ok || key.Id == "" || key.Secret == "" {
+			logger.WithContext(ctx).Error(
+				"[Auth.NewAuthenticator]ignoring auth key without id, secret or valid role",
+				zap.String("key id", key.Id),
+				zap.String("role", key.Role),
+			)
+
+			continue
+		}
+
+		a.keys[key.Id] = key
+	}
+
+	return a
+}
+
+// IsEnabled - returns whether callers must authenticate
+func (a *Authenticator) IsEnabled() bool {
+	return a.isEnabled
+}
+
+// Token - returns the key whose secret is token
+func (a *Authenticator) Token(token string) (config.AuthKey, error) {
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key.Secret)) == 1 {
+			return key, nil
+		}
+	}
+
+	return config.AuthKey{}, ErrUnauthenticated.New("[Authenticator.Token]unknown token")
+}
+
+// Verify - returns key keyId once signature is checked to be its hex HMAC-SHA256 of the signed
+// fields joined by newlines, timestamp being a unix millisecond timestamp within the max skew
+func (a *Authenticator) Verify(keyId, timestamp, signature string, signed ...string) (config.AuthKey, error) {
+	key, ok := a.keys[keyId]
+	if !ok {
+		return config.AuthKey{}, ErrUnauthenticated.New("[Authenticator.Verify]unknown key id")
+	}
+
+	ms, err := strconv.ParseInt(timestamp, BASE10, BIT64)
+	if err != nil {
+		return config.AuthKey{}, ErrUnauthenticated.New(fmt.Sprintf("[Authenticator.Verify]invalid timestamp %q", timestamp))
+	}
+
+	if skew := time.Since(time.UnixMilli(ms)); skew > a.maxSkew || skew < -a.maxSkew {
+		return config.AuthKey{}, ErrUnauthenticated.New(fmt.Sprintf("[Authenticator.Verify]timestamp is %s off", skew))
+	}
+
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return config.AuthKey{}, ErrUnauthenticated.New("[Authenticator.Verify]signature is not hex")
+	}
+
+	if !hmac.Equal(decoded, Sign(key.Secret, signed...)) {
+		return config.AuthKey{}, ErrUnauthenticated.New("[Authenticator.Verify]signature mismatch")
+	}
+
+	return key, nil
+}
+
+// Sign - returns the HMAC-SHA256 of the signed fields joined by newlines, keyed by secret
+func Sign(secret string, signed ...string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(signed, SIGNED_FIELD_SEPARATOR)))
+
+	return mac.Sum(nil)
+}
+
+// BodyHash - returns the hex SHA256 of a signed request's body
+func BodyHash(body []byte) string {
+	hash := sha256.Sum256(body)
+
+	return hex.EncodeToString(hash[:])
+}
+
+// IsGranted - returns whether role is granted everything requiredRole is
+func IsGranted(role, requiredRole string) bool {
+	return roleRanks[role] >= roleRanks[requiredRole]
+}
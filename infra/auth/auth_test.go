@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	KEY_ID   = "operator-key"
+	SECRET   = "operator-secret"
+	METHOD   = "POST"
+	PATH     = "/v1/pipelines/1/start"
+	MAX_SKEW = time.Minute
+)
+
+func TestMain(m *testing.M) {
+	// the logger writes server.log to the working directory
+	dir, err := os.MkdirTemp("", "auth")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+
+	logger.InitLogger(zapcore.FatalLevel)
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newAuthenticator() *Authenticator {
+	return NewAuthenticator(context.Background(), config.AuthConfig{
+		Keys: []config.AuthKey{
+			{Id: KEY_ID, Role: ROLE_OPERATOR, Secret: SECRET},
+			{Id: "invalid-role-key", Role: "root", Secret: "invalid-role-secret"},
+		},
+		MaxSkew: uint32(MAX_SKEW / time.Millisecond),
+	})
+}
+
+func timestampOf(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), BASE10)
+}
+
+func TestVerify(t *testing.T) {
+	now := timestampOf(time.Now())
+	bodyHash := BodyHash([]byte(`{"is_legacy_sync":false}`))
+
+	tests := []struct {
+		name      string
+		keyId     string
+		timestamp string
+		signature string
+		isValid   bool
+	}{
+		{
+			name:      "valid signature",
+			keyId:     KEY_ID,
+			timestamp: now,
+			signature: hex.EncodeToString(Sign(SECRET, METHOD, PATH, now, bodyHash)),
+			isValid:   true,
+		},
+		{
+			name:      "signed by another secret",
+			keyId:     KEY_ID,
+			timestamp: now,
+			signature: hex.EncodeToString(Sign("guess", METHOD, PATH, now, bodyHash)),
+		},
+		{
+			name:      "signed another body",
+			keyId:     KEY_ID,
+			timestamp: now,
+			signature: hex.EncodeToString(Sign(SECRET, METHOD, PATH, now, BodyHash(nil))),
+		},
+		{
+			name:      "signed another timestamp",
+			keyId:     KEY_ID,
+			timestamp: now,
+			signature: hex.EncodeToString(Sign(SECRET, METHOD, PATH, timestampOf(time.Now().Add(-time.Second)), bodyHash)),
+		},
+		{
+			name:      "signature is not hex",
+			keyId:     KEY_ID,
+			timestamp: now,
+			signature: "not hex",
+		},
+		{
+			name:      "empty signature",
+			keyId:     KEY_ID,
+			timestamp: now,
+		},
+		{
+			name:      "unknown key",
+			keyId:     "unknown-key",
+			timestamp: now,
+			signature: hex.EncodeToString(Sign(SECRET, METHOD, PATH, now, bodyHash)),
+		},
+		{
+			name:      "key with invalid role",
+			keyId:     "invalid-role-key",
+			timestamp: now,
+			signature: hex.EncodeToString(Sign("invalid-role-secret", METHOD, PATH, now, bodyHash)),
+		},
+		{
+			name:      "expired timestamp",
+			keyId:     KEY_ID,
+			timestamp: timestampOf(time.Now().Add(-2 * MAX_SKEW)),
+		},
+		{
+			name:      "future timestamp",
+			keyId:     KEY_ID,
+			timestamp: timestampOf(time.Now().Add(2 * MAX_SKEW)),
+		},
+		{
+			name:      "timestamp in seconds",
+			keyId:     KEY_ID,
+			timestamp: strconv.FormatInt(time.Now().Unix(), BASE10),
+		},
+		{
+			name:      "invalid timestamp",
+			keyId:     KEY_ID,
+			timestamp: "yesterday",
+		},
+	}
+
+	authenticator := newAuthenticator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// stale timestamps are signed correctly, so that only the timestamp is at fault
+			signature := tt.signature
+			if signature == "" && tt.timestamp != now {
+				signature = hex.EncodeToString(Sign(SECRET, METHOD, PATH, tt.timestamp, bodyHash))
+			}
+
+			key, err := authenticator.Verify(tt.keyId, tt.timestamp, signature, METHOD, PATH, tt.timestamp, bodyHash)
+
+			if !tt.isValid {
+				if !ErrUnauthenticated.Is(err) {
+					t.Fatalf("got key %q and error %v, want ErrUnauthenticated", key.Id, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fail to verify: %v", err)
+			}
+
+			if key.Id != KEY_ID || key.Role != ROLE_OPERATOR {
+				t.Fatalf("got key %q with role %q, want %q with role %q", key.Id, key.Role, KEY_ID, ROLE_OPERATOR)
+			}
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		isValid bool
+	}{
+		{name: "known token", token: SECRET, isValid: true},
+		{name: "unknown token", token: "guess"},
+		{name: "prefix of token", token: SECRET[:len(SECRET)-1]},
+		{name: "key id as token", token: KEY_ID},
+		{name: "empty token"},
+		{name: "token of key with invalid role", token: "invalid-role-secret"},
+	}
+
+	authenticator := newAuthenticator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := authenticator.Token(tt.token)
+
+			if !tt.isValid {
+				if !ErrUnauthenticated.Is(err) {
+					t.Fatalf("got key %q and error %v, want ErrUnauthenticated", key.Id, err)
+				}
+
+				return
+			}
+
+			if err != nil || key.Id != KEY_ID {
+				t.Fatalf("got key %q and error %v, want key %q", key.Id, err, KEY_ID)
+			}
+		})
+	}
+}
+
+func TestIsGranted(t *testing.T) {
+	tests := []struct {
+		role         string
+		requiredRole string
+		isGranted    bool
+	}{
+		{role: ROLE_VIEWER, requiredRole: ROLE_VIEWER, isGranted: true},
+		{role: ROLE_VIEWER, requiredRole: ROLE_OPERATOR},
+		{role: ROLE_VIEWER, requiredRole: ROLE_ADMIN},
+		{role: ROLE_OPERATOR, requiredRole: ROLE_VIEWER, isGranted: true},
+		{role: ROLE_OPERATOR, requiredRole: ROLE_OPERATOR, isGranted: true},
+		{role: ROLE_OPERATOR, requiredRole: ROLE_ADMIN},
+		{role: ROLE_ADMIN, requiredRole: ROLE_VIEWER, isGranted: true},
+		{role: ROLE_ADMIN, requiredRole: ROLE_ADMIN, isGranted: true},
+		{role: "", requiredRole: ROLE_VIEWER},
+		{role: "root", requiredRole: ROLE_VIEWER},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" requires "+tt.requiredRole, func(t *testing.T) {
+			if got := IsGranted(tt.role, tt.requiredRole); got != tt.isGranted {
+				t.Fatalf("got granted %t, want %t", got, tt.isGranted)
+			}
+		})
+	}
+}
+
+func TestIsEnabled(t *testing.T) {
+	if NewAuthenticator(context.Background(), config.AuthConfig{}).IsEnabled() {
+		t.Fatal("got auth enabled without keys")
+	}
+
+	if !newAuthenticator().IsEnabled() {
+		t.Fatal("got auth disabled with keys")
+	}
+}
@@ -0,0 +1,22 @@
+package auth
+
+import "time"
+
+// Roles, each granted everything the previous one is
+const (
+	ROLE_VIEWER   = "viewer"
+	ROLE_OPERATOR = "operator"
+	ROLE_ADMIN    = "admin"
+)
+
+const (
+	// DEFAULT_MAX_SKEW - how far a signed request's timestamp may be from now
+	DEFAULT_MAX_SKEW = 5 * time.Minute
+	// SIGNED_FIELD_SEPARATOR - joins the fields of a request that are signed
+	SIGNED_FIELD_SEPARATOR = "\n"
+)
+
+const (
+	BASE10 = 10
+	BIT64  = 64
+)
@@ -0,0 +1,12 @@
+package auth
+
+import (
+	"github.com/twothicc/common-go/errortype"
+)
+
+const pkg = "infra/auth"
+
+//nolint:gomnd // error code
+var (
+	ErrUnauthenticated = errortype.ErrorType{Code: 1, Pkg: pkg}
+)
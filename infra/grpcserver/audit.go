@@ -0,0 +1,79 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"path"
+
+	"github.com/twothicc/canal/infra/auditlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// mutatingMethods - control plane methods that change pipelines and are audited
+var mutatingMethods = map[string]bool{
+	"Create": true,
+	"Start":  true,
+	"Stop":   true,
+	"Delete": true,
+}
+
+// pipelineMessage - requests and responses naming a pipeline
+type pipelineMessage interface {
+	GetId() uint32
+}
+
+// auditInterceptor - records every mutating call to auditLog once handled, including calls
+// rejected by the auth interceptor after it
+func auditInterceptor(auditLog *auditlog.AuditLog) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !mutatingMethods[path.Base(info.FullMethod)] {
+			return handler(ctx, req)
+		}
+
+		caller := UNAUTHENTICATED_CALLER
+
+		resp, err := handler(context.WithValue(ctx, callerKey{}, &caller), req)
+
+		record := auditlog.Record{
+			Caller:    caller,
+			Transport: auditlog.TRANSPORT_GRPC,
+			Operation: info.FullMethod,
+			Outcome:   auditlog.OUTCOME_OK,
+		}
+
+		if p, ok := peer.FromContext(ctx); ok {
+			record.ClientIp = p.Addr.String()
+
+			if host, _, splitErr := net.SplitHostPort(record.ClientIp); splitErr == nil {
+				record.ClientIp = host
+			}
+		}
+
+		if msg, ok := req.(proto.Message); ok {
+			if params, marshalErr := protojson.Marshal(msg); marshalErr == nil {
+				record.Params = auditlog.Redact(params)
+			}
+		}
+
+		if msg, ok := req.(pipelineMessage); ok {
+			record.Pipeline = msg.GetId()
+		}
+
+		if msg, ok := resp.(pipelineMessage); ok && record.Pipeline == 0 && err == nil {
+			record.Pipeline = msg.GetId()
+		}
+
+		if err != nil {
+			record.Outcome = auditlog.OUTCOME_FAILED
+			record.Error = status.Convert(err).Message()
+		}
+
+		auditLog.Write(record)
+
+		return resp, err
+	}
+}
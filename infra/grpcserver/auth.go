@@ -0,0 +1,164 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/infra/auth"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// methodRoles - least role required by each control plane method, matching its HTTP route
+//
+// Methods missing here require admin
+var methodRoles = map[string]string{
+	"Get":    auth.ROLE_VIEWER,
+	"List":   auth.ROLE_VIEWER,
+	"Status": auth.ROLE_VIEWER,
+	"Watch":  auth.ROLE_VIEWER,
+	"Start":  auth.ROLE_OPERATOR,
+	"Stop":   auth.ROLE_OPERATOR,
+	"Create": auth.ROLE_ADMIN,
+	"Delete": auth.ROLE_ADMIN,
+}
+
+// callerKey - context key of the *string the auth interceptor sets to the caller's key id
+type callerKey struct{}
+
+// authUnaryInterceptor - authenticates callers by API token or HMAC signature with the same keys
+// as the HTTP control plane, then checks that their role is granted the method
+//
+// Signed calls sign the full method, timestamp and hex SHA256 of the deterministically marshalled
+// request. Every call is allowed when authenticator has no keys
+func authUnaryInterceptor(ctx context.Context, authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(callCtx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var body []byte
+
+		if msg, ok := req.(proto.Message); ok {
+			var err error
+
+			if body, err = (proto.MarshalOptions{Deterministic: true}).Marshal(msg); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		}
+
+		if err := authorize(ctx, callCtx, authenticator, info.FullMethod, body); err != nil {
+			return nil, err
+		}
+
+		return handler(callCtx, req)
+	}
+}
+
+// authStreamInterceptor - see authUnaryInterceptor. Signed streams sign the hash of an empty body,
+// as they are authenticated before any request is received
+func authStreamInterceptor(ctx context.Context, authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ctx, stream.Context(), authenticator, info.FullMethod, nil); err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+// authorize - authenticates the call of fullMethod with body, returning an Unauthenticated or
+// PermissionDenied status error if the caller may not call it
+func authorize(
+	ctx context.Context,
+	callCtx context.Context,
+	authenticator *auth.Authenticator,
+	fullMethod string,
+	body []byte,
+) error {
+	if !authenticator.IsEnabled() {
+		setCaller(callCtx, ANONYMOUS_CALLER)
+
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(callCtx)
+
+	key, err := authenticate(authenticator, md, fullMethod, body)
+	if err != nil {
+		logger.WithContext(ctx).Warn(
+			"[GrpcServer.authorize]unauthenticated call",
+			zap.Strings("caller", md.Get(KEY_ID_METADATA)),
+			zap.String("method", fullMethod),
+			zap.Error(err),
+		)
+
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	setCaller(callCtx, key.Id)
+
+	requiredRole, ok := methodRoles[path.Base(fullMethod)]
+	if !ok {
+		requiredRole = auth.ROLE_ADMIN
+	}
+
+	if !auth.IsGranted(key.Role, requiredRole) {
+		logger.WithContext(ctx).Warn(
+			"[GrpcServer.authorize]unauthorized call",
+			zap.String("caller", key.Id),
+			zap.String("role", key.Role),
+			zap.String("required role", requiredRole),
+			zap.String("method", fullMethod),
+		)
+
+		return status.Error(codes.PermissionDenied, ErrForbidden.New(fmt.Sprintf(
+			"[GrpcServer.authorize]role %s may not call %s, requires %s",
+			key.Role, fullMethod, requiredRole,
+		)).Error())
+	}
+
+	return nil
+}
+
+// authenticate - returns the key of a signed call, or else of its bearer token
+func authenticate(authenticator *auth.Authenticator, md metadata.MD, fullMethod string, body []byte) (config.AuthKey, error) {
+	if signature := first(md, SIGNATURE_METADATA); signature != "" {
+		timestamp := first(md, TIMESTAMP_METADATA)
+
+		return authenticator.Verify(
+			first(md, KEY_ID_METADATA),
+			timestamp,
+			signature,
+			fullMethod,
+			timestamp,
+			auth.BodyHash(body),
+		)
+	}
+
+	token := strings.TrimPrefix(first(md, AUTHORIZATION_METADATA), BEARER_PREFIX)
+	if token == "" {
+		return config.AuthKey{}, ErrUnauthenticated.New("[GrpcServer.authenticate]missing token or signature")
+	}
+
+	return authenticator.Token(token)
+}
+
+// setCaller - records the caller's key id for the audit interceptor, if it is auditing the call
+func setCaller(callCtx context.Context, caller string) {
+	if holder, ok := callCtx.Value(callerKey{}).(*string); ok {
+		*holder = caller
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,19 @@
+package grpcserver
+
+const (
+	// ANONYMOUS_CALLER - audited caller of calls when auth is disabled
+	ANONYMOUS_CALLER = "anonymous"
+	// UNAUTHENTICATED_CALLER - audited caller of calls rejected before authenticating
+	UNAUTHENTICATED_CALLER = "unauthenticated"
+)
+
+// Auth metadata, matching the HTTP auth headers. Signed calls send their key id, a unix
+// millisecond timestamp and the hex HMAC-SHA256 of full method, timestamp and hex SHA256 of
+// the request, joined by newlines
+const (
+	AUTHORIZATION_METADATA = "authorization"
+	BEARER_PREFIX          = "Bearer "
+	KEY_ID_METADATA        = "x-canal-key"
+	TIMESTAMP_METADATA     = "x-canal-timestamp"
+	SIGNATURE_METADATA     = "x-canal-signature"
+)
@@ -0,0 +1,66 @@
+package grpcserver
+
+import (
+	"context"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/handlers/controlplane"
+	"github.com/twothicc/canal/infra/auditlog"
+	"github.com/twothicc/canal/infra/auth"
+	pb "github.com/twothicc/canal/proto/controlplanepb"
+	"github.com/twothicc/common-go/logger"
+	"google.golang.org/grpc"
+)
+
+type GrpcServerDependencies struct {
+	Cfg            *config.Config
+	SyncController synccontroller.SyncController
+	AuditLog       *auditlog.AuditLog
+}
+
+// GrpcServer - gRPC server exposing the control plane
+type GrpcServer struct {
+	*grpc.Server
+	controlPlane *controlplane.Server
+}
+
+// NewGRPCServer - creates a gRPC server with the control plane registered, logging every call,
+// auditing mutating calls, authenticating callers with the same keys and roles as the HTTP
+// control plane and recovering from panics
+func NewGRPCServer(ctx context.Context, dependencies *GrpcServerDependencies) *GrpcServer {
+	zapLogger := logger.WithContext(ctx)
+	authenticator := auth.NewAuthenticator(ctx, dependencies.Cfg.AuthConfig)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
+			grpc_zap.UnaryServerInterceptor(zapLogger),
+			auditInterceptor(dependencies.AuditLog),
+			authUnaryInterceptor(ctx, authenticator),
+			grpc_recovery.UnaryServerInterceptor(),
+		)),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
+			grpc_zap.StreamServerInterceptor(zapLogger),
+			authStreamInterceptor(ctx, authenticator),
+			grpc_recovery.StreamServerInterceptor(),
+		)),
+	)
+
+	controlPlane := controlplane.NewServer(ctx, dependencies.Cfg, dependencies.SyncController)
+
+	pb.RegisterControlPlaneServer(server, controlPlane)
+
+	return &GrpcServer{
+		Server:       server,
+		controlPlane: controlPlane,
+	}
+}
+
+// Shutdown - ends open Watch streams, then waits for pending calls to finish before stopping
+func (s *GrpcServer) Shutdown() {
+	s.controlPlane.Close()
+	s.GracefulStop()
+}
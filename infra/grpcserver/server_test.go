@@ -0,0 +1,386 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/domain/entity/syncmanager"
+	"github.com/twothicc/canal/infra/auditlog"
+	"github.com/twothicc/canal/infra/auth"
+	pb "github.com/twothicc/canal/proto/controlplanepb"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	BUF_SIZE        = 1 << 20
+	PIPELINE_ID     = 1
+	CALL_DEADLINE   = 5 * time.Second
+	VIEWER_KEY      = "viewer-key"
+	OPERATOR_KEY    = "operator-key"
+	ADMIN_KEY       = "admin-key"
+	VIEWER_SECRET   = "viewer-secret"
+	OPERATOR_SECRET = "operator-secret"
+	ADMIN_SECRET    = "admin-secret"
+	WATCH_METHOD    = "/canal.controlplane.v1.ControlPlane/Watch"
+)
+
+var authCfg = config.AuthConfig{
+	Keys: []config.AuthKey{
+		{Id: VIEWER_KEY, Role: auth.ROLE_VIEWER, Secret: VIEWER_SECRET},
+		{Id: OPERATOR_KEY, Role: auth.ROLE_OPERATOR, Secret: OPERATOR_SECRET},
+		{Id: ADMIN_KEY, Role: auth.ROLE_ADMIN, Secret: ADMIN_SECRET},
+	},
+}
+
+func TestMain(m *testing.M) {
+	// the logger writes server.log to the working directory
+	dir, err := os.MkdirTemp("", "grpcserver")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+
+	logger.InitLogger(zapcore.FatalLevel)
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeSyncController - has a single pipeline, PIPELINE_ID, which every call succeeds on
+type fakeSyncController struct {
+	synccontroller.SyncController
+}
+
+func (s *fakeSyncController) Start(context.Context, uint32, bool) error {
+	return nil
+}
+
+func (s *fakeSyncController) Stop(context.Context, uint32) (*syncmanager.DrainResult, error) {
+	return &syncmanager.DrainResult{IsCompleted: true}, nil
+}
+
+func (s *fakeSyncController) Remove(context.Context, uint32) error {
+	return nil
+}
+
+func (s *fakeSyncController) Status() map[uint32]*syncmanager.Status {
+	return map[uint32]*syncmanager.Status{
+		PIPELINE_ID: {ServerId: PIPELINE_ID, State: syncmanager.STATE_STREAMING, IsRunning: true},
+	}
+}
+
+// newClient - serves a gRPC server with authCfg over an in-memory connection, returning a
+// client and the audit log of the server
+func newClient(t *testing.T, authCfg config.AuthConfig, opts ...grpc.DialOption) (pb.ControlPlaneClient, *auditlog.AuditLog) {
+	t.Helper()
+
+	ctx := context.Background()
+	listener := bufconn.Listen(BUF_SIZE)
+
+	auditLog, err := auditlog.NewAuditLog(ctx, config.AuditConfig{Path: filepath.Join(t.TempDir(), "audit.log")}, config.KafkaConfig{})
+	if err != nil {
+		t.Fatalf("fail to open audit log: %v", err)
+	}
+
+	server := NewGRPCServer(ctx, &GrpcServerDependencies{
+		Cfg:            &config.Config{AuthConfig: authCfg},
+		SyncController: &fakeSyncController{},
+		AuditLog:       auditLog,
+	})
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	opts = append(
+		opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	conn, err := grpc.DialContext(ctx, "bufnet", opts...)
+	if err != nil {
+		t.Fatalf("fail to dial grpc server: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.controlPlane.Close()
+		server.Stop()
+		auditLog.Close()
+	})
+
+	return pb.NewControlPlaneClient(conn), auditLog
+}
+
+// withToken - returns ctx sending secret as a bearer token
+func withToken(ctx context.Context, secret string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, AUTHORIZATION_METADATA, BEARER_PREFIX+secret)
+}
+
+// signer - signs unary calls as keyId with secret, timestamped now plus skew
+//
+// If signedReq is set, it is signed in place of the request sent
+type signer struct {
+	signedReq proto.Message
+	keyId     string
+	secret    string
+	skew      time.Duration
+}
+
+func (s signer) interceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		signedReq := s.signedReq
+		if signedReq == nil {
+			signedReq = req.(proto.Message)
+		}
+
+		body, err := (proto.MarshalOptions{Deterministic: true}).Marshal(signedReq)
+		if err != nil {
+			return err
+		}
+
+		return invoker(s.sign(ctx, method, body), method, req, reply, cc, opts...)
+	}
+}
+
+func (s signer) sign(ctx context.Context, method string, body []byte) context.Context {
+	timestamp := strconv.FormatInt(time.Now().Add(s.skew).UnixMilli(), 10)
+	signature := hex.EncodeToString(auth.Sign(s.secret, method, timestamp, auth.BodyHash(body)))
+
+	return metadata.AppendToOutgoingContext(
+		ctx,
+		KEY_ID_METADATA, s.keyId,
+		TIMESTAMP_METADATA, timestamp,
+		SIGNATURE_METADATA, signature,
+	)
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != want {
+		t.Fatalf("got code %s, want %s: %v", got, want, err)
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	client, auditLog := newClient(t, config.AuthConfig{})
+
+	if _, err := client.Delete(context.Background(), &pb.DeleteRequest{Id: PIPELINE_ID}); err != nil {
+		t.Fatalf("fail to delete: %v", err)
+	}
+
+	if records := auditLog.Page(0, 1).Records; len(records) != 1 || records[0].Caller != ANONYMOUS_CALLER {
+		t.Fatalf("got audit records %+v, want a delete by %s", records, ANONYMOUS_CALLER)
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	client, _ := newClient(t, authCfg)
+
+	calls := map[string]func(ctx context.Context) error{
+		"List": func(ctx context.Context) error {
+			_, err := client.List(ctx, &pb.ListRequest{})
+			return err
+		},
+		"Start": func(ctx context.Context) error {
+			_, err := client.Start(ctx, &pb.StartRequest{Id: PIPELINE_ID})
+			return err
+		},
+		"Delete": func(ctx context.Context) error {
+			_, err := client.Delete(ctx, &pb.DeleteRequest{Id: PIPELINE_ID})
+			return err
+		},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		want   codes.Code
+	}{
+		{name: "missing token", method: "List", want: codes.Unauthenticated},
+		{name: "unknown token", method: "List", token: "guess", want: codes.Unauthenticated},
+		{name: "viewer lists", method: "List", token: VIEWER_SECRET, want: codes.OK},
+		{name: "viewer starts", method: "Start", token: VIEWER_SECRET, want: codes.PermissionDenied},
+		{name: "operator starts", method: "Start", token: OPERATOR_SECRET, want: codes.OK},
+		{name: "operator deletes", method: "Delete", token: OPERATOR_SECRET, want: codes.PermissionDenied},
+		{name: "admin deletes", method: "Delete", token: ADMIN_SECRET, want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = withToken(ctx, tt.token)
+			}
+
+			assertCode(t, calls[tt.method](ctx), tt.want)
+		})
+	}
+}
+
+func TestAuthSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		signer signer
+		want   codes.Code
+	}{
+		{
+			name:   "valid signature",
+			signer: signer{keyId: OPERATOR_KEY, secret: OPERATOR_SECRET},
+			want:   codes.OK,
+		},
+		{
+			name:   "wrong secret",
+			signer: signer{keyId: OPERATOR_KEY, secret: ADMIN_SECRET},
+			want:   codes.Unauthenticated,
+		},
+		{
+			name:   "unknown key",
+			signer: signer{keyId: "unknown-key", secret: OPERATOR_SECRET},
+			want:   codes.Unauthenticated,
+		},
+		{
+			name:   "expired timestamp",
+			signer: signer{keyId: OPERATOR_KEY, secret: OPERATOR_SECRET, skew: -auth.DEFAULT_MAX_SKEW - time.Minute},
+			want:   codes.Unauthenticated,
+		},
+		{
+			name:   "future timestamp",
+			signer: signer{keyId: OPERATOR_KEY, secret: OPERATOR_SECRET, skew: auth.DEFAULT_MAX_SKEW + time.Minute},
+			want:   codes.Unauthenticated,
+		},
+		{
+			name: "signed another request",
+			signer: signer{
+				keyId:     OPERATOR_KEY,
+				secret:    OPERATOR_SECRET,
+				signedReq: &pb.StopRequest{Id: PIPELINE_ID + 1},
+			},
+			want: codes.Unauthenticated,
+		},
+		{
+			name:   "insufficient role",
+			signer: signer{keyId: VIEWER_KEY, secret: VIEWER_SECRET},
+			want:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newClient(t, authCfg, grpc.WithUnaryInterceptor(tt.signer.interceptor()))
+
+			_, err := client.Stop(context.Background(), &pb.StopRequest{Id: PIPELINE_ID})
+			assertCode(t, err, tt.want)
+		})
+	}
+}
+
+func TestAuthStream(t *testing.T) {
+	client, _ := newClient(t, authCfg)
+	watchSigner := signer{keyId: VIEWER_KEY, secret: VIEWER_SECRET}
+
+	tests := []struct {
+		ctx  context.Context
+		name string
+		want codes.Code
+	}{
+		{name: "missing token", ctx: context.Background(), want: codes.Unauthenticated},
+		{name: "viewer token", ctx: withToken(context.Background(), VIEWER_SECRET), want: codes.OK},
+		{
+			name: "signed stream",
+			ctx:  watchSigner.sign(context.Background(), WATCH_METHOD, nil),
+			want: codes.OK,
+		},
+		{
+			name: "wrong signature",
+			ctx:  signer{keyId: VIEWER_KEY, secret: ADMIN_SECRET}.sign(context.Background(), WATCH_METHOD, nil),
+			want: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(tt.ctx, CALL_DEADLINE)
+			defer cancel()
+
+			stream, err := client.Watch(ctx, &pb.WatchRequest{})
+			if err != nil {
+				t.Fatalf("fail to watch: %v", err)
+			}
+
+			event, err := stream.Recv()
+			assertCode(t, err, tt.want)
+
+			if tt.want == codes.OK && event.GetPipeline().GetId() != PIPELINE_ID {
+				t.Fatalf("got event of pipeline %d, want %d", event.GetPipeline().GetId(), PIPELINE_ID)
+			}
+		})
+	}
+}
+
+func TestAuthAudit(t *testing.T) {
+	client, auditLog := newClient(t, authCfg)
+
+	_, err := client.Delete(context.Background(), &pb.DeleteRequest{Id: PIPELINE_ID})
+	assertCode(t, err, codes.Unauthenticated)
+
+	_, err = client.Delete(withToken(context.Background(), VIEWER_SECRET), &pb.DeleteRequest{Id: PIPELINE_ID})
+	assertCode(t, err, codes.PermissionDenied)
+
+	_, err = client.Delete(withToken(context.Background(), ADMIN_SECRET), &pb.DeleteRequest{Id: PIPELINE_ID})
+	assertCode(t, err, codes.OK)
+
+	want := []struct {
+		caller  string
+		outcome string
+	}{
+		{ADMIN_KEY, auditlog.OUTCOME_OK},
+		{VIEWER_KEY, auditlog.OUTCOME_FAILED},
+		{UNAUTHENTICATED_CALLER, auditlog.OUTCOME_FAILED},
+	}
+
+	records := auditLog.Page(0, len(want)).Records
+	if len(records) != len(want) {
+		t.Fatalf("got %d audit records, want %d", len(records), len(want))
+	}
+
+	for i, record := range records {
+		if record.Caller != want[i].caller || record.Outcome != want[i].outcome {
+			t.Fatalf("got audit record by %s with outcome %s, want by %s with outcome %s",
+				record.Caller, record.Outcome, want[i].caller, want[i].outcome)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package httprouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/infra/auditlog"
+	"github.com/twothicc/canal/tools/httpcode"
+)
+
+// readOnlyRoutes - POST routes that do not change anything and are not audited
+var readOnlyRoutes = map[string]bool{
+	http.MethodPost + " /sync/status":   true,
+	http.MethodPost + " /sync/validate": true,
+}
+
+// auditedIds - body fields naming the pipeline of a request or of its response
+type auditedIds struct {
+	ServerId uint32
+}
+
+// auditWriter - keeps the start of the response body to find the id of created pipelines
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if remaining := MAX_AUDITED_RESPONSE - w.body.Len(); remaining > 0 {
+		if len(data) < remaining {
+			remaining = len(data)
+		}
+
+		w.body.Write(data[:remaining])
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// AuditHandler - records every mutating request to auditLog once handled, including those
+// rejected by auth
+func AuditHandler(auditLog *auditlog.AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation := c.Request.Method + " " + c.FullPath()
+		if c.FullPath() == "" {
+			operation = c.Request.Method + " " + c.Request.URL.Path
+		}
+
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead ||
+			c.Request.Method == http.MethodOptions || readOnlyRoutes[operation] {
+			c.Next()
+
+			return
+		}
+
+		var body []byte
+
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+
+		record := auditlog.Record{
+			Caller:    Caller(c),
+			ClientIp:  c.ClientIP(),
+			Transport: auditlog.TRANSPORT_HTTP,
+			Operation: operation,
+			Pipeline:  auditedPipeline(c, body, writer.body.Bytes()),
+			Params:    auditlog.Redact(body),
+			Outcome:   auditlog.OUTCOME_OK,
+		}
+
+		if record.Caller == "" {
+			record.Caller = UNAUTHENTICATED_CALLER
+		}
+
+		switch status := c.Writer.Status(); {
+		case status == httpcode.HTTP_UNAUTHORIZED || status == httpcode.HTTP_FORBIDDEN:
+			record.Outcome = auditlog.OUTCOME_DENIED
+		case status >= httpcode.HTTP_BAD_REQUEST:
+			record.Outcome = auditlog.OUTCOME_FAILED
+		}
+
+		if lastErr := c.Errors.Last(); lastErr != nil {
+			record.Error = lastErr.Error()
+		}
+
+		auditLog.Write(record)
+	}
+}
+
+// auditedPipeline - returns the pipeline id of the route, else of the request body, else of
+// the response body for created pipelines
+func auditedPipeline(c *gin.Context, body, response []byte) uint32 {
+	if id, err := strconv.ParseUint(c.Param(ID_PARAM), BASE10, BIT32); err == nil {
+		return uint32(id)
+	}
+
+	var ids auditedIds
+
+	if json.Unmarshal(body, &ids) == nil && ids.ServerId != 0 {
+		return ids.ServerId
+	}
+
+	if json.Unmarshal(response, &ids) == nil {
+		return ids.ServerId
+	}
+
+	return 0
+}
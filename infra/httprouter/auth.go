@@ -0,0 +1,174 @@
+package httprouter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/infra/auth"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/common-go/logger"
+	"go.uber.org/zap"
+)
+
+// routeRoles - least role required by each route, keyed by method and registered path
+//
+// Routes missing here require admin
+var routeRoles = map[string]string{
+	http.MethodPost + " /sync/status":         ROLE_VIEWER,
+	http.MethodPost + " /sync/validate":       ROLE_OPERATOR,
+	http.MethodPost + " /sync/stop":           ROLE_OPERATOR,
+	http.MethodPost + " /sync/pause":          ROLE_OPERATOR,
+	http.MethodPost + " /sync/resume":         ROLE_OPERATOR,
+	http.MethodPost + " /sync/update":         ROLE_OPERATOR,
+	http.MethodGet + " /sync/:id/tail":        ROLE_OPERATOR,
+	http.MethodGet + " /sync/:id/events":      ROLE_OPERATOR,
+	http.MethodPost + " /sync/run":            ROLE_ADMIN,
+	http.MethodPost + " /sync/delete":         ROLE_ADMIN,
+	http.MethodGet + " /v1/pipelines":         ROLE_VIEWER,
+	http.MethodGet + " /v1/pipelines/:id":     ROLE_VIEWER,
+	http.MethodGet + " " + OPENAPI_PATH:       ROLE_VIEWER,
+	http.MethodGet + " " + METRICS_PATH:       ROLE_VIEWER,
+	http.MethodGet + " " + RELOAD_STATUS_PATH: ROLE_VIEWER,
+	http.MethodGet + " " + READYZ_DETAIL_PATH: ROLE_VIEWER,
+	http.MethodPost + " /v1/pipelines/:id":    ROLE_OPERATOR,
+	http.MethodPut + " /v1/pipelines/:id":     ROLE_OPERATOR,
+	http.MethodPost + " /v1/pipelines":        ROLE_ADMIN,
+	http.MethodDelete + " /v1/pipelines/:id":  ROLE_ADMIN,
+	http.MethodGet + " " + AUDIT_PATH:         ROLE_ADMIN,
+}
+
+// publicRoutes - routes anyone may call, e.g. probes that cannot authenticate
+var publicRoutes = map[string]bool{
+	http.MethodGet + " " + HEALTHZ_PATH: true,
+	http.MethodGet + " " + READYZ_PATH:  true,
+}
+
+// NewAuthHandler - authenticates callers by API token or HMAC signature, then checks that
+// their role is granted the route
+//
+// Every request is allowed when authCfg has no keys, as are public routes. Invalid keys are ignored
+func NewAuthHandler(ctx context.Context, authCfg config.AuthConfig) gin.HandlerFunc {
+	a := auth.NewAuthenticator(ctx, authCfg)
+
+	if !a.IsEnabled() {
+		logger.WithContext(ctx).Warn("[HttpRouter.NewAuthHandler]no auth keys, control plane is open to anyone")
+
+		return func(c *gin.Context) {
+			c.Set(CALLER_KEY, ANONYMOUS_CALLER)
+		}
+	}
+
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+
+		if publicRoutes[route] {
+			c.Set(CALLER_KEY, ANONYMOUS_CALLER)
+
+			return
+		}
+
+		requiredRole, ok := routeRoles[route]
+		if !ok {
+			requiredRole = ROLE_ADMIN
+		}
+
+		key, err := authenticate(a, c)
+		if err != nil {
+			logger.WithContext(ctx).Warn(
+				"[HttpRouter.NewAuthHandler]unauthenticated request",
+				zap.String("caller", c.GetHeader(KEY_ID_HEADER)),
+				zap.String("client ip", c.ClientIP()),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Error(err),
+			)
+
+			abortAuth(ctx, c, httpcode.HTTP_UNAUTHORIZED, err)
+
+			return
+		}
+
+		c.Set(CALLER_KEY, key.Id)
+
+		if !auth.IsGranted(key.Role, requiredRole) {
+			err := ErrForbidden.New(fmt.Sprintf(
+				"[HttpRouter.NewAuthHandler]role %s may not call %s %s, requires %s",
+				key.Role, c.Request.Method, c.Request.URL.Path, requiredRole,
+			))
+
+			logger.WithContext(ctx).Warn(
+				"[HttpRouter.NewAuthHandler]unauthorized request",
+				zap.String("caller", key.Id),
+				zap.String("role", key.Role),
+				zap.String("required role", requiredRole),
+				zap.String("client ip", c.ClientIP()),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+			)
+
+			abortAuth(ctx, c, httpcode.HTTP_FORBIDDEN, err)
+		}
+	}
+}
+
+// Caller - returns the key id of the authenticated caller of c
+func Caller(c *gin.Context) string {
+	return c.GetString(CALLER_KEY)
+}
+
+// authenticate - returns the key of a signed request, or else of its bearer token
+func authenticate(a *auth.Authenticator, c *gin.Context) (config.AuthKey, error) {
+	if c.GetHeader(SIGNATURE_HEADER) != "" {
+		return verifySignature(a, c)
+	}
+
+	token := strings.TrimPrefix(c.GetHeader(AUTHORIZATION_HEADER), BEARER_PREFIX)
+	if token == "" {
+		return config.AuthKey{}, ErrUnauthenticated.New("[HttpRouter.authenticate]missing token or signature")
+	}
+
+	return a.Token(token)
+}
+
+// verifySignature - checks the HMAC signature of the request against the secret of its key id
+//
+// The body is read to be hashed, then restored for the handler
+func verifySignature(a *auth.Authenticator, c *gin.Context) (config.AuthKey, error) {
+	var (
+		body []byte
+		err  error
+	)
+
+	if c.Request.Body != nil {
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			return config.AuthKey{}, ErrUnauthenticated.New(fmt.Sprintf("[HttpRouter.verifySignature]%s", err.Error()))
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	timestamp := c.GetHeader(TIMESTAMP_HEADER)
+
+	return a.Verify(
+		c.GetHeader(KEY_ID_HEADER),
+		timestamp,
+		c.GetHeader(SIGNATURE_HEADER),
+		c.Request.Method,
+		c.Request.URL.RequestURI(),
+		timestamp,
+		auth.BodyHash(body),
+	)
+}
+
+func abortAuth(ctx context.Context, c *gin.Context, statusCode int, err error) {
+	if abortErr := c.AbortWithError(statusCode, err); abortErr != nil {
+		logger.WithContext(ctx).Error("[HttpRouter.abortAuth]fail to abort after failed auth", zap.Error(err))
+	}
+}
@@ -0,0 +1,57 @@
+package httprouter
+
+import "github.com/twothicc/canal/infra/auth"
+
+const (
+	OPENAPI_PATH    = "/v1/openapi.json"
+	OPENAPI_TITLE   = "canal control plane"
+	OPENAPI_VERSION = "1.0.0"
+)
+
+const (
+	METRICS_PATH       = "/metrics"
+	HEALTHZ_PATH       = "/healthz"
+	READYZ_PATH        = "/readyz"
+	READYZ_DETAIL_PATH = "/readyz/detail"
+	RELOAD_STATUS_PATH = "/config/reload-status"
+)
+
+const (
+	BASE10 = 10
+	BIT32  = 32
+)
+
+// Roles, each granted everything the previous one is
+const (
+	ROLE_VIEWER   = auth.ROLE_VIEWER
+	ROLE_OPERATOR = auth.ROLE_OPERATOR
+	ROLE_ADMIN    = auth.ROLE_ADMIN
+)
+
+// Auth headers. Signed requests send their key id, a unix millisecond timestamp and the hex
+// HMAC-SHA256 of method, request uri, timestamp and hex SHA256 of the body, joined by newlines
+const (
+	AUTHORIZATION_HEADER = "Authorization"
+	BEARER_PREFIX        = "Bearer "
+	KEY_ID_HEADER        = "X-Canal-Key"
+	TIMESTAMP_HEADER     = "X-Canal-Timestamp"
+	SIGNATURE_HEADER     = "X-Canal-Signature"
+)
+
+const (
+	// CALLER_KEY - gin context key of the authenticated caller's key id
+	CALLER_KEY = "caller"
+	// ANONYMOUS_CALLER - caller of requests when auth is disabled
+	ANONYMOUS_CALLER = "anonymous"
+	// UNAUTHENTICATED_CALLER - audited caller of requests rejected before authenticating
+	UNAUTHENTICATED_CALLER = "unauthenticated"
+)
+
+const (
+	// ID_PARAM - route parameter of a pipeline id
+	ID_PARAM = "id"
+	// MAX_AUDITED_RESPONSE - bytes of a response kept to find the id of a created pipeline
+	MAX_AUDITED_RESPONSE = 4096
+	// AUDIT_PATH - pages through the audit log
+	AUDIT_PATH = "/audit"
+)
@@ -0,0 +1,13 @@
+package httprouter
+
+import (
+	"github.com/twothicc/common-go/errortype"
+)
+
+const pkg = "infra/httprouter"
+
+//nolint:gomnd // error code
+var (
+	ErrUnauthenticated = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrForbidden       = errortype.ErrorType{Code: 2, Pkg: pkg}
+)
@@ -2,15 +2,18 @@ package httprouter
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/gin-gonic/gin"
+	"github.com/twothicc/canal/tools/errorcode"
 	"github.com/twothicc/common-go/logger"
 	"go.uber.org/zap"
 )
 
-type errorResponse struct {
-	msg string
+// ErrorResponse - errors of a failed request along with their errortype code and package
+//
+// Errors not created through errortype have code 0 and an empty package
+type ErrorResponse struct {
+	Errors []errorcode.Detail
 }
 
 func ErrorHandler(ctx context.Context) gin.HandlerFunc {
@@ -18,17 +21,13 @@ func ErrorHandler(ctx context.Context) gin.HandlerFunc {
 		c.Next()
 
 		if c.IsAborted() {
-			errorMsg := ""
+			details := []errorcode.Detail{}
 
 			fields := []zap.Field{}
 			for _, err := range c.Errors {
 				fields = append(fields, zap.Error(err))
 
-				if errorMsg == "" {
-					errorMsg = err.Error()
-				} else if errorMsg != "" {
-					errorMsg = fmt.Sprintf("%s\n%s", errorMsg, err.Error())
-				}
+				details = append(details, errorcode.Parse(err.Err))
 			}
 
 			statusCode := c.Writer.Status()
@@ -37,8 +36,8 @@ func ErrorHandler(ctx context.Context) gin.HandlerFunc {
 				logger.WithContext(ctx).Error("[HttpRouter.ErrorHandler]errors", fields...)
 			}
 
-			c.JSON(statusCode, errorResponse{
-				msg: errorMsg,
+			c.JSON(statusCode, ErrorResponse{
+				Errors: details,
 			})
 		}
 	}
@@ -2,11 +2,21 @@ package httprouter
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/twothicc/canal/config"
+	"github.com/twothicc/canal/domain/entity/configreloader"
 	"github.com/twothicc/canal/domain/entity/synccontroller"
+	"github.com/twothicc/canal/handlers/audit"
+	"github.com/twothicc/canal/handlers/health"
+	"github.com/twothicc/canal/handlers/metrics"
+	"github.com/twothicc/canal/handlers/reload"
 	"github.com/twothicc/canal/handlers/sync"
+	v1 "github.com/twothicc/canal/handlers/v1"
+	"github.com/twothicc/canal/infra/auditlog"
+	"github.com/twothicc/canal/tools/httpcode"
+	"github.com/twothicc/canal/tools/openapi"
 	"github.com/twothicc/common-go/grpcclient"
 )
 
@@ -14,12 +24,16 @@ type HttpRouterDependencies struct {
 	GrpcClient     *grpcclient.Client
 	Cfg            *config.Config
 	SyncController synccontroller.SyncController
+	ConfigReloader configreloader.ConfigReloader
+	AuditLog       *auditlog.AuditLog
 }
 
 func NewHTTPRouter(ctx context.Context, dependencies *HttpRouterDependencies) *gin.Engine {
 	router := gin.Default()
 
 	router.Use(ErrorHandler(ctx))
+	router.Use(AuditHandler(dependencies.AuditLog))
+	router.Use(NewAuthHandler(ctx, dependencies.Cfg.AuthConfig))
 
 	syncGroup := router.Group("/sync")
 
@@ -31,6 +45,57 @@ func NewHTTPRouter(ctx context.Context, dependencies *HttpRouterDependencies) *g
 	syncGroup.POST("/status", sync.NewStatusHandler(ctx, dependencies.SyncController))
 	syncGroup.POST("/stop", sync.NewStopHandler(ctx, dependencies.SyncController))
 	syncGroup.POST("/delete", sync.NewDeleteHandler(ctx, dependencies.SyncController))
+	syncGroup.POST("/pause", sync.NewPauseHandler(ctx, dependencies.SyncController))
+	syncGroup.POST("/resume", sync.NewResumeHandler(ctx, dependencies.SyncController))
+	syncGroup.POST("/update", sync.NewUpdateHandler(ctx, dependencies.SyncController))
+	syncGroup.POST("/validate", sync.NewValidateHandler(ctx, dependencies.Cfg))
+	syncGroup.GET("/:id/tail", sync.NewTailHandler(ctx, dependencies.SyncController))
+	syncGroup.GET("/:id/events", sync.NewEventsHandler(ctx, dependencies.SyncController))
+
+	router.GET(METRICS_PATH, metrics.NewMetricsHandler(ctx, dependencies.SyncController))
+	router.GET(AUDIT_PATH, audit.NewAuditHandler(ctx, dependencies.AuditLog))
+	router.GET(RELOAD_STATUS_PATH, reload.NewReloadStatusHandler(dependencies.ConfigReloader))
+	router.GET(HEALTHZ_PATH, health.NewHealthzHandler())
+
+	readiness := health.NewReadiness(dependencies.Cfg, dependencies.SyncController)
+	router.GET(READYZ_PATH, health.NewReadyzHandler(ctx, readiness))
+	router.GET(READYZ_DETAIL_PATH, health.NewReadyzDetailHandler(ctx, readiness))
+
+	registerV1(ctx, router, dependencies)
 
 	return router
 }
+
+// registerV1 - registers the /v1 routes and serves their OpenAPI document
+func registerV1(ctx context.Context, router *gin.Engine, dependencies *HttpRouterDependencies) {
+	routes := v1.NewRoutes(ctx, dependencies.Cfg, dependencies.SyncController)
+
+	ops := make([]openapi.Operation, 0, len(routes)+1)
+	isRegistered := make(map[string]bool)
+
+	for _, route := range routes {
+		op := route.Operation
+		op.Errors = append(append([]int{}, op.Errors...), httpcode.HTTP_UNAUTHORIZED, httpcode.HTTP_FORBIDDEN)
+
+		ops = append(ops, op)
+
+		if key := route.Method + " " + route.GinPath; !isRegistered[key] {
+			router.Handle(route.Method, route.GinPath, route.Handler)
+			isRegistered[key] = true
+		}
+	}
+
+	ops = append(ops, openapi.Operation{
+		Method:   http.MethodGet,
+		Path:     OPENAPI_PATH,
+		Summary:  "Gets this OpenAPI document",
+		Statuses: []int{httpcode.HTTP_OK},
+		Errors:   []int{httpcode.HTTP_UNAUTHORIZED, httpcode.HTTP_FORBIDDEN},
+	})
+
+	doc := openapi.Generate(OPENAPI_TITLE, OPENAPI_VERSION, ops, ErrorResponse{})
+
+	router.GET(OPENAPI_PATH, func(c *gin.Context) {
+		c.JSON(httpcode.HTTP_OK, doc)
+	})
+}
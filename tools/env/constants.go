@@ -7,6 +7,7 @@ const (
 	PORT          = "PORT"
 	ENV           = "ENV"
 	DATABASE_PASS = "DATABASE_PASS"
+	STORE_PASS    = "STORE_PASS"
 )
 
 // development environments
@@ -0,0 +1,6 @@
+package errorcode
+
+const (
+	BASE10 = 10
+	BIT32  = 32
+)
@@ -0,0 +1,41 @@
+package errorcode
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// errortype.Error keeps its code and package unexported, so they are recovered from its message
+var errorPattern = regexp.MustCompile(`(?s)^error: code=(-?\d+), pkg=([^,]*), msg=(.*)$`)
+
+// Detail - errortype details of an error
+type Detail struct {
+	Pkg  string
+	Msg  string
+	Code int32
+}
+
+// Parse - extracts the errortype code and package of err
+//
+// Errors not created through errortype have an empty package and code 0, and their whole
+// message as Msg
+func Parse(err error) Detail {
+	if err == nil {
+		return Detail{}
+	}
+
+	msg := err.Error()
+
+	matches := errorPattern.FindStringSubmatch(msg)
+	if matches == nil {
+		return Detail{Msg: msg}
+	}
+
+	code, _ := strconv.ParseInt(matches[1], BASE10, BIT32)
+
+	return Detail{
+		Code: int32(code),
+		Pkg:  matches[2],
+		Msg:  matches[3],
+	}
+}
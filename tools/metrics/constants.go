@@ -0,0 +1,31 @@
+package metrics
+
+const (
+	NAMESPACE = "canal"
+)
+
+// Label names
+const (
+	LABEL_PIPELINE = "pipeline"
+	LABEL_SCHEMA   = "schema"
+	LABEL_TABLE    = "table"
+	LABEL_ACTION   = "action"
+	LABEL_STAGE    = "stage"
+	LABEL_CODE     = "code"
+)
+
+// Error stages
+const (
+	STAGE_PARSE   = "parse"
+	STAGE_PRODUCE = "produce"
+)
+
+const (
+	BASE10 = 10
+)
+
+// Sarama metrics
+const (
+	SARAMA_SUBSYSTEM = "sarama"
+	SARAMA_HELP      = "Sarama producer metric %s"
+)
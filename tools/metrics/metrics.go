@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry - holds every metric of this instance, along with Go runtime and process metrics
+var registry = prometheus.NewRegistry()
+
+var (
+	// EventsTotal - rows events produced to kafka
+	EventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "events_total",
+		Help:      "Rows events produced to kafka.",
+	}, []string{LABEL_PIPELINE, LABEL_SCHEMA, LABEL_TABLE, LABEL_ACTION})
+
+	// ErrorsTotal - rows events that failed to parse or produce, by errortype code
+	ErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "errors_total",
+		Help:      "Rows events that failed to parse or produce, by errortype code.",
+	}, []string{LABEL_PIPELINE, LABEL_STAGE, LABEL_CODE})
+
+	// ProduceLatency - seconds from producing a message until kafka acknowledged or rejected it
+	ProduceLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "produce_latency_seconds",
+		Help:      "Seconds from producing a message until kafka acknowledged or rejected it.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{LABEL_PIPELINE})
+
+	// CheckpointSaveLatency - seconds taken to save a checkpoint
+	CheckpointSaveLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "checkpoint_save_seconds",
+		Help:      "Seconds taken to save a checkpoint.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+	}, []string{LABEL_PIPELINE})
+
+	// CheckpointFailuresTotal - checkpoints that failed to save
+	CheckpointFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "checkpoint_failures_total",
+		Help:      "Checkpoints that failed to save.",
+	}, []string{LABEL_PIPELINE})
+
+	// SnapshotTables - tables to be read by the current snapshot
+	SnapshotTables = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "snapshot_tables",
+		Help:      "Tables to be read by the current snapshot.",
+	}, []string{LABEL_PIPELINE})
+
+	// SnapshotTablesDone - tables read so far by the current snapshot
+	SnapshotTablesDone = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "snapshot_tables_done",
+		Help:      "Tables read so far by the current snapshot.",
+	}, []string{LABEL_PIPELINE})
+
+	// SnapshotRowsTotal - rows read by snapshots
+	SnapshotRowsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "snapshot_rows_total",
+		Help:      "Rows read by snapshots.",
+	}, []string{LABEL_PIPELINE, LABEL_SCHEMA, LABEL_TABLE})
+)
+
+// pipelineVecs - metric vectors labelled by pipeline, cleared when a pipeline is deleted
+var pipelineVecs = []*prometheus.MetricVec{
+	EventsTotal.MetricVec,
+	ErrorsTotal.MetricVec,
+	ProduceLatency.MetricVec,
+	CheckpointSaveLatency.MetricVec,
+	CheckpointFailuresTotal.MetricVec,
+	SnapshotTables.MetricVec,
+	SnapshotTablesDone.MetricVec,
+	SnapshotRowsTotal.MetricVec,
+}
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		EventsTotal,
+		ErrorsTotal,
+		ProduceLatency,
+		CheckpointSaveLatency,
+		CheckpointFailuresTotal,
+		SnapshotTables,
+		SnapshotTablesDone,
+		SnapshotRowsTotal,
+		saramaBridge,
+	)
+}
+
+// Register - adds collector to the metrics served by Handler
+func Register(collector prometheus.Collector) error {
+	return registry.Register(collector)
+}
+
+// Handler - serves every metric in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Pipeline - returns the pipeline label of serverId
+func Pipeline(serverId uint32) string {
+	return strconv.FormatUint(uint64(serverId), BASE10)
+}
+
+// Code - returns the code label of an errortype code
+func Code(code int32) string {
+	return strconv.FormatInt(int64(code), BASE10)
+}
+
+// DeletePipeline - drops every series of the pipeline serverId, e.g. once it is deleted
+func DeletePipeline(serverId uint32) {
+	labels := prometheus.Labels{LABEL_PIPELINE: Pipeline(serverId)}
+
+	for _, vec := range pipelineVecs {
+		vec.DeletePartialMatch(labels)
+	}
+
+	UnregisterSarama(serverId)
+}
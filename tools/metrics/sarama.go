@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// saramaQuantiles - quantiles reported for sarama histograms
+var saramaQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// saramaCollector - bridges the go-metrics registries of the sarama producers of each pipeline
+//
+// Sarama names some metrics after brokers and topics, so metrics are only known when
+// collected and the collector is unchecked
+type saramaCollector struct {
+	registries map[uint32]gometrics.Registry
+	mu         sync.RWMutex
+}
+
+var saramaBridge = &saramaCollector{
+	registries: make(map[uint32]gometrics.Registry),
+}
+
+// RegisterSarama - exposes the sarama metrics in registry as those of the pipeline serverId
+func RegisterSarama(serverId uint32, registry gometrics.Registry) {
+	saramaBridge.mu.Lock()
+	defer saramaBridge.mu.Unlock()
+
+	saramaBridge.registries[serverId] = registry
+}
+
+// UnregisterSarama - stops exposing the sarama metrics of the pipeline serverId
+func UnregisterSarama(serverId uint32) {
+	saramaBridge.mu.Lock()
+	defer saramaBridge.mu.Unlock()
+
+	delete(saramaBridge.registries, serverId)
+}
+
+func (s *saramaCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (s *saramaCollector) Collect(ch chan<- prometheus.Metric) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for serverId, registry := range s.registries {
+		pipeline := Pipeline(serverId)
+
+		registry.Each(func(name string, metric interface{}) {
+			collectSarama(ch, pipeline, name, metric)
+		})
+	}
+}
+
+// collectSarama - converts a go-metrics metric into Prometheus metrics
+//
+// Meters become a counter of their count and a gauge of their one minute rate, histograms
+// become summaries
+func collectSarama(ch chan<- prometheus.Metric, pipeline, name string, metric interface{}) {
+	name = saramaName(name)
+
+	switch m := metric.(type) {
+	case gometrics.Counter:
+		ch <- prometheus.MustNewConstMetric(saramaDesc(name), prometheus.GaugeValue, float64(m.Count()), pipeline)
+	case gometrics.Gauge:
+		ch <- prometheus.MustNewConstMetric(saramaDesc(name), prometheus.GaugeValue, float64(m.Value()), pipeline)
+	case gometrics.GaugeFloat64:
+		ch <- prometheus.MustNewConstMetric(saramaDesc(name), prometheus.GaugeValue, m.Value(), pipeline)
+	case gometrics.Meter:
+		snapshot := m.Snapshot()
+
+		ch <- prometheus.MustNewConstMetric(saramaDesc(name+"_total"), prometheus.CounterValue, float64(snapshot.Count()), pipeline)
+		ch <- prometheus.MustNewConstMetric(saramaDesc(name+"_rate"), prometheus.GaugeValue, snapshot.Rate1(), pipeline)
+	case gometrics.Histogram:
+		snapshot := m.Snapshot()
+
+		values := snapshot.Percentiles(saramaQuantiles)
+		quantiles := make(map[float64]float64, len(saramaQuantiles))
+
+		for idx, quantile := range saramaQuantiles {
+			quantiles[quantile] = values[idx]
+		}
+
+		ch <- prometheus.MustNewConstSummary(
+			saramaDesc(name),
+			uint64(snapshot.Count()),
+			float64(snapshot.Sum()),
+			quantiles,
+			pipeline,
+		)
+	}
+}
+
+func saramaDesc(name string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SARAMA_SUBSYSTEM, name),
+		fmt.Sprintf(SARAMA_HELP, name),
+		[]string{LABEL_PIPELINE},
+		nil,
+	)
+}
+
+// saramaName - turns a sarama metric name, e.g. request-latency-in-ms-for-broker-1, into a
+// valid Prometheus name
+func saramaName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '_'
+	}, name)
+}
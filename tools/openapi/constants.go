@@ -0,0 +1,8 @@
+package openapi
+
+const (
+	OPENAPI_VERSION = "3.0.3"
+	SCHEMA_REF      = "#/components/schemas/%s"
+	SCHEMA_NAME     = "%s.%s"
+	JSON_MEDIA_TYPE = "application/json"
+)
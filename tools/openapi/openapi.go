@@ -0,0 +1,122 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Operation - an API route to document
+//
+// Path uses OpenAPI templating, e.g. /v1/pipelines/{id}. Path parameters are documented as
+// unsigned integers. Request and Response are zero values of the bodies, nil if there is none.
+// Every status in Statuses responds with Response, every status in Errors with the error response
+type Operation struct {
+	Request  interface{}
+	Response interface{}
+	Method   string
+	Path     string
+	Summary  string
+	Statuses []int
+	Errors   []int
+}
+
+// Generate - builds an OpenAPI document of ops, describing errors with errorResponse
+func Generate(title, version string, ops []Operation, errorResponse interface{}) map[string]interface{} {
+	schemas := newSchemas()
+	paths := map[string]interface{}{}
+
+	errorSchema := schemas.of(errorResponse)
+
+	for _, op := range ops {
+		pathItem, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			pathItem = map[string]interface{}{}
+			paths[op.Path] = pathItem
+		}
+
+		responses := map[string]interface{}{}
+
+		for _, status := range op.Statuses {
+			responses[fmt.Sprint(status)] = response(http.StatusText(status), schemas, op.Response)
+		}
+
+		for _, status := range op.Errors {
+			responses[fmt.Sprint(status)] = map[string]interface{}{
+				"description": http.StatusText(status),
+				"content":     content(errorSchema),
+			}
+		}
+
+		operation := map[string]interface{}{
+			"summary":   op.Summary,
+			"responses": responses,
+		}
+
+		if params := pathParams(op.Path); len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  content(schemas.of(op.Request)),
+			}
+		}
+
+		pathItem[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": OPENAPI_VERSION,
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+		},
+	}
+}
+
+func response(description string, schemas *schemas, body interface{}) map[string]interface{} {
+	res := map[string]interface{}{
+		"description": description,
+	}
+
+	if body != nil {
+		res["content"] = content(schemas.of(body))
+	}
+
+	return res
+}
+
+func content(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		JSON_MEDIA_TYPE: map[string]interface{}{
+			"schema": schema,
+		},
+	}
+}
+
+func pathParams(path string) []interface{} {
+	params := []interface{}{}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema": map[string]interface{}{
+				"type":   "integer",
+				"format": "int64",
+			},
+		})
+	}
+
+	return params
+}
@@ -0,0 +1,128 @@
+package openapi
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// schemas - JSON schemas of named struct types, referenced from the document's components
+type schemas struct {
+	components map[string]interface{}
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: map[string]interface{}{},
+	}
+}
+
+// of - returns the schema of v as encoding/json would marshal it
+func (s *schemas) of(v interface{}) map[string]interface{} {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]interface{}{"type": "integer", "format": "int64", "description": "nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		return s.ref(t)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// ref - registers the schema of struct type t once and returns a reference to it
+func (s *schemas) ref(t reflect.Type) map[string]interface{} {
+	name := fmt.Sprintf(SCHEMA_NAME, path.Base(t.PkgPath()), t.Name())
+	ref := map[string]interface{}{"$ref": fmt.Sprintf(SCHEMA_REF, name)}
+
+	if _, ok := s.components[name]; ok {
+		return ref
+	}
+
+	// placeholder for self referencing types
+	s.components[name] = map[string]interface{}{}
+
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	s.fields(t, properties, &required)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	s.components[name] = schema
+
+	return ref
+}
+
+// fields - adds the exported fields of t to properties, flattening embedded structs
+func (s *schemas) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			s.fields(field.Type, properties, required)
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		properties[name] = s.schema(field.Type)
+
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
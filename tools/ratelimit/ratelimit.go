@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter - schedules work back to back, each caller reserving the time its work takes out of
+// a shared budget, so that bursts are smoothed out
+type Limiter struct {
+	next time.Time
+	mu   sync.Mutex
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{}
+}
+
+// Wait - blocks until work taking cost of the budget can start, after the work of earlier callers
+func (l *Limiter) Wait(ctx context.Context, cost time.Duration) error {
+	if cost <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(cost)
+
+	l.mu.Unlock()
+
+	return Sleep(ctx, wait)
+}
+
+// Sleep - blocks for d or until ctx is done, returning ctx's error if it is
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
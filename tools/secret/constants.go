@@ -0,0 +1,10 @@
+package secret
+
+const (
+	// SCHEME_SEPARATOR - separates the scheme of a reference from what it refers to
+	SCHEME_SEPARATOR = ":"
+	// ENV_SCHEME - env:NAME refers to the environment variable NAME
+	ENV_SCHEME = "env"
+	// FILE_SCHEME - file:/path refers to the contents of the file at /path, without trailing newlines
+	FILE_SCHEME = "file"
+)
@@ -0,0 +1,14 @@
+package secret
+
+import (
+	"github.com/twothicc/common-go/errortype"
+)
+
+const pkg = "tools/secret"
+
+//nolint:gomnd // error code
+var (
+	ErrReference  = errortype.ErrorType{Code: 1, Pkg: pkg}
+	ErrNotFound   = errortype.ErrorType{Code: 2, Pkg: pkg}
+	ErrNotAllowed = errortype.ErrorType{Code: 3, Pkg: pkg}
+)
@@ -0,0 +1,166 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Provider - resolves the references of a scheme into secrets
+//
+// Errors must not contain the secret
+type Provider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	providers = map[string]Provider{
+		ENV_SCHEME:  envProvider{},
+		FILE_SCHEME: fileProvider{},
+	}
+	providersMu sync.RWMutex
+
+	allowedEnv  = map[string]bool{}
+	allowedDirs []string
+	allowedMu   sync.RWMutex
+)
+
+// Register - adds p as the provider of references starting with scheme:, e.g. a secret manager
+// client, replacing any provider of scheme
+func Register(scheme string, p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	providers[scheme] = p
+}
+
+// Allow - sets the references Validate accepts, being env:NAME for each of envNames and
+// file:/path for paths under any of dirs, replacing those previously allowed
+func Allow(envNames []string, dirs []string) {
+	allowedMu.Lock()
+	defer allowedMu.Unlock()
+
+	allowedEnv = make(map[string]bool, len(envNames))
+	for _, name := range envNames {
+		allowedEnv[name] = true
+	}
+
+	allowedDirs = make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		allowedDirs = append(allowedDirs, filepath.Clean(dir))
+	}
+}
+
+// Validate - checks that value is empty or an allowed reference, without resolving it
+//
+// Used for references given by callers of the API, who must not read secrets of the instance
+// such as its auth keys. Errors never contain value, which may be a secret given by mistake
+func Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if err := Check(value); err != nil {
+		return err
+	}
+
+	scheme, ref, _ := strings.Cut(value, SCHEME_SEPARATOR)
+
+	if !isAllowed(scheme, ref) {
+		return ErrNotAllowed.New("[secret.Validate]secret reference is not allowed")
+	}
+
+	return nil
+}
+
+// Check - checks that value is empty or a reference of a registered scheme, without resolving it
+//
+// Used for references in the config file, which may refer to any secret
+func Check(value string) error {
+	_, _, err := parse(value)
+
+	return err
+}
+
+// Resolve - returns the secret value refers to, or an empty string if value is empty
+func Resolve(ctx context.Context, value string) (string, error) {
+	p, ref, err := parse(value)
+	if err != nil || p == nil {
+		return "", err
+	}
+
+	return p.Resolve(ctx, ref)
+}
+
+func parse(value string) (Provider, string, error) {
+	if value == "" {
+		return nil, "", nil
+	}
+
+	scheme, ref, ok := strings.Cut(value, SCHEME_SEPARATOR)
+	if !ok {
+		return nil, "", ErrReference.New("[secret.parse]secrets must be given as scheme:reference, e.g. env:NAME")
+	}
+
+	providersMu.RLock()
+	p, ok := providers[scheme]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, "", ErrReference.New("[secret.parse]secret reference has an unknown scheme")
+	}
+
+	return p, ref, nil
+}
+
+// isAllowed - returns whether the reference ref of scheme was allowed
+//
+// Paths are cleaned first, so that ../ cannot leave an allowed directory
+func isAllowed(scheme, ref string) bool {
+	allowedMu.RLock()
+	defer allowedMu.RUnlock()
+
+	switch scheme {
+	case ENV_SCHEME:
+		return allowedEnv[ref]
+	case FILE_SCHEME:
+		if !filepath.IsAbs(ref) {
+			return false
+		}
+
+		path := filepath.Clean(ref)
+
+		for _, dir := range allowedDirs {
+			if rel, err := filepath.Rel(dir, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type envProvider struct{}
+
+func (envProvider) Resolve(_ context.Context, name string) (string, error) {
+	secret, ok := os.LookupEnv(name)
+	if !ok {
+		return "", ErrNotFound.New(fmt.Sprintf("[secret.envProvider.Resolve]environment variable %s is not set", name))
+	}
+
+	return secret, nil
+}
+
+type fileProvider struct{}
+
+func (fileProvider) Resolve(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", ErrNotFound.New(fmt.Sprintf("[secret.fileProvider.Resolve]%s", err.Error()))
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/twothicc/common-go/errortype"
+)
+
+const (
+	ALLOWED_ENV = "CANAL_PIPELINE_PASS"
+	ALLOWED_DIR = "/run/secrets/canal"
+)
+
+func TestValidate(t *testing.T) {
+	Allow([]string{ALLOWED_ENV}, []string{ALLOWED_DIR + "/"})
+
+	tests := []struct {
+		want  *errortype.ErrorType
+		name  string
+		value string
+	}{
+		{name: "empty value"},
+		{name: "allowed env", value: "env:" + ALLOWED_ENV},
+		{name: "file in allowed dir", value: "file:" + ALLOWED_DIR + "/pass"},
+		{name: "file nested in allowed dir", value: "file:" + ALLOWED_DIR + "/mysql/pass"},
+		{name: "file leaving and reentering allowed dir", value: "file:" + ALLOWED_DIR + "/../canal/pass"},
+		{name: "env outside allowlist", value: "env:AUTH_ADMIN_SECRET", want: &ErrNotAllowed},
+		{name: "env with prefix of allowed name", value: "env:CANAL_PIPELINE", want: &ErrNotAllowed},
+		{name: "env extending allowed name", value: "env:" + ALLOWED_ENV + "_2", want: &ErrNotAllowed},
+		{name: "env in lowercase", value: "env:canal_pipeline_pass", want: &ErrNotAllowed},
+		{name: "empty env name", value: "env:", want: &ErrNotAllowed},
+		{name: "file escaping allowed dir", value: "file:" + ALLOWED_DIR + "/../../../etc/shadow", want: &ErrNotAllowed},
+		{name: "file escaping to sibling dir", value: "file:" + ALLOWED_DIR + "/../canal-evil/pass", want: &ErrNotAllowed},
+		{name: "dir sharing prefix of allowed dir", value: "file:" + ALLOWED_DIR + "-evil/pass", want: &ErrNotAllowed},
+		{name: "allowed dir itself", value: "file:" + ALLOWED_DIR, want: &ErrNotAllowed},
+		{name: "allowed dir with trailing slash", value: "file:" + ALLOWED_DIR + "/", want: &ErrNotAllowed},
+		{name: "parent of allowed dir", value: "file:/run/secrets", want: &ErrNotAllowed},
+		{name: "relative file", value: "file:run/secrets/canal/pass", want: &ErrNotAllowed},
+		{name: "relative file escaping", value: "file:../canal/pass", want: &ErrNotAllowed},
+		{name: "empty file path", value: "file:", want: &ErrNotAllowed},
+		{name: "plain text", value: "hunter2", want: &ErrReference},
+		{name: "unknown scheme", value: "vault:secret/canal", want: &ErrReference},
+		{name: "scheme in uppercase", value: "ENV:" + ALLOWED_ENV, want: &ErrReference},
+		{name: "missing scheme", value: ":" + ALLOWED_ENV, want: &ErrReference},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.value)
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("fail to validate: %v", err)
+				}
+
+				return
+			}
+
+			if !tt.want.Is(err) {
+				t.Fatalf("got error %v, want error code %d", err, tt.want.Code)
+			}
+		})
+	}
+}
+
+func TestValidateNothingAllowed(t *testing.T) {
+	Allow(nil, nil)
+
+	for _, value := range []string{"env:" + ALLOWED_ENV, "file:" + ALLOWED_DIR + "/pass", "file:/"} {
+		if err := Validate(value); !ErrNotAllowed.Is(err) {
+			t.Fatalf("got error %v validating %s, want ErrNotAllowed", err, value)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	Allow(nil, nil)
+
+	// config file references are not limited to the allowlist
+	for _, value := range []string{"", "env:AUTH_ADMIN_SECRET", "file:/etc/canal/pass", "file:relative"} {
+		if err := Check(value); err != nil {
+			t.Fatalf("fail to check %s: %v", value, err)
+		}
+	}
+
+	for _, value := range []string{"hunter2", "vault:secret/canal"} {
+		if err := Check(value); !ErrReference.Is(err) {
+			t.Fatalf("got error %v checking %s, want ErrReference", err, value)
+		}
+	}
+}